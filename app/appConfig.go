@@ -0,0 +1,120 @@
+package app
+
+import (
+	"strconv"
+
+	"github.com/magiconair/properties"
+)
+
+// appConfig is the typed config loaded from the properties, a key which is not set
+// or invalid has the default of the schema. The paths are empty for their default in the ludo dir.
+// A loaded config is never modified, it is replaced when the properties change
+type appConfig struct {
+	pipedApi        string
+	instanceListApi string
+	isSourcePiped   bool
+	streamQuality   string
+
+	isCacheEnabled bool
+	cacheDir       string
+	exportTemplate string
+	dataStore      string
+	backupCount    int
+
+	httpListen   string
+	httpToken    string
+	daemonSocket string
+	mprisEnabled bool
+
+	logLevel   string
+	logLevels  map[string]string // by subsystem
+	logFile    string
+	logMaxSize int
+	logBackups int
+
+	lyricsApi           string
+	listenBrainzToken   string
+	listenBrainzApiRoot string
+	lastFm              lastFmConfig
+}
+
+// config of the properties, replaced with them
+var loadedConfig = loadConfig(properties.NewProperties())
+
+// returns the config, safe to read while the config is changed
+func currentConfig() *appConfig {
+	propsMu.RLock()
+	defer propsMu.RUnlock()
+	return loadedConfig
+}
+
+func loadConfig(prop *properties.Properties) *appConfig {
+	r := configReader{prop: prop}
+	cfg := &appConfig{
+		pipedApi:        r.string(pipedApiKey),
+		instanceListApi: r.string(instanceListApiKey),
+		isSourcePiped:   r.bool(isSourcePiped),
+		streamQuality:   r.string(streamQualityKey),
+
+		isCacheEnabled: r.bool(isCacheEnabledKey),
+		cacheDir:       r.string(cacheDirKey),
+		exportTemplate: r.string(cacheExportTemplateKey),
+		dataStore:      r.string(dataStoreKey),
+		backupCount:    r.int(backupCountKey),
+
+		httpListen:   r.string(httpListenKey),
+		httpToken:    r.string(httpTokenKey),
+		daemonSocket: r.string(daemonSocketKey),
+		mprisEnabled: r.bool(mprisEnabledKey),
+
+		logLevel:   r.string(logLevelKey),
+		logLevels:  make(map[string]string),
+		logFile:    r.string(logFileKey),
+		logMaxSize: r.int(logMaxSizeKey),
+		logBackups: r.int(logBackupsKey),
+
+		lyricsApi:           r.string(lyricsApiKey),
+		listenBrainzToken:   r.string(listenBrainzTokenKey),
+		listenBrainzApiRoot: r.string(listenBrainzApiRootKey),
+		lastFm: lastFmConfig{
+			apiRoot:    r.string(lastFmApiRootKey),
+			apiKey:     r.string(lastFmApiKeyKey),
+			apiSecret:  r.string(lastFmApiSecretKey),
+			sessionKey: r.string(lastFmSessionKeyKey),
+			username:   r.string(lastFmUsernameKey),
+			password:   r.string(lastFmPasswordKey),
+			token:      r.string(lastFmTokenKey),
+		},
+	}
+
+	for _, subsystem := range prop.FilterStripPrefix(logLevelKey + ".").Keys() {
+		if level := r.string(logLevelKey + "." + subsystem); level != "" {
+			cfg.logLevels[subsystem] = level
+		}
+	}
+	return cfg
+}
+
+// reads the keys of the schema from the properties,
+// invalid values are reported by the config check and read as the default
+type configReader struct {
+	prop *properties.Properties
+}
+
+func (r configReader) string(key string) string {
+	configKey, _ := findConfigKey(key)
+	if value, ok := r.prop.Get(key); ok && configKey.check(value) == nil {
+		return value
+	}
+	return configKey.Default
+}
+
+func (r configReader) bool(key string) bool {
+	value, _ := parseConfigBool(r.string(key))
+	return value
+}
+
+func (r configReader) int(key string) int {
+	value, _ := strconv.Atoi(r.string(key))
+	return value
+}
@@ -0,0 +1,114 @@
+package app
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/magiconair/properties"
+)
+
+func TestLoadConfigDefaults(t *testing.T) {
+	cfg := loadConfig(properties.NewProperties())
+
+	if cfg.pipedApi != defaultPipedApi || cfg.instanceListApi != defaultInstanceListApi || cfg.lyricsApi != defaultLyricsApi {
+		t.Errorf("apis = %s %s %s, want the defaults", cfg.pipedApi, cfg.instanceListApi, cfg.lyricsApi)
+	}
+	if !cfg.isSourcePiped || !cfg.isCacheEnabled || !cfg.mprisEnabled {
+		t.Errorf("switches = %v %v %v, want true", cfg.isSourcePiped, cfg.isCacheEnabled, cfg.mprisEnabled)
+	}
+	if cfg.streamQuality != QualityBest || cfg.exportTemplate != defaultExportTemplate || cfg.logLevel != "info" {
+		t.Errorf("values = %s %s %s, want the defaults", cfg.streamQuality, cfg.exportTemplate, cfg.logLevel)
+	}
+	if cfg.backupCount != defaultBackupCount || cfg.logMaxSize != defaultLogMaxSize || cfg.logBackups != defaultLogBackups {
+		t.Errorf("numbers = %d %d %d, want the defaults", cfg.backupCount, cfg.logMaxSize, cfg.logBackups)
+	}
+	if cfg.dataStore != "" || cfg.cacheDir != "" || cfg.daemonSocket != "" || cfg.logFile != "" {
+		t.Errorf("paths = %q %q %q %q, want empty for the ludo dir", cfg.dataStore, cfg.cacheDir, cfg.daemonSocket, cfg.logFile)
+	}
+	if cfg.lastFm.apiRoot != defaultLastFmApi || cfg.listenBrainzApiRoot != defaultListenBrainzApi {
+		t.Errorf("scrobbler apis = %s %s, want the defaults", cfg.lastFm.apiRoot, cfg.listenBrainzApiRoot)
+	}
+}
+
+func TestLoadConfigValues(t *testing.T) {
+	cfg := loadConfig(newTestProps(map[string]string{
+		pipedApiKey:              "https://piped.example.com",
+		streamQualityKey:         "max-kbps=128",
+		isCacheEnabledKey:        "off",
+		backupCountKey:           "2",
+		logLevelKey:              "warn",
+		logLevelKey + ".vlc":     "debug",
+		logLevelKey + ".httpApi": "loud",
+		lastFmApiKeyKey:          "key",
+		lastFmUsernameKey:        "user",
+	}))
+
+	if cfg.pipedApi != "https://piped.example.com" || cfg.streamQuality != "max-kbps=128" {
+		t.Errorf("values = %s %s", cfg.pipedApi, cfg.streamQuality)
+	}
+	if cfg.isCacheEnabled || cfg.backupCount != 2 || cfg.logLevel != "warn" {
+		t.Errorf("cache = %v backups = %d level = %s", cfg.isCacheEnabled, cfg.backupCount, cfg.logLevel)
+	}
+	if len(cfg.logLevels) != 1 || cfg.logLevels["vlc"] != "debug" {
+		t.Errorf("subsystem levels = %v, want only vlc", cfg.logLevels)
+	}
+	if cfg.lastFm.apiKey != "key" || cfg.lastFm.username != "user" || cfg.lastFm.apiRoot != defaultLastFmApi {
+		t.Errorf("last.fm = %+v", cfg.lastFm)
+	}
+}
+
+// invalid values are reported by the config check and read as the default
+func TestLoadConfigInvalid(t *testing.T) {
+	prop := newTestProps(map[string]string{
+		pipedApiKey:       "piped",
+		streamQualityKey:  "loud",
+		isCacheEnabledKey: "maybe",
+		backupCountKey:    "-1",
+		logMaxSizeKey:     "big",
+	})
+	cfg := loadConfig(prop)
+
+	if cfg.pipedApi != defaultPipedApi || cfg.streamQuality != QualityBest || !cfg.isCacheEnabled {
+		t.Errorf("values = %s %s %v, want the defaults", cfg.pipedApi, cfg.streamQuality, cfg.isCacheEnabled)
+	}
+	if cfg.backupCount != defaultBackupCount || cfg.logMaxSize != defaultLogMaxSize {
+		t.Errorf("numbers = %d %d, want the defaults", cfg.backupCount, cfg.logMaxSize)
+	}
+	if issues := validateProps(prop); len(issues) != 5 {
+		t.Errorf("issues = %v, want 5", issues)
+	}
+}
+
+// the config is replaced when a key is set or unset
+func TestSetConfigReplacesConfig(t *testing.T) {
+	prevProps, prevConfig, prevPath := props, loadedConfig, propsPath
+	t.Cleanup(func() { props, loadedConfig, propsPath = prevProps, prevConfig, prevPath })
+	setProps(properties.NewProperties())
+	propsPath = filepath.Join(t.TempDir(), ludoPropertiesFile)
+
+	before := currentConfig()
+	if _, err := SetConfig(backupCountKey, "7"); err != nil {
+		t.Fatal(err)
+	}
+	if got := currentConfig().backupCount; got != 7 {
+		t.Errorf("backup count = %d, want 7", got)
+	}
+	if before.backupCount != defaultBackupCount {
+		t.Errorf("the previous config was changed")
+	}
+
+	if _, err := UnsetConfig(backupCountKey); err != nil {
+		t.Fatal(err)
+	}
+	if got := currentConfig().backupCount; got != defaultBackupCount {
+		t.Errorf("backup count = %d, want %d", got, defaultBackupCount)
+	}
+}
+
+func newTestProps(values map[string]string) *properties.Properties {
+	prop := properties.NewProperties()
+	for key, value := range values {
+		prop.Set(key, value)
+	}
+	return prop
+}
@@ -0,0 +1,165 @@
+package app
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var streamLog = newLogger("audioStream")
+
+// stream quality preferences
+const (
+	QualityBest          = "best"
+	QualityWorst         = "worst"
+	QualityOpusPreferred = "opus-preferred"
+	QualityM4aPreferred  = "m4a-preferred"
+	QualityMaxKbps       = "max-kbps="
+)
+
+type AudioStream struct {
+	Url      string
+	MimeType string
+	Codec    string
+	Quality  string
+	Bitrate  int
+}
+
+func (stream AudioStream) String() string {
+	codec := stream.Codec
+	if codec == "" {
+		codec = stream.MimeType
+	}
+	if stream.Bitrate > 0 {
+		return fmt.Sprintf("%s %dkbps", codec, stream.Bitrate/1000)
+	}
+	return strings.TrimSpace(codec + " " + stream.Quality)
+}
+
+func (stream AudioStream) isOpus() bool {
+	return strings.Contains(stream.Codec, "opus") || stream.MimeType == "audio/webm"
+}
+
+func (stream AudioStream) isM4a() bool {
+	return strings.HasPrefix(stream.Codec, "mp4a") || stream.MimeType == "audio/mp4"
+}
+
+// returns the stream quality of the config, an invalid quality is read as best
+func GetStreamQuality() string {
+	return currentConfig().streamQuality
+}
+
+func validateStreamQuality(quality string) error {
+	switch quality {
+	case QualityBest, QualityWorst, QualityOpusPreferred, QualityM4aPreferred:
+		return nil
+	}
+	if _, err := parseMaxKbps(quality); err != nil {
+		return fmt.Errorf("invalid stream quality '%s' (best, worst, opus-preferred, m4a-preferred, max-kbps=N)", quality)
+	}
+	return nil
+}
+
+func parseMaxKbps(quality string) (int, error) {
+	val, ok := strings.CutPrefix(quality, QualityMaxKbps)
+	if !ok {
+		return 0, fmt.Errorf("not a %sN quality", QualityMaxKbps)
+	}
+	kbps, err := strconv.Atoi(val)
+	if err != nil {
+		return 0, err
+	}
+	if kbps <= 0 {
+		return 0, fmt.Errorf("invalid max kbps %d", kbps)
+	}
+	return kbps, nil
+}
+
+// selects a stream from the list as per the quality preference
+func selectAudioStream(streams []AudioStream, quality string) (AudioStream, bool) {
+	if len(streams) == 0 {
+		return AudioStream{}, false
+	}
+
+	// sorted by bitrate, highest first
+	sorted := make([]AudioStream, len(streams))
+	copy(sorted, streams)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Bitrate > sorted[j].Bitrate
+	})
+
+	best := sorted[0]
+	worst := sorted[len(sorted)-1]
+
+	firstMatch := func(match func(AudioStream) bool) (AudioStream, bool) {
+		for _, stream := range sorted {
+			if match(stream) {
+				return stream, true
+			}
+		}
+		return AudioStream{}, false
+	}
+
+	switch quality {
+	case QualityBest:
+		return best, true
+	case QualityWorst:
+		return worst, true
+	case QualityOpusPreferred:
+		if stream, ok := firstMatch(AudioStream.isOpus); ok {
+			return stream, true
+		}
+		return best, true
+	case QualityM4aPreferred:
+		if stream, ok := firstMatch(AudioStream.isM4a); ok {
+			return stream, true
+		}
+		return best, true
+	}
+
+	if kbps, err := parseMaxKbps(quality); err == nil {
+		if stream, ok := firstMatch(func(s AudioStream) bool { return s.Bitrate <= kbps*1000 }); ok {
+			return stream, true
+		}
+		return worst, true
+	}
+
+	streamLog.Warn("Unknown quality preference", "quality", quality)
+	return best, true
+}
+
+// parses the audioStreams of a piped streams response
+func getPipedApiAudioStreamList(response interface{}) []AudioStream {
+	streamList, ok := getValue(response, path{"audioStreams"}).([]interface{})
+	if !ok {
+		return []AudioStream{}
+	}
+
+	audioStreams := make([]AudioStream, 0, len(streamList))
+	for _, item := range streamList {
+		var stream AudioStream
+
+		if url, ok := getValue(item, path{"url"}).(string); ok {
+			stream.Url = url
+		}
+		if mimeType, ok := getValue(item, path{"mimeType"}).(string); ok {
+			stream.MimeType = mimeType
+		}
+		if codec, ok := getValue(item, path{"codec"}).(string); ok {
+			stream.Codec = codec
+		}
+		if quality, ok := getValue(item, path{"quality"}).(string); ok {
+			stream.Quality = quality
+		}
+		if bitrate, ok := getValue(item, path{"bitrate"}).(float64); ok {
+			stream.Bitrate = int(bitrate)
+		}
+
+		if stream.Url != "" {
+			audioStreams = append(audioStreams, stream)
+		}
+	}
+
+	return audioStreams
+}
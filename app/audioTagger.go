@@ -0,0 +1,115 @@
+package app
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+
+	vlc "github.com/adrg/libvlc-go/v3"
+)
+
+var taggerLog = newLogger("audioTagger")
+
+const (
+	ytWatchUrl     = "https://www.youtube.com/watch?v="
+	ytThumbnailUrl = "https://i.ytimg.com/vi/"
+)
+
+// writes title, artist, track id and source url into the given audio file.
+// libVlc picks the tag format (MP4/ID3/Vorbis) based on the container,
+// duration is not written as it is already part of the container
+func writeAudioTags(filePath string, audio AudioBasic) error {
+	media, err := vlc.NewMediaFromPath(filePath)
+	if err != nil {
+		return err
+	}
+	defer media.Release()
+
+	// read the existing tags so that saving does not drop them
+	if err := media.Parse(); err != nil {
+		return err
+	}
+
+	tags := []struct {
+		key vlc.MediaMetaKey
+		val string
+	}{
+		{vlc.MediaTitle, audio.Title},
+		{vlc.MediaArtist, audio.Uploader},
+		{vlc.MediaTrackID, audio.YtId},
+		{vlc.MediaURL, getAudioSourceUrl(audio.YtId)},
+	}
+
+	for _, tag := range tags {
+		if tag.val == "" {
+			continue
+		}
+		if err := media.SetMeta(tag.key, tag.val); err != nil {
+			return err
+		}
+	}
+
+	taggerLog.Debug("Writing tags", "path", filePath)
+	return media.SaveMeta()
+}
+
+func getAudioSourceUrl(ytId string) string {
+	if ytId == "" {
+		return ""
+	}
+	return ytWatchUrl + ytId
+}
+
+func getAudioThumbnailUrl(ytId string) string {
+	if ytId == "" {
+		return ""
+	}
+	return ytThumbnailUrl + ytId + "/hqdefault.jpg"
+}
+
+// expands the export template for the given audio
+// supported fields: {id}, {title}, {uploader}, {duration}, {ext}
+func expandExportTemplate(template string, audio AudioBasic, ext string) (string, error) {
+	if template == "" {
+		return "", errors.New("empty export template")
+	}
+
+	replacer := strings.NewReplacer(
+		"{id}", sanitizeFileName(audio.YtId),
+		"{title}", sanitizeFileName(audio.Title),
+		"{uploader}", sanitizeFileName(audio.Uploader),
+		"{duration}", sanitizeFileName(audio.GetFormattedDuration()),
+		"{ext}", strings.TrimPrefix(ext, "."),
+	)
+
+	// template always uses '/' as separator, irrespective of OS
+	parts := strings.Split(template, "/")
+	for i, part := range parts {
+		part = strings.TrimSpace(replacer.Replace(part))
+		if part == "" || part == "." || part == ".." {
+			return "", errors.New("invalid export template: " + template)
+		}
+		parts[i] = part
+	}
+
+	return filepath.Join(parts...), nil
+}
+
+func sanitizeFileName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		switch r {
+		case '/', '\\', ':', '*', '?', '"', '<', '>', '|':
+			return '_'
+		}
+		if r < 32 {
+			return -1
+		}
+		return r
+	}, name)
+
+	name = strings.Trim(strings.TrimSpace(name), ".")
+	if name == "" {
+		name = "Unknown"
+	}
+	return name
+}
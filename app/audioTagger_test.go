@@ -0,0 +1,70 @@
+package app
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestExpandExportTemplate(t *testing.T) {
+	audio := AudioBasic{YtId: "abc-123_XYZ", Title: `AC/DC: Back in Black?`, Uploader: "AC/DC", Duration: 255}
+
+	tests := []struct {
+		template string
+		ext      string
+		want     string
+		wantErr  bool
+	}{
+		{"{uploader}/{title}.{ext}", ".m4a", filepath.Join("AC_DC", "AC_DC_ Back in Black_.m4a"), false},
+		{"{id} - {duration}.{ext}", ".webm", "abc-123_XYZ - 4m15s.webm", false},
+		{"", ".m4a", "", true},
+		{"{uploader}/../{title}", ".m4a", "", true},
+		{"{uploader}//{title}", ".m4a", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.template, func(t *testing.T) {
+			got, err := expandExportTemplate(tt.template, audio, tt.ext)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, want error %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetUniqueExportPath(t *testing.T) {
+	exportedPaths := make(map[string]bool)
+	song := filepath.Join("export", "Artist", "Song.m4a")
+
+	for _, want := range []string{
+		song,
+		filepath.Join("export", "Artist", "Song (2).m4a"),
+		filepath.Join("export", "Artist", "Song (3).m4a"),
+	} {
+		if got := getUniqueExportPath(song, exportedPaths); got != want {
+			t.Errorf("got %q, want %q", got, want)
+		}
+	}
+
+	// names differing only in case are the same file on windows and mac
+	if got, want := getUniqueExportPath(filepath.Join("export", "artist", "song.m4a"), exportedPaths), filepath.Join("export", "artist", "song (4).m4a"); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if got, want := getUniqueExportPath(filepath.Join("export", "Artist", "Other.m4a"), exportedPaths), filepath.Join("export", "Artist", "Other.m4a"); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestAudioUrls(t *testing.T) {
+	if got := getAudioSourceUrl("dQw4w9WgXcQ"); got != "https://www.youtube.com/watch?v=dQw4w9WgXcQ" {
+		t.Errorf("source url = %q", got)
+	}
+	if got := getAudioThumbnailUrl("dQw4w9WgXcQ"); got != "https://i.ytimg.com/vi/dQw4w9WgXcQ/hqdefault.jpg" {
+		t.Errorf("thumbnail url = %q", got)
+	}
+	if getAudioSourceUrl("") != "" || getAudioThumbnailUrl("") != "" {
+		t.Errorf("urls are built for a missing id")
+	}
+}
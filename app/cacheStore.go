@@ -1,155 +1,491 @@
-package app
-
-import (
-	"io"
-	"log"
-	"net/http"
-	"os"
-	"path/filepath"
-	"strings"
-)
-
-var audioCache CacheStore
-
-type CacheStore struct {
-	isEnabled bool
-	cacheDir  string
-	cacheMap  map[string]string
-}
-
-var cacheLog = log.New(io.Discard, "cacheStore: ", log.LstdFlags|log.Lmsgprefix)
-
-func (cache *CacheStore) Init(cacheDir string) error {
-	if !cache.isEnabled {
-		cacheLog.Println("Caching is disabled")
-		return nil
-	}
-
-	cacheLog.Println("Cache at:", cacheDir)
-	cache.cacheDir = cacheDir
-	if err := os.MkdirAll(cacheDir, 0755); err != nil {
-		return err
-	}
-	cmap, err := cache.buildCacheMap()
-	if err != nil {
-		return err
-	}
-	cache.cacheMap = cmap
-	return nil
-}
-
-func (cache *CacheStore) Close() error {
-	return nil
-}
-
-func (cache *CacheStore) CacheAudio(audio AudioDetails) {
-	if !cache.isEnabled {
-		cacheLog.Println("Caching is disabled")
-		return
-	}
-
-	trackTitle := audio.Title
-	fileName := audio.YtId
-	audioStreamUrl := audio.AudioStreamUrl
-	fileLoc := filepath.Join(cache.cacheDir, fileName)
-
-	// check if file does not exist
-	if _, ok := cache.LookupCache(audio.AudioBasic); !ok {
-		go func() {
-			err := downloadFile(fileLoc, audioStreamUrl)
-			if err != nil {
-				cacheLog.Println("Error in downloading file:", trackTitle, "|", fileName)
-				cacheLog.Println(err)
-			}
-		}()
-	}
-}
-
-func (cache *CacheStore) LookupCache(audio AudioBasic) (string, bool) {
-	if !cache.isEnabled {
-		cacheLog.Println("Caching is disabled")
-		return "", false
-	}
-
-	cachePath, ok := cache.cacheMap[audio.YtId]
-
-	if !ok {
-		fileLoc := filepath.Join(cache.cacheDir, audio.YtId+".m4a")
-		cachePath, ok = searchCacheDir(fileLoc)
-		if !ok {
-			return "", false
-		}
-		cache.cacheMap[audio.YtId] = cachePath
-	}
-
-	cacheLog.Println("Audio Cached at:", cachePath)
-	return cachePath, true
-}
-
-func (cache *CacheStore) buildCacheMap() (map[string]string, error) {
-	cacheMap := make(map[string]string)
-	matches, err := filepath.Glob(filepath.Join(cache.cacheDir, "*.m4a"))
-	if err != nil {
-		return nil, err
-	}
-
-	cacheLog.Println(matches)
-	for _, match := range matches {
-		fileId := strings.Split(filepath.Base(match), ".")[0]
-		cacheMap[fileId] = match
-	}
-
-	cacheLog.Println(cacheMap)
-	return cacheMap, nil
-}
-
-func searchCacheDir(fileLoc string) (string, bool) {
-	if fd, err := os.Stat(fileLoc); err != nil || fd.IsDir() {
-		return "", false
-	}
-	return fileLoc, true
-}
-
-func downloadFile(filePath, fileUrl string) error {
-	// intialise download client
-	client := http.Client{
-		CheckRedirect: func(r *http.Request, via []*http.Request) error {
-			r.URL.Opaque = r.URL.Path
-			return nil
-		},
-	}
-
-	// get response
-	resp, err := client.Get(fileUrl)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-
-	// check response file formate
-	if resp.Header.Get("Content-Type") == "audio/mp4" {
-		filePath += ".m4a"
-	}
-	tmpFilePath := filePath + ".tmp"
-
-	// create temp file
-	file, err := os.Create(tmpFilePath)
-	if err != nil {
-		return err
-	}
-	cacheLog.Println("File created")
-
-	// download file
-	size, err := io.Copy(file, resp.Body)
-	cacheLog.Println()
-	if err != nil {
-		return err
-	}
-	file.Close()
-
-	cacheLog.Printf("Downloaded a file %s with size %d\n", filePath, size)
-	// rename temp file and remove incase of any error
-	err = os.Rename(tmpFilePath, filePath)
-	os.Remove(tmpFilePath)
-	return err
-}
+package app
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	vlc "github.com/adrg/libvlc-go/v3"
+)
+
+var audioCache CacheStore
+
+type CacheStore struct {
+	isEnabled bool
+	cacheDir  string
+	mu        sync.Mutex // guards cacheMap, used by the player and the cache commands
+	cacheMap  map[string]string
+}
+
+type CacheReport struct {
+	Checked  int
+	Removed  []string
+	Requeued int
+}
+
+type CacheStats struct {
+	Enabled bool           `json:"enabled"`
+	Dir     string         `json:"dir"`
+	Files   int            `json:"files"`
+	Size    int64          `json:"size"`
+	Formats map[string]int `json:"formats"`
+}
+
+var cacheLog = newLogger("cacheStore")
+
+// file extension of cached audio for each stream mime type
+var cacheFileExtMap = map[string]string{
+	"audio/mp4":  ".m4a",
+	"audio/webm": ".webm",
+}
+
+var cacheFileExtList = []string{".m4a", ".webm"}
+
+func (cache *CacheStore) Init(cacheDir string) error {
+	if !cache.isEnabled {
+		cacheLog.Debug("Caching is disabled")
+		return nil
+	}
+
+	cacheLog.Info("Cache loaded", "dir", cacheDir)
+	cache.cacheDir = cacheDir
+	if err := os.MkdirAll(cacheDir, 0755); err != nil {
+		return err
+	}
+	if err := cache.cleanCacheDir(true); err != nil {
+		return err
+	}
+	return cache.rebuildCacheMap()
+}
+
+func (cache *CacheStore) Close() error {
+	return nil
+}
+
+func (cache *CacheStore) CacheAudio(audio AudioDetails) {
+	if !cache.isEnabled {
+		cacheLog.Debug("Caching is disabled")
+		return
+	}
+
+	trackTitle := audio.Title
+	fileName := audio.YtId
+	audioStreamUrl := audio.AudioStreamUrl
+	fileLoc := filepath.Join(cache.cacheDir, fileName)
+
+	// check if file does not exist
+	if _, ok := cache.LookupCache(audio.AudioBasic); !ok {
+		go func() {
+			defer HandlePanic()
+			filePath, err := downloadFile(fileLoc, audioStreamUrl)
+			if err != nil {
+				cacheLog.Error("Error in downloading file", "title", trackTitle, "file", fileName, "err", err)
+				return
+			}
+
+			if err := verifyAudioFile(filePath); err != nil {
+				cacheLog.Warn("Removing unplayable file", "title", trackTitle, "file", fileName, "err", err)
+				os.Remove(filePath)
+				return
+			}
+
+			if err := writeAudioTags(filePath, audio.AudioBasic); err != nil {
+				cacheLog.Warn("Error in writing tags", "title", trackTitle, "file", fileName, "err", err)
+			}
+		}()
+	}
+}
+
+func (cache *CacheStore) LookupCache(audio AudioBasic) (string, bool) {
+	if !cache.isEnabled {
+		cacheLog.Debug("Caching is disabled")
+		return "", false
+	}
+
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	cachePath, ok := cache.cacheMap[audio.YtId]
+
+	if !ok {
+		for _, ext := range cacheFileExtList {
+			fileLoc := filepath.Join(cache.cacheDir, audio.YtId+ext)
+			if cachePath, ok = searchCacheDir(fileLoc); ok {
+				break
+			}
+		}
+		if !ok {
+			return "", false
+		}
+		cache.cacheMap[audio.YtId] = cachePath
+	}
+
+	cacheLog.Info("Audio cached", "path", cachePath)
+	return cachePath, true
+}
+
+// re-checks every cached audio for playback and removes the broken ones
+// removed audio is downloaded again if redownload is set
+func (cache *CacheStore) VerifyCache(redownload bool) (CacheReport, error) {
+	var report CacheReport
+	if !cache.isEnabled {
+		return report, errors.New("caching is disabled")
+	}
+
+	// temp files may belong to ongoing downloads
+	if err := cache.cleanCacheDir(false); err != nil {
+		return report, err
+	}
+	if err := cache.rebuildCacheMap(); err != nil {
+		return report, err
+	}
+
+	for ytId, cachePath := range cache.getCacheMap() {
+		report.Checked++
+		if err := verifyAudioFile(cachePath); err == nil {
+			continue
+		}
+
+		cacheLog.Warn("Removing corrupt file", "path", cachePath)
+		if err := os.Remove(cachePath); err != nil {
+			return report, err
+		}
+		cache.mu.Lock()
+		delete(cache.cacheMap, ytId)
+		cache.mu.Unlock()
+		report.Removed = append(report.Removed, ytId)
+	}
+
+	if redownload {
+		getSong := GetSong(IsSourcePiped())
+		for _, ytId := range report.Removed {
+			audio, err := getSong(ytId, true)
+			if err != nil {
+				cacheLog.Warn("Could not requeue", "ytId", ytId, "err", err)
+				continue
+			}
+			cache.CacheAudio(*audio)
+			report.Requeued++
+		}
+	}
+
+	return report, nil
+}
+
+// returns the number and total size of the cached audio, with the count for each file extension
+func (cache *CacheStore) Stats() (CacheStats, error) {
+	stats := CacheStats{Enabled: cache.isEnabled, Dir: cache.cacheDir, Formats: make(map[string]int)}
+	if !cache.isEnabled {
+		return stats, nil
+	}
+
+	cmap, err := cache.buildCacheMap()
+	if err != nil {
+		return stats, err
+	}
+
+	for _, cachePath := range cmap {
+		fileInfo, err := os.Stat(cachePath)
+		if err != nil {
+			return stats, err
+		}
+		stats.Files++
+		stats.Size += fileInfo.Size()
+		stats.Formats[strings.TrimPrefix(filepath.Ext(cachePath), ".")]++
+	}
+	return stats, nil
+}
+
+// removes empty and unreadable files, along with
+// leftover temp files of interrupted downloads if removeTemp is set
+func (cache *CacheStore) cleanCacheDir(removeTemp bool) error {
+	entries, err := os.ReadDir(cache.cacheDir)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		filePath := filepath.Join(cache.cacheDir, entry.Name())
+
+		isTemp := strings.HasSuffix(entry.Name(), ".tmp")
+		if isTemp && !removeTemp {
+			continue
+		}
+
+		if isTemp || !isReadableFile(filePath) {
+			cacheLog.Warn("Removing broken file", "path", filePath)
+			if err := os.Remove(filePath); err != nil {
+				cacheLog.Error("Error in removing file", "path", filePath, "err", err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (cache *CacheStore) rebuildCacheMap() error {
+	cmap, err := cache.buildCacheMap()
+	if err != nil {
+		return err
+	}
+
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	cache.cacheMap = cmap
+	return nil
+}
+
+// returns a copy of the cache map
+func (cache *CacheStore) getCacheMap() map[string]string {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	cmap := make(map[string]string, len(cache.cacheMap))
+	for ytId, cachePath := range cache.cacheMap {
+		cmap[ytId] = cachePath
+	}
+	return cmap
+}
+
+func (cache *CacheStore) buildCacheMap() (map[string]string, error) {
+	cacheMap := make(map[string]string)
+	var matches []string
+	for _, ext := range cacheFileExtList {
+		extMatches, err := filepath.Glob(filepath.Join(cache.cacheDir, "*"+ext))
+		if err != nil {
+			return nil, err
+		}
+		matches = append(matches, extMatches...)
+	}
+
+	for _, match := range matches {
+		fileId := strings.Split(filepath.Base(match), ".")[0]
+		cacheMap[fileId] = match
+	}
+
+	cacheLog.Debug("Cache indexed", "files", len(cacheMap))
+	return cacheMap, nil
+}
+
+// describes the format of a cached audio file
+func getCachedStreamFormat(cachePath string) AudioStream {
+	stream := AudioStream{Url: cachePath, Quality: "cached"}
+	ext := filepath.Ext(cachePath)
+	for mimeType, mimeExt := range cacheFileExtMap {
+		if mimeExt == ext {
+			stream.MimeType = mimeType
+		}
+	}
+	return stream
+}
+
+func isReadableFile(filePath string) bool {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+
+	buf := make([]byte, 1)
+	_, err = file.Read(buf)
+	return err == nil
+}
+
+// checks if the file is non empty and can be parsed by libVlc as audio
+func verifyAudioFile(filePath string) error {
+	if !isReadableFile(filePath) {
+		return errors.New("empty or unreadable file")
+	}
+
+	media, err := vlc.NewMediaFromPath(filePath)
+	if err != nil {
+		return err
+	}
+	defer media.Release()
+
+	if err := media.Parse(); err != nil {
+		return err
+	}
+
+	duration, err := media.Duration()
+	if err != nil {
+		return err
+	}
+	if duration <= 0 {
+		return errors.New("could not read audio duration")
+	}
+
+	return nil
+}
+
+func searchCacheDir(fileLoc string) (string, bool) {
+	if fd, err := os.Stat(fileLoc); err != nil || fd.IsDir() {
+		return "", false
+	}
+	return fileLoc, true
+}
+
+// copies all cached audio to exportDir, naming the files as per the template
+// returns the number of files exported
+func (cache *CacheStore) ExportCache(exportDir string, template string) (int, error) {
+	if !cache.isEnabled {
+		return 0, errors.New("caching is disabled")
+	}
+
+	// rebuild to include audio downloaded in this session
+	if err := cache.rebuildCacheMap(); err != nil {
+		return 0, err
+	}
+	cacheMap := cache.getCacheMap()
+
+	// sorted so that the same audio gets the same name on every export
+	ytIds := make([]string, 0, len(cacheMap))
+	for ytId := range cacheMap {
+		ytIds = append(ytIds, ytId)
+	}
+	sort.Strings(ytIds)
+
+	count := 0
+	exportedPaths := make(map[string]bool)
+	for _, ytId := range ytIds {
+		cachePath := cacheMap[ytId]
+		audio := AudioBasic{YtId: ytId, Title: ytId}
+		if audDoc, err := audioDb.GetaudioDoc(ytId); err == nil && audDoc != nil {
+			audio = audDoc.AudioBasic
+		}
+
+		fileName, err := expandExportTemplate(template, audio, filepath.Ext(cachePath))
+		if err != nil {
+			return count, err
+		}
+
+		exportPath, err := exportFile(cachePath, filepath.Join(exportDir, fileName), exportedPaths)
+		if err != nil {
+			cacheLog.Error("Error in exporting file", "path", cachePath, "exportPath", exportPath, "err", err)
+			return count, err
+		}
+		count++
+	}
+
+	return count, nil
+}
+
+// returns the path with a " (n)" suffix if it is already used by the export,
+// ex: Artist/Song.m4a -> Artist/Song (2).m4a. Paths are compared ignoring case,
+// as on windows and mac they are the same file
+func getUniqueExportPath(exportPath string, exportedPaths map[string]bool) string {
+	ext := filepath.Ext(exportPath)
+	base := strings.TrimSuffix(exportPath, ext)
+
+	uniquePath := exportPath
+	for n := 2; exportedPaths[strings.ToLower(uniquePath)]; n++ {
+		uniquePath = fmt.Sprintf("%s (%d)%s", base, n, ext)
+	}
+	exportedPaths[strings.ToLower(uniquePath)] = true
+	return uniquePath
+}
+
+// copies the file to a unique export path and returns it. Files already in the
+// export dir are not overwritten, the copy gets the next " (n)" suffix instead
+func exportFile(srcPath, exportPath string, exportedPaths map[string]bool) (string, error) {
+	if err := os.MkdirAll(filepath.Dir(exportPath), 0755); err != nil {
+		return exportPath, err
+	}
+
+	for {
+		uniquePath := getUniqueExportPath(exportPath, exportedPaths)
+		dst, err := os.OpenFile(uniquePath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if errors.Is(err, fs.ErrExist) {
+			continue
+		}
+		if err != nil {
+			return uniquePath, err
+		}
+
+		if err := copyFile(srcPath, dst); err != nil {
+			os.Remove(uniquePath)
+			return uniquePath, err
+		}
+		return uniquePath, nil
+	}
+}
+
+// copies the file into dst and closes it
+func copyFile(srcPath string, dst *os.File) error {
+	src, err := os.Open(srcPath)
+	if err != nil {
+		dst.Close()
+		return err
+	}
+	defer src.Close()
+
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		return err
+	}
+	return dst.Close()
+}
+
+func downloadFile(filePath, fileUrl string) (string, error) {
+	// intialise download client
+	client := http.Client{
+		CheckRedirect: func(r *http.Request, via []*http.Request) error {
+			r.URL.Opaque = r.URL.Path
+			return nil
+		},
+	}
+
+	// get response
+	resp, err := client.Get(fileUrl)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", errors.New("[downloadFile] bad response: " + resp.Status)
+	}
+
+	// check response file formate
+	mimeType, _, _ := strings.Cut(resp.Header.Get("Content-Type"), ";")
+	if ext, ok := cacheFileExtMap[strings.TrimSpace(mimeType)]; ok {
+		filePath += ext
+	}
+	tmpFilePath := filePath + ".tmp"
+
+	// create temp file
+	file, err := os.Create(tmpFilePath)
+	if err != nil {
+		return "", err
+	}
+
+	// download file
+	size, err := io.Copy(file, resp.Body)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmpFilePath)
+		return "", err
+	}
+
+	// check for truncated body
+	if resp.ContentLength >= 0 && size != resp.ContentLength {
+		os.Remove(tmpFilePath)
+		return "", fmt.Errorf("incomplete download, expected %d bytes got %d", resp.ContentLength, size)
+	}
+
+	cacheLog.Debug("File downloaded", "path", filePath, "size", size)
+	// rename temp file and remove incase of any error
+	err = os.Rename(tmpFilePath, filePath)
+	os.Remove(tmpFilePath)
+	return filePath, err
+}
@@ -0,0 +1,103 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func newTestCache(t *testing.T, files ...string) *CacheStore {
+	t.Helper()
+	dir := t.TempDir()
+	for _, file := range files {
+		if err := os.WriteFile(filepath.Join(dir, file), []byte("audio"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cache := &CacheStore{isEnabled: true}
+	if err := cache.Init(dir); err != nil {
+		t.Fatal(err)
+	}
+	return cache
+}
+
+func TestCacheInit(t *testing.T) {
+	cache := newTestCache(t, "audio000001.m4a", "audio000002.webm", "audio000003.m4a.tmp", "notes.txt")
+
+	cacheMap := cache.getCacheMap()
+	if len(cacheMap) != 2 || cacheMap["audio000001"] == "" || cacheMap["audio000002"] == "" {
+		t.Errorf("cache map = %v, want the m4a and webm files", cacheMap)
+	}
+	if _, err := os.Stat(filepath.Join(cache.cacheDir, "audio000003.m4a.tmp")); !os.IsNotExist(err) {
+		t.Errorf("temp file not removed on init")
+	}
+}
+
+// lookups run on the player while the cache commands rebuild the map
+func TestLookupCacheConcurrent(t *testing.T) {
+	cache := newTestCache(t, "audio000001.m4a")
+	// downloaded after the map was built
+	if err := os.WriteFile(filepath.Join(cache.cacheDir, "audio000002.webm"), []byte("audio"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for _, ytId := range []string{"audio000001", "audio000002"} {
+				if _, ok := cache.LookupCache(AudioBasic{YtId: ytId}); !ok {
+					t.Errorf("%s not found", ytId)
+				}
+			}
+			if _, ok := cache.LookupCache(AudioBasic{YtId: "missing0001"}); ok {
+				t.Errorf("missing audio found")
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			if err := cache.rebuildCacheMap(); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// files already in the export dir are kept, the export gets the next name
+func TestExportCacheExistingFiles(t *testing.T) {
+	setTestAudioDb(t)
+	cache := newTestCache(t, "audio000001.m4a", "audio000002.m4a")
+	exportDir := t.TempDir()
+	existing := filepath.Join(exportDir, "audio000001.m4a")
+	if err := os.WriteFile(existing, []byte("existing"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	count, err := cache.ExportCache(exportDir, "{id}.{ext}")
+	if err != nil {
+		t.Fatalf("export: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("exported = %d, want 2", count)
+	}
+
+	want := map[string]string{
+		"audio000001.m4a":     "existing",
+		"audio000001 (2).m4a": "audio",
+		"audio000002.m4a":     "audio",
+	}
+	entries, _ := os.ReadDir(exportDir)
+	if len(entries) != len(want) {
+		t.Errorf("export dir has %d files, want %d", len(entries), len(want))
+	}
+	for name, content := range want {
+		data, err := os.ReadFile(filepath.Join(exportDir, name))
+		if err != nil || string(data) != content {
+			t.Errorf("%s = %q, %v, want %q", name, data, err, content)
+		}
+	}
+}
@@ -0,0 +1,462 @@
+package app
+
+import (
+	"fmt"
+	"log/slog"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/magiconair/properties"
+)
+
+// ConfigType is the type of the value of a config key
+type ConfigType uint8
+
+const (
+	ConfigString ConfigType = iota
+	ConfigBool
+	ConfigInt
+	ConfigUrl
+	ConfigPath
+)
+
+func (configType ConfigType) String() string {
+	switch configType {
+	case ConfigBool:
+		return "bool"
+	case ConfigInt:
+		return "number"
+	case ConfigUrl:
+		return "url"
+	case ConfigPath:
+		return "path"
+	}
+	return "text"
+}
+
+// ConfigKey describes a key of the properties file
+type ConfigKey struct {
+	Key      string // a key ending with <name> matches any key with the prefix, ex: config.log.level.<subsystem>
+	Type     ConfigType
+	Default  string
+	Help     string
+	Secret   bool                     // the value is hidden in lists and the log
+	Validate func(value string) error // checked after the type, optional
+}
+
+// ConfigEntry is a config key with its value in the properties file
+type ConfigEntry struct {
+	ConfigKey
+	Value string
+	IsSet bool
+}
+
+// ConfigIssue is an unknown key or an invalid value of the properties file
+type ConfigIssue struct {
+	Key     string
+	Message string
+}
+
+func (issue ConfigIssue) String() string {
+	return issue.Key + ": " + issue.Message
+}
+
+// schema of the properties file, in the order shown in help
+var configSchema = []ConfigKey{
+	{Key: pipedApiKey, Type: ConfigUrl, Default: defaultPipedApi, Help: "default piped api to be used"},
+	{Key: instanceListApiKey, Type: ConfigUrl, Default: defaultInstanceListApi, Help: "default instance list api to be used"},
+	{Key: isCacheEnabledKey, Type: ConfigBool, Default: "true", Help: "enable/disable audio caching, enabled by default"},
+	{Key: cacheDirKey, Type: ConfigPath, Help: "path to audio caching, default is cache in the ludo dir"},
+	{Key: cacheExportTemplateKey, Default: defaultExportTemplate, Validate: validateExportTemplate,
+		Help: "file name template for exported songs ({id},{title},{uploader},{duration},{ext}), default is {uploader}/{title}.{ext}"},
+	{Key: dataStoreKey, Type: ConfigPath, Help: "path to db, default is the ludo dir"},
+	{Key: backupCountKey, Type: ConfigInt, Default: strconv.Itoa(defaultBackupCount), Validate: validateMinInt(0),
+		Help: "number of library backups kept in the backups dir, taken on exit and before import, default is 5"},
+	{Key: isSourcePiped, Type: ConfigBool, Default: "true", Help: "enable piped as default source for audio searching"},
+	{Key: streamQualityKey, Default: QualityBest, Validate: validateStreamQuality,
+		Help: "audio stream used for playback and caching (best, worst, opus-preferred, m4a-preferred, max-kbps=N), default is best"},
+	{Key: httpListenKey, Validate: validateListenAddr, Help: "address of the http api for remote control, ex: 127.0.0.1:8080, disabled by default"},
+	{Key: httpTokenKey, Secret: true, Help: "bearer token required by the http api"},
+	{Key: daemonSocketKey, Type: ConfigPath, Help: "unix socket of the daemon, default is ludo.sock in the ludo dir"},
+	{Key: mprisEnabledKey, Type: ConfigBool, Default: "true", Help: "expose the player over mpris on linux, default true"},
+	{Key: logLevelKey, Default: "info", Validate: validateLogLevel, Help: "log level (debug, info, warn, error), default is info"},
+	{Key: logLevelKey + ".<subsystem>", Validate: validateLogLevel, Help: "log level of a subsystem, ex: config.log.level.vlc=debug"},
+	{Key: logFileKey, Type: ConfigPath, Help: "log file, default is ludo.log in the ludo dir"},
+	{Key: logMaxSizeKey, Type: ConfigInt, Default: strconv.Itoa(defaultLogMaxSize), Validate: validateMinInt(1),
+		Help: "size in MB at which the log file is rotated, default is 5"},
+	{Key: logBackupsKey, Type: ConfigInt, Default: strconv.Itoa(defaultLogBackups), Validate: validateMinInt(0),
+		Help: "number of rotated log files kept, default is 3"},
+	{Key: lyricsApiKey, Type: ConfigUrl, Default: defaultLyricsApi, Help: "LRCLIB compatible lyrics api, default is https://lrclib.net"},
+	{Key: listenBrainzTokenKey, Secret: true, Help: "ListenBrainz user token, enables scrobbling of listens"},
+	{Key: listenBrainzApiRootKey, Type: ConfigUrl, Default: defaultListenBrainzApi, Help: "ListenBrainz api root, default is https://api.listenbrainz.org"},
+	{Key: lastFmApiKeyKey, Secret: true, Help: "Last.fm api key, enables scrobbling of listens"},
+	{Key: lastFmApiSecretKey, Secret: true, Help: "Last.fm api secret, used to sign the api calls"},
+	{Key: lastFmSessionKeyKey, Secret: true, Help: "Last.fm session key, else a session is created from username and password or token"},
+	{Key: lastFmUsernameKey, Help: "Last.fm username, used with password to create a session"},
+	{Key: lastFmPasswordKey, Secret: true, Help: "Last.fm password, used with username to create a session"},
+	{Key: lastFmTokenKey, Secret: true, Help: "Last.fm token authorized by the user, used to create a session"},
+	{Key: lastFmApiRootKey, Type: ConfigUrl, Default: defaultLastFmApi, Help: "Last.fm api root, default is https://ws.audioscrobbler.com/2.0/"},
+}
+
+var (
+	propsMu   sync.RWMutex
+	propsPath string
+
+	// issues of the properties file found on init
+	configWarnings []ConfigIssue
+)
+
+// returns the schema of the properties file
+func ConfigSchema() []ConfigKey {
+	return configSchema
+}
+
+// returns the unknown keys and invalid values found in the properties file on init
+func ConfigWarnings() []ConfigIssue {
+	return configWarnings
+}
+
+// returns the properties, safe to read while the config is changed.
+// The config is changed on a copy, so the returned maps are never modified
+func currentProps() properties.Properties {
+	propsMu.RLock()
+	defer propsMu.RUnlock()
+	return props
+}
+
+// replaces the properties and the config, on init
+func setProps(prop *properties.Properties) {
+	propsMu.Lock()
+	defer propsMu.Unlock()
+	props, loadedConfig = *prop, loadConfig(prop)
+}
+
+// returns the schema of the key
+func findConfigKey(key string) (ConfigKey, bool) {
+	for _, configKey := range configSchema {
+		if configKey.matches(key) {
+			return configKey, true
+		}
+	}
+	return ConfigKey{}, false
+}
+
+func (configKey ConfigKey) matches(key string) bool {
+	if prefix, ok := configKey.pattern(); ok {
+		return strings.HasPrefix(key, prefix) && len(key) > len(prefix)
+	}
+	return configKey.Key == key
+}
+
+// returns the prefix of a key ending with <name>
+func (configKey ConfigKey) pattern() (string, bool) {
+	if !strings.HasSuffix(configKey.Key, ">") {
+		return "", false
+	}
+	prefix, _, ok := strings.Cut(configKey.Key, "<")
+	return prefix, ok
+}
+
+// checks the value against the type and validator of the key
+func (configKey ConfigKey) check(value string) error {
+	switch configKey.Type {
+	case ConfigBool:
+		if _, err := parseConfigBool(value); err != nil {
+			return err
+		}
+	case ConfigInt:
+		if _, err := strconv.Atoi(value); err != nil {
+			return fmt.Errorf("expected a number, got '%s'", value)
+		}
+	case ConfigUrl:
+		u, err := url.Parse(value)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("expected an http(s) url, got '%s'", value)
+		}
+	case ConfigPath:
+		if strings.TrimSpace(value) == "" {
+			return fmt.Errorf("expected a path")
+		}
+	}
+
+	if configKey.Validate != nil {
+		return configKey.Validate(value)
+	}
+	return nil
+}
+
+func parseConfigBool(value string) (bool, error) {
+	switch strings.ToLower(value) {
+	case "true", "1", "yes", "on":
+		return true, nil
+	case "false", "0", "no", "off":
+		return false, nil
+	}
+	return false, fmt.Errorf("expected true or false, got '%s'", value)
+}
+
+// returns the value of the key, its default if not set
+func GetConfig(key string) (ConfigEntry, error) {
+	configKey, ok := findConfigKey(key)
+	if !ok {
+		return ConfigEntry{}, unknownKeyErr(key)
+	}
+
+	current := currentProps()
+	entry := ConfigEntry{ConfigKey: configKey, Value: configKey.Default}
+	entry.Key = key
+	if value, ok := current.Get(key); ok {
+		entry.Value, entry.IsSet = value, true
+	}
+	return entry, nil
+}
+
+// returns the keys of the schema with their values, followed by the set keys matching a pattern
+func ListConfig() []ConfigEntry {
+	current := currentProps()
+	entries := make([]ConfigEntry, 0, len(configSchema))
+	for _, configKey := range configSchema {
+		if prefix, ok := configKey.pattern(); ok {
+			keys := current.FilterPrefix(prefix).Keys()
+			sort.Strings(keys)
+			for _, key := range keys {
+				entry := ConfigEntry{ConfigKey: configKey, Value: current.MustGetString(key), IsSet: true}
+				entry.Key = key
+				entries = append(entries, entry)
+			}
+			continue
+		}
+
+		entry := ConfigEntry{ConfigKey: configKey, Value: configKey.Default}
+		if value, ok := current.Get(configKey.Key); ok {
+			entry.Value, entry.IsSet = value, true
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// returns the unknown keys and invalid values of the properties
+func ValidateConfig() []ConfigIssue {
+	current := currentProps()
+	return validateProps(&current)
+}
+
+func validateProps(prop *properties.Properties) []ConfigIssue {
+	var issues []ConfigIssue
+	for _, key := range prop.Keys() {
+		configKey, ok := findConfigKey(key)
+		if !ok {
+			msg := "unknown key"
+			if similar := similarConfigKey(key); similar != "" {
+				msg += ", did you mean " + similar + "?"
+			}
+			issues = append(issues, ConfigIssue{Key: key, Message: msg})
+			continue
+		}
+		if err := configKey.check(prop.MustGetString(key)); err != nil {
+			issues = append(issues, ConfigIssue{Key: key, Message: err.Error()})
+		}
+	}
+	return issues
+}
+
+// validates the config on init, the issues are logged and kept for the frontends
+func checkConfig(prop *properties.Properties) {
+	configWarnings = validateProps(prop)
+	for _, issue := range configWarnings {
+		propLog.Warn("Config issue", "key", issue.Key, "issue", issue.Message)
+	}
+}
+
+// sets the key after validating the value and writes the properties file,
+// returns true if the value is applied now, else it is applied on restart
+func SetConfig(key string, value string) (bool, error) {
+	configKey, ok := findConfigKey(key)
+	if !ok {
+		return false, unknownKeyErr(key)
+	}
+	if err := configKey.check(value); err != nil {
+		return false, fmt.Errorf("%w: %s %w", ErrInvalidConfig, key, err)
+	}
+
+	if err := updateProps(func(prop *properties.Properties) error {
+		_, _, err := prop.Set(key, value)
+		return err
+	}); err != nil {
+		return false, err
+	}
+	propLog.Info("Config set", "key", key, "value", redactProperty(key, value))
+	return applyConfig(key), nil
+}
+
+// removes the key from the properties file, its default is used
+func UnsetConfig(key string) (bool, error) {
+	if _, ok := findConfigKey(key); !ok {
+		return false, unknownKeyErr(key)
+	}
+
+	if err := updateProps(func(prop *properties.Properties) error {
+		if _, ok := prop.Get(key); !ok {
+			return fmt.Errorf("%w: %s is not set", ErrInvalidConfig, key)
+		}
+		prop.Delete(key)
+		return nil
+	}); err != nil {
+		return false, err
+	}
+	propLog.Info("Config unset", "key", key)
+	return applyConfig(key), nil
+}
+
+// changes a copy of the properties, writes it to the properties file and replaces the properties and the config
+func updateProps(change func(prop *properties.Properties) error) error {
+	propsMu.Lock()
+	defer propsMu.Unlock()
+
+	if propsPath == "" {
+		return fmt.Errorf("properties file is not loaded")
+	}
+
+	updated := properties.NewProperties()
+	updated.Merge(&props)
+	if err := change(updated); err != nil {
+		return err
+	}
+	if err := writePropertiesFile(updated, propsPath); err != nil {
+		return fmt.Errorf("error in writing properties file: %w", err)
+	}
+	props, loadedConfig = *updated, loadConfig(updated)
+	return nil
+}
+
+// applies the keys of the changed config which can change while running, returns false for the others
+func applyConfig(key string) bool {
+	cfg := currentConfig()
+	switch key {
+	case pipedApiKey:
+		Piped.SetPipedApi(cfg.pipedApi)
+	case instanceListApiKey:
+		Piped.setInstanceListApi(cfg.instanceListApi)
+	case isSourcePiped, streamQualityKey, cacheExportTemplateKey, backupCountKey, lyricsApiKey:
+		// read when used
+	default:
+		return false
+	}
+	return true
+}
+
+// writes the properties to a temp file which is renamed to the path,
+// so the file is never left half written
+func writePropertiesFile(prop *properties.Properties, path string) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	tmpFile, err := os.CreateTemp(dir, "."+filepath.Base(path)+"-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpFile.Name())
+
+	if _, err := prop.WriteComment(tmpFile, "# ", properties.UTF8); err != nil {
+		tmpFile.Close()
+		return err
+	}
+	if err := tmpFile.Sync(); err != nil {
+		tmpFile.Close()
+		return err
+	}
+	if err := tmpFile.Close(); err != nil {
+		return err
+	}
+	// the file may hold secrets, the temp file is only readable by the user
+	// and an existing file keeps its mode
+	if info, err := os.Stat(path); err == nil {
+		if err := os.Chmod(tmpFile.Name(), info.Mode().Perm()); err != nil {
+			return err
+		}
+	}
+	return os.Rename(tmpFile.Name(), path)
+}
+
+func unknownKeyErr(key string) error {
+	if similar := similarConfigKey(key); similar != "" {
+		return fmt.Errorf("%w: unknown key %s, did you mean %s?", ErrInvalidConfig, key, similar)
+	}
+	return fmt.Errorf("%w: unknown key %s", ErrInvalidConfig, key)
+}
+
+// returns the key of the schema closest to the key, if it is likely a typo
+func similarConfigKey(key string) string {
+	similar, minDist := "", 4
+	for _, configKey := range configSchema {
+		candidate := configKey.Key
+		if prefix, ok := configKey.pattern(); ok {
+			candidate = strings.TrimSuffix(prefix, ".")
+		}
+		if dist := editDistance(strings.ToLower(key), strings.ToLower(candidate)); dist < minDist {
+			similar, minDist = configKey.Key, dist
+		}
+	}
+	return similar
+}
+
+// returns the levenshtein distance of the strings
+func editDistance(a string, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
+
+// Validators
+
+func validateMinInt(minValue int) func(string) error {
+	return func(value string) error {
+		if n, _ := strconv.Atoi(value); n < minValue {
+			return fmt.Errorf("expected %d or more, got %d", minValue, n)
+		}
+		return nil
+	}
+}
+
+func validateListenAddr(value string) error {
+	if _, _, err := net.SplitHostPort(value); err != nil {
+		return fmt.Errorf("expected host:port, got '%s'", value)
+	}
+	return nil
+}
+
+func validateLogLevel(value string) error {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(value)); err != nil {
+		return fmt.Errorf("expected debug, info, warn or error, got '%s'", value)
+	}
+	return nil
+}
+
+func validateExportTemplate(value string) error {
+	_, err := expandExportTemplate(value, AudioBasic{YtId: "id", Title: "title", Uploader: "uploader"}, "mp3")
+	return err
+}
@@ -0,0 +1,60 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/magiconair/properties"
+)
+
+func TestWritePropertiesFileMode(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("file modes are not supported on windows")
+	}
+
+	prop := properties.NewProperties()
+	prop.Set(listenBrainzTokenKey, "secret")
+
+	tests := []struct {
+		name     string
+		existing os.FileMode
+		want     os.FileMode
+	}{
+		{"new file", 0, 0600},
+		{"existing file", 0640, 0640},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), ludoPropertiesFile)
+			if tt.existing != 0 {
+				if err := os.WriteFile(path, nil, tt.existing); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.Chmod(path, tt.existing); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if err := writePropertiesFile(prop, path); err != nil {
+				t.Fatal(err)
+			}
+			info, err := os.Stat(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if mode := info.Mode().Perm(); mode != tt.want {
+				t.Errorf("mode = %o, want %o", mode, tt.want)
+			}
+
+			saved, err := properties.LoadFile(path, properties.UTF8)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if token := saved.GetString(listenBrainzTokenKey, ""); token != "secret" {
+				t.Errorf("token = %q, want secret", token)
+			}
+		})
+	}
+}
@@ -0,0 +1,150 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	crashLogLines     = 200
+	crashCloseTimeout = 5 * time.Second
+	crashExitCode     = 2
+	crashReportPrefix = "crash-"
+)
+
+// the app loggers write here, the last lines are added to the crash reports
+var logTail = &logRing{lines: make([]string, crashLogLines)}
+
+var (
+	crashing   atomic.Bool
+	crashMu    sync.Mutex
+	crashHooks []func()
+)
+
+// HandlePanic is deferred at the goroutine and callback boundaries,
+// the panics of the http requests are recovered by httpRecover instead.
+// On a panic it writes a crash report to the ludo dir, runs the crash hooks,
+// closes the app and exits
+func HandlePanic() {
+	if r := recover(); r != nil {
+		crash(r, debug.Stack())
+	}
+}
+
+// registers a hook run on a crash before the app is closed, ex: restoring the terminal
+func AddCrashHook(hook func()) {
+	crashMu.Lock()
+	defer crashMu.Unlock()
+	crashHooks = append(crashHooks, hook)
+}
+
+func crash(r interface{}, stack []byte) {
+	// a panic of another goroutine is already being handled, wait for the exit
+	if !crashing.CompareAndSwap(false, true) {
+		select {}
+	}
+
+	reportPath, reportErr := writeCrashReport(r, stack)
+	appLog.Error("Crashed", "panic", fmt.Sprint(r), "report", reportPath)
+
+	crashMu.Lock()
+	hooks := crashHooks
+	crashMu.Unlock()
+	for _, hook := range hooks {
+		runCrashStep(hook)
+	}
+
+	// the player may be stuck in the panicking callback, the close is not waited for long
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		runCrashStep(func() { Close() })
+	}()
+	select {
+	case <-closed:
+	case <-time.After(crashCloseTimeout):
+	}
+
+	fmt.Fprintln(os.Stderr, "Ludo crashed:", r)
+	if reportErr != nil {
+		fmt.Fprintln(os.Stderr, "Error in writing crash report:", reportErr)
+		os.Stderr.Write(stack)
+	} else {
+		fmt.Fprintln(os.Stderr, "Crash report written to", reportPath)
+	}
+	os.Exit(crashExitCode)
+}
+
+// runs a step of the crash handling, ignoring its panics
+func runCrashStep(step func()) {
+	defer func() { recover() }()
+	step()
+}
+
+// writes the panic, stack, versions and last log lines to a file in the ludo dir
+func writeCrashReport(r interface{}, stack []byte) (string, error) {
+	crashDir, err := getLudoDir()
+	if err != nil {
+		crashDir = os.TempDir()
+	}
+	if err := os.MkdirAll(crashDir, 0755); err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	var report strings.Builder
+	fmt.Fprintln(&report, "Ludo crash report")
+	fmt.Fprintln(&report, "Time:", now.Format(time.RFC3339))
+	fmt.Fprintln(&report, "Ludo version:", Version)
+	fmt.Fprintln(&report, "Go version:", runtime.Version(), runtime.GOOS+"/"+runtime.GOARCH)
+	fmt.Fprintf(&report, "\nPanic: %v\n\n%s\n", r, stack)
+	fmt.Fprintln(&report, "Last log lines")
+	for _, line := range logTail.tail() {
+		fmt.Fprintln(&report, line)
+	}
+
+	reportPath := filepath.Join(crashDir, crashReportPrefix+now.Format("20060102-150405")+".log")
+	return reportPath, os.WriteFile(reportPath, []byte(report.String()), 0644)
+}
+
+// logRing keeps the last lines written to it
+type logRing struct {
+	mu    sync.Mutex
+	lines []string
+	next  int
+	count int
+}
+
+func (ring *logRing) Write(p []byte) (int, error) {
+	ring.mu.Lock()
+	defer ring.mu.Unlock()
+
+	for _, line := range strings.Split(strings.TrimRight(string(p), "\n"), "\n") {
+		ring.lines[ring.next] = line
+		ring.next = (ring.next + 1) % len(ring.lines)
+		if ring.count < len(ring.lines) {
+			ring.count++
+		}
+	}
+	return len(p), nil
+}
+
+// returns the kept lines, oldest first
+func (ring *logRing) tail() []string {
+	ring.mu.Lock()
+	defer ring.mu.Unlock()
+
+	lines := make([]string, 0, ring.count)
+	start := ring.next - ring.count + len(ring.lines)
+	for i := 0; i < ring.count; i++ {
+		lines = append(lines, ring.lines[(start+i)%len(ring.lines)])
+	}
+	return lines
+}
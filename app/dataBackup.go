@@ -0,0 +1,198 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ostafen/clover/v2/document"
+	"github.com/ostafen/clover/v2/query"
+)
+
+const (
+	backupFilePrefix = "dumps-"
+	backupFileExt    = ".json"
+	backupTimeLayout = "20060102-150405.000"
+)
+
+type ImportReport struct {
+	Added  int
+	Merged int
+}
+
+// audio doc as read from a dump file
+type dumpDoc struct {
+	audioDoc
+	Likes int // like counter of docs saved before like states
+}
+
+// writes all audio docs to the file as a json array
+func (adb *AudioDatastore) ExportDb(filePath string) error {
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		return err
+	}
+	return adb.db.ExportCollection(audioDocCollection, filePath)
+}
+
+// reads the audio docs from a dump file, replacing the library or,
+// if merge is set, combining them with the existing docs of the same id
+func (adb *AudioDatastore) ImportDb(filePath string, merge bool) (ImportReport, error) {
+	var report ImportReport
+
+	audDocs, err := readDumpFile(filePath)
+	if err != nil {
+		return report, err
+	}
+
+	// the library may be changed even if the import fails
+	defer func() {
+		adb.searchIdx.reset()
+		adb.ratingRev.Add(1)
+	}()
+
+	if !merge {
+		if err := adb.db.Delete(query.NewQuery(audioDocCollection)); err != nil {
+			return report, err
+		}
+	}
+
+	for _, audDoc := range audDocs {
+		doc, err := adb.db.FindFirst(query.NewQuery(audioDocCollection).Where(query.Field("YtId").Eq(audDoc.YtId)))
+		if err != nil {
+			return report, err
+		}
+
+		if doc == nil {
+			if _, err := adb.db.InsertOne(audioDocCollection, audDoc.getDocument()); err != nil {
+				return report, err
+			}
+			report.Added++
+			continue
+		}
+
+		currDoc := &audioDoc{}
+		if err := doc.Unmarshal(currDoc); err != nil {
+			return report, err
+		}
+		merged := mergeAudioDocs(currDoc, audDoc)
+
+		err = adb.db.UpdateById(audioDocCollection, doc.ObjectId(), func(doc *document.Document) *document.Document {
+			doc.Set("PlayCount", merged.PlayCount)
+			doc.Set("LastPlay", merged.LastPlay)
+			doc.Set("Like", merged.Like)
+			doc.Set("Rating", merged.Rating)
+			doc.Set("Tags", merged.Tags)
+			return doc
+		})
+		if err != nil {
+			return report, err
+		}
+		report.Merged++
+	}
+
+	return report, nil
+}
+
+// exports the library into the backup dir, keeping only the latest backups
+func (adb *AudioDatastore) BackupDb(backupDir string, keep int) (string, error) {
+	backupPath := filepath.Join(backupDir, backupFilePrefix+time.Now().Format(backupTimeLayout)+backupFileExt)
+	if err := adb.ExportDb(backupPath); err != nil {
+		return "", err
+	}
+	dbLog.Info("Backup created", "path", backupPath)
+
+	if err := rotateBackups(backupDir, keep); err != nil {
+		return backupPath, err
+	}
+	return backupPath, nil
+}
+
+// removes all but the latest keep backups, keep <= 0 retains everything
+func rotateBackups(backupDir string, keep int) error {
+	if keep <= 0 {
+		return nil
+	}
+
+	backups, err := filepath.Glob(filepath.Join(backupDir, backupFilePrefix+"*"+backupFileExt))
+	if err != nil {
+		return err
+	}
+	// names have sortable timestamps, latest last
+	sort.Strings(backups)
+
+	for len(backups) > keep {
+		dbLog.Info("Removing old backup", "path", backups[0])
+		if err := os.Remove(backups[0]); err != nil {
+			return err
+		}
+		backups = backups[1:]
+	}
+	return nil
+}
+
+func readDumpFile(filePath string) ([]*audioDoc, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	var dumpDocs []dumpDoc
+	if err := json.Unmarshal(data, &dumpDocs); err != nil {
+		return nil, fmt.Errorf("invalid dump file: %w", err)
+	}
+
+	audDocs := make([]*audioDoc, 0, len(dumpDocs))
+	for i := range dumpDocs {
+		audDoc := dumpDocs[i].audioDoc
+		if strings.TrimSpace(audDoc.YtId) == "" {
+			return nil, errors.New("invalid dump file: audio without id")
+		}
+		if audDoc.Like == Neutral && dumpDocs[i].Likes > 0 {
+			audDoc.Like = Liked
+		}
+		audDocs = append(audDocs, &audDoc)
+	}
+	return audDocs, nil
+}
+
+// combines two docs of the same audio, so that repeated syncs between
+// machines do not inflate counts: the higher play count, the latest play,
+// a like state or rating set on either side and the tags of both are kept
+func mergeAudioDocs(curr *audioDoc, other *audioDoc) *audioDoc {
+	merged := *curr
+
+	if other.PlayCount > merged.PlayCount {
+		merged.PlayCount = other.PlayCount
+	}
+
+	otherIsLatest := other.LastPlay.After(merged.LastPlay)
+	if otherIsLatest {
+		merged.LastPlay = other.LastPlay
+	}
+
+	if other.Like != Neutral && (merged.Like == Neutral || otherIsLatest) {
+		merged.Like = other.Like
+	}
+	if other.Rating != 0 && (merged.Rating == 0 || otherIsLatest) {
+		merged.Rating = other.Rating
+	}
+
+	tagSet := make(map[string]bool, len(merged.Tags))
+	merged.Tags = append([]string{}, curr.Tags...)
+	for _, tag := range merged.Tags {
+		tagSet[tag] = true
+	}
+	for _, tag := range other.Tags {
+		if !tagSet[tag] {
+			tagSet[tag] = true
+			merged.Tags = append(merged.Tags, tag)
+		}
+	}
+
+	return &merged
+}
@@ -0,0 +1,138 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func saveTestAudioDocs(t *testing.T, adb *AudioDatastore, audDocs ...audioDoc) {
+	t.Helper()
+	for _, audDoc := range audDocs {
+		if _, err := adb.db.InsertOne(audioDocCollection, audDoc.getDocument()); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func getTestAudioDocs(t *testing.T, adb *AudioDatastore) map[string]*audioDoc {
+	t.Helper()
+	audDocs, err := adb.GetAudioList(RecentlyPlayed, 0, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	audDocMap := make(map[string]*audioDoc, len(audDocs))
+	for _, audDoc := range audDocs {
+		audDocMap[audDoc.YtId] = audDoc
+	}
+	return audDocMap
+}
+
+func TestImportDb(t *testing.T) {
+	lastPlay := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+	exported := []audioDoc{
+		{AudioBasic: testAudio(1), PlayCount: 5, LastPlay: lastPlay, Like: Liked, Tags: []string{"chill"}},
+		{AudioBasic: testAudio(2), PlayCount: 1, LastPlay: lastPlay, Rating: 4, Tags: []string{}},
+	}
+	local := []audioDoc{
+		{AudioBasic: testAudio(1), PlayCount: 2, LastPlay: lastPlay.Add(time.Hour), Tags: []string{"focus"}},
+		{AudioBasic: testAudio(3), PlayCount: 1, LastPlay: lastPlay, Tags: []string{}},
+	}
+
+	source := newTestDatastore(t)
+	saveTestAudioDocs(t, source, exported...)
+	dumpPath := filepath.Join(t.TempDir(), "dump.json")
+	if err := source.ExportDb(dumpPath); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("replace", func(t *testing.T) {
+		adb := newTestDatastore(t)
+		saveTestAudioDocs(t, adb, local...)
+
+		report, err := adb.ImportDb(dumpPath, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if report.Added != 2 || report.Merged != 0 {
+			t.Errorf("report = %+v, want 2 added", report)
+		}
+
+		audDocs := getTestAudioDocs(t, adb)
+		if len(audDocs) != 2 || audDocs[testAudio(3).YtId] != nil {
+			t.Fatalf("docs = %v, want only the imported docs", audDocs)
+		}
+		if audDoc := audDocs[testAudio(1).YtId]; audDoc.PlayCount != 5 || audDoc.Like != Liked || !audDoc.LastPlay.Equal(lastPlay) {
+			t.Errorf("imported doc = %+v", audDoc)
+		}
+	})
+
+	t.Run("merge", func(t *testing.T) {
+		adb := newTestDatastore(t)
+		saveTestAudioDocs(t, adb, local...)
+
+		report, err := adb.ImportDb(dumpPath, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if report.Added != 1 || report.Merged != 1 {
+			t.Errorf("report = %+v, want 1 added and 1 merged", report)
+		}
+
+		audDocs := getTestAudioDocs(t, adb)
+		if len(audDocs) != 3 {
+			t.Fatalf("docs = %d, want 3", len(audDocs))
+		}
+		merged := audDocs[testAudio(1).YtId]
+		if merged.PlayCount != 5 || merged.Like != Liked || !merged.LastPlay.Equal(lastPlay.Add(time.Hour)) || len(merged.Tags) != 2 {
+			t.Errorf("merged doc = %+v", merged)
+		}
+	})
+
+	t.Run("invalid dump", func(t *testing.T) {
+		adb := newTestDatastore(t)
+		saveTestAudioDocs(t, adb, local...)
+
+		invalidPath := filepath.Join(t.TempDir(), "invalid.json")
+		if err := os.WriteFile(invalidPath, []byte(`[{"Title": "no id"}]`), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := adb.ImportDb(invalidPath, false); err == nil {
+			t.Fatal("no error for a dump without ids")
+		}
+		if audDocs := getTestAudioDocs(t, adb); len(audDocs) != 2 {
+			t.Errorf("docs = %d, want the library unchanged", len(audDocs))
+		}
+	})
+}
+
+// an import failing midway is undone by importing the backup
+func TestBackupRestore(t *testing.T) {
+	adb := newTestDatastore(t)
+	saveTestAudioDocs(t, adb,
+		audioDoc{AudioBasic: testAudio(1), PlayCount: 3, LastPlay: time.Now().UTC().Truncate(time.Second), Rating: 5, Tags: []string{"chill"}},
+		audioDoc{AudioBasic: testAudio(2), PlayCount: 1, LastPlay: time.Now().UTC().Truncate(time.Second), Tags: []string{}},
+	)
+	before := getTestAudioDocs(t, adb)
+
+	backupPath, err := adb.BackupDb(t.TempDir(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := adb.ImportDb(backupPath, false); err != nil {
+		t.Fatal(err)
+	}
+
+	after := getTestAudioDocs(t, adb)
+	if len(after) != len(before) {
+		t.Fatalf("docs = %d, want %d", len(after), len(before))
+	}
+	for ytId, audDoc := range before {
+		restored := after[ytId]
+		if restored == nil || restored.PlayCount != audDoc.PlayCount || restored.Rating != audDoc.Rating ||
+			!restored.LastPlay.Equal(audDoc.LastPlay) || len(restored.Tags) != len(audDoc.Tags) {
+			t.Errorf("restored doc = %+v, want %+v", restored, audDoc)
+		}
+	}
+}
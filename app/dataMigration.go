@@ -0,0 +1,160 @@
+package app
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ostafen/clover/v2"
+	"github.com/ostafen/clover/v2/document"
+	"github.com/ostafen/clover/v2/query"
+)
+
+const (
+	metadataCollection = "metadata"
+	schemaVersionKey   = "schemaVersion"
+)
+
+// migration upgrades the documents of the datastore to its version
+type migration struct {
+	version     int
+	description string
+	migrate     func(db *clover.DB) error
+}
+
+// ordered list of migrations, the last version is the current schema
+var migrations = []migration{
+	{1, "store numeric fields as integers and play time as time", normalizeAudioDocTypes},
+	{2, "replace like counter with like state", migrateLikeCounter},
+	{3, "add missing like, rating and tags fields", addAudioDocDefaults},
+}
+
+func latestSchemaVersion() int {
+	return migrations[len(migrations)-1].version
+}
+
+// runs the pending migrations in order, after taking a backup of the library
+func (adb *AudioDatastore) migrate(backupDir string) error {
+	version, err := adb.getSchemaVersion()
+	if err != nil {
+		return err
+	}
+	if version >= latestSchemaVersion() {
+		return nil
+	}
+
+	count, err := adb.db.Count(query.NewQuery(audioDocCollection))
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		backupPath, err := adb.BackupDb(backupDir, 0)
+		if err != nil {
+			return fmt.Errorf("backup before migration failed: %w", err)
+		}
+		dbLog.Info("Backup before migration", "path", backupPath)
+	}
+
+	for _, m := range migrations {
+		if m.version <= version {
+			continue
+		}
+
+		dbLog.Info("Migrating schema", "version", m.version, "description", m.description)
+		if err := m.migrate(adb.db); err != nil {
+			return fmt.Errorf("migration to schema v%d failed: %w", m.version, err)
+		}
+		if err := adb.setSchemaVersion(m.version); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// returns the schema version of the datastore, 0 if it was never migrated
+func (adb *AudioDatastore) getSchemaVersion() (int, error) {
+	doc, err := adb.db.FindFirst(query.NewQuery(metadataCollection).Where(query.Field("Key").Eq(schemaVersionKey)))
+	if err != nil || doc == nil {
+		return 0, err
+	}
+	return getDocInt(doc, "Value"), nil
+}
+
+func (adb *AudioDatastore) setSchemaVersion(version int) error {
+	return adb.setMetadata(schemaVersionKey, version)
+}
+
+// returns the string value of the metadata key, empty if not set
+func (adb *AudioDatastore) getMetadataString(key string) (string, error) {
+	doc, err := adb.db.FindFirst(query.NewQuery(metadataCollection).Where(query.Field("Key").Eq(key)))
+	if err != nil || doc == nil {
+		return "", err
+	}
+	value, _ := doc.Get("Value").(string)
+	return value, nil
+}
+
+// sets the value of the metadata key, replacing the old value
+func (adb *AudioDatastore) setMetadata(key string, value interface{}) error {
+	q := query.NewQuery(metadataCollection).Where(query.Field("Key").Eq(key))
+	if err := adb.db.Delete(q); err != nil {
+		return err
+	}
+
+	doc := document.NewDocument()
+	doc.Set("Key", key)
+	doc.Set("Value", value)
+	_, err := adb.db.InsertOne(metadataCollection, doc)
+	return err
+}
+
+// Migrations
+
+// docs imported from json have float numbers and string times
+func normalizeAudioDocTypes(db *clover.DB) error {
+	return db.UpdateFunc(query.NewQuery(audioDocCollection), func(doc *document.Document) *document.Document {
+		for _, field := range []string{"Duration", "PlayCount", "Like", "Rating", "Likes"} {
+			if doc.Has(field) {
+				doc.Set(field, getDocInt(doc, field))
+			}
+		}
+
+		if lastPlay, ok := doc.Get("LastPlay").(string); ok {
+			playTime, err := time.Parse(time.RFC3339Nano, lastPlay)
+			if err != nil {
+				dbLog.Warn("Invalid LastPlay", "ytId", doc.Get("YtId"), "lastPlay", lastPlay)
+			}
+			doc.Set("LastPlay", playTime)
+		}
+		return doc
+	})
+}
+
+// docs saved before like states have a Likes counter
+func migrateLikeCounter(db *clover.DB) error {
+	return db.UpdateFunc(query.NewQuery(audioDocCollection).Where(query.Field("Likes").Exists()), func(doc *document.Document) *document.Document {
+		fields := doc.AsMap()
+		if !doc.Has("Like") && getDocInt(doc, "Likes") > 0 {
+			fields["Like"] = Liked
+		}
+		delete(fields, "Likes")
+		return document.NewDocumentOf(fields)
+	})
+}
+
+func addAudioDocDefaults(db *clover.DB) error {
+	return db.UpdateFunc(query.NewQuery(audioDocCollection), func(doc *document.Document) *document.Document {
+		defaults := map[string]interface{}{
+			"Like":      Neutral,
+			"Rating":    0,
+			"Tags":      []string{},
+			"PlayCount": 0,
+		}
+		for field, value := range defaults {
+			if !doc.Has(field) {
+				doc.Set(field, value)
+			}
+		}
+		return doc
+	})
+}
@@ -0,0 +1,162 @@
+package app
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ostafen/clover/v2"
+	"github.com/ostafen/clover/v2/document"
+	"github.com/ostafen/clover/v2/query"
+)
+
+var v0LastPlay = time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+
+// writes a datastore as saved before the migrations, numbers are floats,
+// the last play is a string and likes are a counter
+func seedV0Datastore(t *testing.T, dir string) {
+	t.Helper()
+	db, err := clover.Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if err := db.CreateCollection(audioDocCollection); err != nil {
+		t.Fatal(err)
+	}
+
+	v0Docs := []map[string]interface{}{
+		{"YtId": "liked000001", "Title": "Liked", "Uploader": "Uploader", "Duration": 200.0, "PlayCount": 3.0, "LastPlay": v0LastPlay.Format(time.RFC3339Nano), "Likes": 2.0},
+		{"YtId": "neutral0001", "Title": "Neutral", "Uploader": "Uploader", "Duration": 100.0, "PlayCount": 1.0, "LastPlay": v0LastPlay.Format(time.RFC3339Nano), "Likes": 0.0},
+		{"YtId": "noplays0001", "Title": "No plays", "Uploader": "Uploader", "Duration": 50.0},
+	}
+	for _, fields := range v0Docs {
+		if _, err := db.InsertOne(audioDocCollection, document.NewDocumentOf(fields)); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func assertV3Datastore(t *testing.T, adb *AudioDatastore) {
+	t.Helper()
+
+	version, err := adb.getSchemaVersion()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != latestSchemaVersion() {
+		t.Errorf("schema version = %d, want %d", version, latestSchemaVersion())
+	}
+
+	docs, err := adb.db.FindAll(query.NewQuery(audioDocCollection))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(docs) != 3 {
+		t.Fatalf("docs = %d, want 3", len(docs))
+	}
+	for _, doc := range docs {
+		for _, field := range []string{"Duration", "PlayCount", "Like", "Rating"} {
+			if _, isFloat := doc.Get(field).(float64); isFloat || !doc.Has(field) {
+				t.Errorf("%s: %s = %#v, want an integer", doc.Get("YtId"), field, doc.Get(field))
+			}
+		}
+		if doc.Has("Likes") {
+			t.Errorf("%s: the like counter was not removed", doc.Get("YtId"))
+		}
+		if !doc.Has("Tags") {
+			t.Errorf("%s: no tags", doc.Get("YtId"))
+		}
+	}
+
+	tests := []struct {
+		ytId      string
+		playCount int
+		like      LikeState
+		lastPlay  time.Time
+	}{
+		{"liked000001", 3, Liked, v0LastPlay},
+		{"neutral0001", 1, Neutral, v0LastPlay},
+		{"noplays0001", 0, Neutral, time.Time{}},
+	}
+	for _, tt := range tests {
+		audDoc, err := adb.GetaudioDoc(tt.ytId)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.ytId, err)
+		}
+		if audDoc.PlayCount != tt.playCount || audDoc.Like != tt.like || audDoc.Rating != 0 || len(audDoc.Tags) != 0 {
+			t.Errorf("%s: playCount=%d like=%d rating=%d tags=%v, want playCount=%d like=%d", tt.ytId, audDoc.PlayCount, audDoc.Like, audDoc.Rating, audDoc.Tags, tt.playCount, tt.like)
+		}
+		if !audDoc.LastPlay.Equal(tt.lastPlay) {
+			t.Errorf("%s: last play = %v, want %v", tt.ytId, audDoc.LastPlay, tt.lastPlay)
+		}
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	dir := t.TempDir()
+	backupDir := filepath.Join(dir, "backup")
+	seedV0Datastore(t, dir)
+
+	adb := &AudioDatastore{}
+	if err := adb.InitDb(dir, backupDir); err != nil {
+		t.Fatalf("init db: %v", err)
+	}
+	defer adb.CloseDb()
+	assertV3Datastore(t, adb)
+
+	backups, err := filepath.Glob(filepath.Join(backupDir, backupFilePrefix+"*"+backupFileExt))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backups) != 1 {
+		t.Fatalf("backups = %v, want one backup before the migration", backups)
+	}
+	backupDocs, err := readDumpFile(backups[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backupDocs) != 3 {
+		t.Errorf("backup docs = %d, want 3", len(backupDocs))
+	}
+
+	// a migrated datastore is not migrated or backed up again
+	if err := adb.migrate(backupDir); err != nil {
+		t.Fatalf("second migrate: %v", err)
+	}
+	assertV3Datastore(t, adb)
+	if backups, _ := filepath.Glob(filepath.Join(backupDir, "*")); len(backups) != 1 {
+		t.Errorf("backups = %v, want no new backup", backups)
+	}
+
+	// the migrations give the same docs when run again
+	for _, m := range migrations {
+		if err := m.migrate(adb.db); err != nil {
+			t.Fatalf("migration v%d again: %v", m.version, err)
+		}
+	}
+	assertV3Datastore(t, adb)
+}
+
+func TestMigrateEmptyDatastore(t *testing.T) {
+	dir := t.TempDir()
+	backupDir := filepath.Join(dir, "backup")
+
+	adb := &AudioDatastore{}
+	if err := adb.InitDb(dir, backupDir); err != nil {
+		t.Fatalf("init db: %v", err)
+	}
+	defer adb.CloseDb()
+
+	version, err := adb.getSchemaVersion()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != latestSchemaVersion() {
+		t.Errorf("schema version = %d, want %d", version, latestSchemaVersion())
+	}
+	if backups, _ := filepath.Glob(filepath.Join(backupDir, "*")); len(backups) != 0 {
+		t.Errorf("backups = %v, want none for an empty datastore", backups)
+	}
+}
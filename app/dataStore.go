@@ -1,154 +1,494 @@
-package app
-
-import (
-	"io"
-	"log"
-	"time"
-
-	"github.com/ostafen/clover/v2"
-	"github.com/ostafen/clover/v2/document"
-	"github.com/ostafen/clover/v2/query"
-)
-
-var dbLog = log.New(io.Discard, "audioDb: ", log.LstdFlags|log.Lmsgprefix)
-
-var audioDb AudioDatastore
-
-type AudioDatastore struct {
-	db *clover.DB
-}
-
-const (
-	audioDocCollection = "audioDocs"
-	playListCollection = "playlists"
-)
-
-func (adb *AudioDatastore) InitDb(path string) error {
-	db, err := clover.Open(path)
-	if err != nil {
-		return err
-	}
-	adb.db = db
-
-	if ok, _ := db.HasCollection(audioDocCollection); !ok {
-		db.CreateCollection(audioDocCollection)
-	}
-
-	return nil
-}
-
-func (adb *AudioDatastore) CloseDb() error {
-	if adb.db == nil {
-		return nil
-	}
-	return adb.db.Close()
-}
-
-// Audio Doc collection
-
-func (adb *AudioDatastore) GetaudioDoc(ytId string) (*audioDoc, error) {
-	audDoc, err := GetaudioDoc(adb.db.FindFirst(query.NewQuery(audioDocCollection).Where(query.Field("YtId").Eq(ytId))))
-	if err != nil {
-		return nil, err
-	}
-	return audDoc, nil
-}
-
-func (adb *AudioDatastore) IsAudioDocExist(ytId string) (bool, error) {
-	ok, err := adb.db.Exists(query.NewQuery(audioDocCollection).Where(query.Field("YtId").Eq(ytId)))
-	if err != nil {
-		return false, err
-	}
-	return ok, nil
-}
-
-func (adb *AudioDatastore) SaveAudioDoc(aud AudioBasic) error {
-	audioDoc := NewaudioDoc(aud)
-	_, err := adb.db.InsertOne(audioDocCollection, audioDoc.getDocument())
-	return err
-}
-
-func (adb *AudioDatastore) UpdateListened(ytId string) error {
-	doc, err := adb.db.FindFirst(query.NewQuery(audioDocCollection).Where(query.Field("YtId").Eq(ytId)))
-	if err != nil {
-		return err
-	}
-
-	id := doc.ObjectId()
-
-	currPlayed, ok := doc.Get("PlayCount").(int64)
-	if !ok {
-		currPlayed = 0
-	}
-	played := currPlayed + 1
-
-	err = adb.db.UpdateById(audioDocCollection, id, func(doc *document.Document) *document.Document {
-		dbLog.Println("Before:", doc.AsMap())
-		doc.Set("PlayCount", played)
-		doc.Set("LastPlay", time.Now())
-		dbLog.Println("After:", doc.AsMap())
-		return doc
-	})
-
-	dbLog.Println("error:", err)
-
-	return err
-}
-
-func (adb *AudioDatastore) SaveOrIncrementAudioDoc(aud AudioBasic) error {
-	ok, err := adb.IsAudioDocExist(aud.YtId)
-	if err != nil {
-		return err
-	}
-	if ok {
-		return adb.UpdateListened(aud.YtId)
-	}
-	return adb.SaveAudioDoc(aud)
-}
-
-func (adb *AudioDatastore) UpdateLikes(ytId string) error {
-	doc, err := adb.db.FindFirst(query.NewQuery(audioDocCollection).Where(query.Field("YtId").Eq(ytId)))
-	if err != nil {
-		return err
-	}
-
-	id := doc.ObjectId()
-	currLikes, ok := doc.Get("Likes").(int64)
-	if !ok {
-		currLikes = 0
-	}
-	likes := currLikes + 1
-
-	err = adb.db.UpdateById(audioDocCollection, id, func(doc *document.Document) *document.Document {
-		doc.Set("Likes", likes)
-		return doc
-	})
-
-	return err
-}
-
-func (adb *AudioDatastore) GetAudioList(crit AudioListCriteria, offset int, limit int) ([]*audioDoc, error) {
-	q := query.NewQuery(audioDocCollection)
-
-	if crit == RecentlyPlayed {
-		q = q.Sort(query.SortOption{
-			Field: "LastPlay", Direction: -1,
-		})
-	} else if crit == MostPlayed {
-		q = q.Sort(query.SortOption{
-			Field: "PlayCount", Direction: -1,
-		})
-	} else if crit == MostLikes {
-		q = q.Where(query.Field("Likes").Gt(0)).Sort(query.SortOption{
-			Field: "LastPlay", Direction: -1,
-		})
-	}
-
-	q = q.Skip(offset).Limit(limit)
-
-	audDocs, err := GetaudioDocList(adb.db.FindAll(q))
-	if err != nil {
-		return nil, err
-	}
-	return audDocs, nil
-}
+package app
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ostafen/clover/v2"
+	"github.com/ostafen/clover/v2/document"
+	"github.com/ostafen/clover/v2/query"
+)
+
+var dbLog = newLogger("audioDb")
+
+var audioDb AudioDatastore
+
+type AudioDatastore struct {
+	db        *clover.DB
+	upsertMu  sync.Mutex // clover has no unique index, the upserts of audio docs are serialised
+	ratingRev atomic.Int64
+	searchIdx searchIndex
+}
+
+const (
+	audioDocCollection  = "audioDocs"
+	playListCollection  = "playlists"
+	playEventCollection = "playEvents"
+	scrobbleCollection  = "scrobbleQueue"
+	lyricsCollection    = "lyrics"
+)
+
+// opens the datastore at path and migrates it to the latest schema,
+// backups before migration are written to backupDir.
+// Fails with ErrLibraryLocked if another ludo has it open
+func (adb *AudioDatastore) InitDb(path string, backupDir string) error {
+	db, err := openCloverDb(path, dbLockTimeout)
+	if err != nil {
+		return err
+	}
+	adb.db = db
+
+	if ok, _ := db.HasCollection(audioDocCollection); !ok {
+		db.CreateCollection(audioDocCollection)
+	}
+
+	if ok, _ := db.HasCollection(playListCollection); !ok {
+		db.CreateCollection(playListCollection)
+	}
+
+	if ok, _ := db.HasCollection(playEventCollection); !ok {
+		db.CreateCollection(playEventCollection)
+	}
+
+	if ok, _ := db.HasCollection(scrobbleCollection); !ok {
+		db.CreateCollection(scrobbleCollection)
+	}
+
+	if ok, _ := db.HasCollection(lyricsCollection); !ok {
+		db.CreateCollection(lyricsCollection)
+	}
+
+	if ok, _ := db.HasCollection(metadataCollection); !ok {
+		db.CreateCollection(metadataCollection)
+	}
+
+	if err := adb.migrate(backupDir); err != nil {
+		return err
+	}
+
+	return adb.ensureIndexes()
+}
+
+// creates the indexes used by lookups by id and by the sorted audio lists
+func (adb *AudioDatastore) ensureIndexes() error {
+	indexes := []struct{ collection, field string }{
+		{audioDocCollection, "YtId"},
+		{audioDocCollection, "LastPlay"},
+		{audioDocCollection, "PlayCount"},
+		{playEventCollection, "StartTime"},
+		{lyricsCollection, "YtId"},
+	}
+
+	for _, idx := range indexes {
+		ok, err := adb.db.HasIndex(idx.collection, idx.field)
+		if err != nil {
+			return err
+		}
+		if ok {
+			continue
+		}
+
+		dbLog.Debug("Creating index", "collection", idx.collection, "field", idx.field)
+		if err := adb.db.CreateIndex(idx.collection, idx.field); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (adb *AudioDatastore) CloseDb() error {
+	if adb.db == nil {
+		return nil
+	}
+	return adb.db.Close()
+}
+
+// Audio Doc collection
+
+func (adb *AudioDatastore) GetaudioDoc(ytId string) (*audioDoc, error) {
+	audDoc, err := GetaudioDoc(adb.db.FindFirst(query.NewQuery(audioDocCollection).Where(query.Field("YtId").Eq(ytId))))
+	if err != nil {
+		return nil, err
+	}
+	return audDoc, nil
+}
+
+func (adb *AudioDatastore) IsAudioDocExist(ytId string) (bool, error) {
+	ok, err := adb.db.Exists(query.NewQuery(audioDocCollection).Where(query.Field("YtId").Eq(ytId)))
+	if err != nil {
+		return false, err
+	}
+	return ok, nil
+}
+
+func (adb *AudioDatastore) SaveAudioDoc(aud AudioBasic) error {
+	audioDoc := NewaudioDoc(aud)
+	_, err := adb.db.InsertOne(audioDocCollection, audioDoc.getDocument())
+	if err == nil {
+		adb.searchIdx.add(aud)
+	}
+	return err
+}
+
+func (adb *AudioDatastore) UpdateListened(ytId string) error {
+	q := query.NewQuery(audioDocCollection).Where(query.Field("YtId").Eq(ytId))
+	return adb.db.UpdateFunc(q, func(doc *document.Document) *document.Document {
+		incrementListened(doc)
+		return doc
+	})
+}
+
+// saves the audio doc if it does not exist, else increments its play count
+func (adb *AudioDatastore) SaveOrIncrementAudioDoc(aud AudioBasic) error {
+	return adb.upsertAudioDoc(aud, incrementListened)
+}
+
+func (adb *AudioDatastore) SetLike(aud AudioBasic, like LikeState) error {
+	if like < Disliked || like > Liked {
+		return errors.New("invalid like state")
+	}
+	return adb.updateAudioDocField(aud, "Like", like)
+}
+
+// sets the star rating (1-5) of the audio, 0 clears the rating
+func (adb *AudioDatastore) SetRating(aud AudioBasic, rating int) error {
+	if rating < 0 || rating > maxRating {
+		return fmt.Errorf("invalid rating, must be between 0 and %d", maxRating)
+	}
+	return adb.updateAudioDocField(aud, "Rating", rating)
+}
+
+// returns the audio docs of the given ids, mapped by id
+func (adb *AudioDatastore) GetAudioDocMap(ytIds []string) (map[string]*audioDoc, error) {
+	ids := make([]interface{}, len(ytIds))
+	for i, ytId := range ytIds {
+		ids[i] = ytId
+	}
+
+	audDocs, err := GetaudioDocList(adb.db.FindAll(query.NewQuery(audioDocCollection).Where(query.Field("YtId").In(ids...))))
+	if err != nil {
+		return nil, err
+	}
+
+	audDocMap := make(map[string]*audioDoc, len(audDocs))
+	for _, audDoc := range audDocs {
+		audDocMap[audDoc.YtId] = audDoc
+	}
+	return audDocMap, nil
+}
+
+// returns the ids of all disliked audio
+func (adb *AudioDatastore) GetDislikedIds() (map[string]bool, error) {
+	audDocs, err := GetaudioDocList(adb.db.FindAll(query.NewQuery(audioDocCollection).Where(query.Field("Like").Eq(Disliked))))
+	if err != nil {
+		return nil, err
+	}
+
+	dislikedIds := make(map[string]bool, len(audDocs))
+	for _, audDoc := range audDocs {
+		dislikedIds[audDoc.YtId] = true
+	}
+	return dislikedIds, nil
+}
+
+// counter which changes whenever a like or rating is updated
+func (adb *AudioDatastore) RatingRevision() int64 {
+	return adb.ratingRev.Load()
+}
+
+// sets the field of the audio doc, the doc is created if it does not exist
+func (adb *AudioDatastore) updateAudioDocField(aud AudioBasic, field string, value interface{}) error {
+	err := adb.upsertAudioDoc(aud, func(doc *document.Document) {
+		doc.Set(field, value)
+	})
+
+	if err == nil {
+		adb.ratingRev.Add(1)
+	}
+	return err
+}
+
+// applies the update to the audio doc, the doc is created if it does not exist.
+// An existing doc is found by the YtId index and updated in one transaction,
+// a new doc takes a second transaction to insert. Clover cannot run both in
+// one transaction, so the lock keeps concurrent upserts from inserting twice
+func (adb *AudioDatastore) upsertAudioDoc(aud AudioBasic, update func(doc *document.Document)) error {
+	adb.upsertMu.Lock()
+	defer adb.upsertMu.Unlock()
+
+	found := false
+	q := query.NewQuery(audioDocCollection).Where(query.Field("YtId").Eq(aud.YtId))
+	err := adb.db.UpdateFunc(q, func(doc *document.Document) *document.Document {
+		found = true
+		update(doc)
+		return doc
+	})
+	if err != nil || found {
+		return err
+	}
+
+	audDoc := audioDoc{AudioBasic: aud}
+	doc := audDoc.getDocument()
+	update(doc)
+	if _, err = adb.db.InsertOne(audioDocCollection, doc); err == nil {
+		adb.searchIdx.add(aud)
+	}
+	return err
+}
+
+// adds the tag to the audio, tags are stored in lower case
+func (adb *AudioDatastore) AddTag(aud AudioBasic, tag string) error {
+	tag = normalizeTag(tag)
+	if tag == "" {
+		return errors.New("empty tag")
+	}
+
+	return adb.upsertAudioDoc(aud, func(doc *document.Document) {
+		tags := getDocTags(doc)
+		for _, t := range tags {
+			if t == tag {
+				return
+			}
+		}
+		doc.Set("Tags", append(tags, tag))
+	})
+}
+
+func (adb *AudioDatastore) RemoveTag(aud AudioBasic, tag string) error {
+	tag = normalizeTag(tag)
+
+	return adb.upsertAudioDoc(aud, func(doc *document.Document) {
+		tags := getDocTags(doc)
+		newTags := make([]string, 0, len(tags))
+		for _, t := range tags {
+			if t != tag {
+				newTags = append(newTags, t)
+			}
+		}
+		doc.Set("Tags", newTags)
+	})
+}
+
+func (adb *AudioDatastore) GetAudioList(crit AudioListCriteria, offset int, limit int) ([]*audioDoc, error) {
+	q := query.NewQuery(audioDocCollection)
+
+	if crit == RecentlyPlayed {
+		q = q.Sort(query.SortOption{
+			Field: "LastPlay", Direction: -1,
+		})
+	} else if crit == MostPlayed {
+		q = q.Sort(query.SortOption{
+			Field: "PlayCount", Direction: -1,
+		})
+	} else if crit == MostLikes {
+		q = q.Where(query.Field("Like").Eq(Liked)).Sort(query.SortOption{
+			Field: "Rating", Direction: -1,
+		}, query.SortOption{
+			Field: "LastPlay", Direction: -1,
+		})
+	}
+
+	q = q.Skip(offset).Limit(limit)
+
+	audDocs, err := GetaudioDocList(adb.db.FindAll(q))
+	if err != nil {
+		return nil, err
+	}
+	return audDocs, nil
+}
+
+// returns the audio list of a criteria (recent, plays, likes) or of a smart playlist name
+func (adb *AudioDatastore) GetNamedAudioList(name string, offset int, limit int) ([]*audioDoc, error) {
+	switch name {
+	case "recent":
+		return adb.GetAudioList(RecentlyPlayed, offset, limit)
+	case "plays":
+		return adb.GetAudioList(MostPlayed, offset, limit)
+	case "likes":
+		return adb.GetAudioList(MostLikes, offset, limit)
+	}
+	return adb.GetSmartPlaylistAudio(name, offset, limit)
+}
+
+// Play event collection
+
+func (adb *AudioDatastore) SavePlayEvent(event *PlayEvent) error {
+	_, err := adb.db.InsertOne(playEventCollection, document.NewDocumentOf(event))
+	return err
+}
+
+// returns play events started within [from, to), latest first.
+// A zero from has no lower bound, clover does not match the zero time
+func (adb *AudioDatastore) GetPlayEvents(from time.Time, to time.Time, offset int, limit int) ([]*PlayEvent, error) {
+	criteria := query.Field("StartTime").Lt(to)
+	if !from.IsZero() {
+		criteria = query.Field("StartTime").GtEq(from).And(criteria)
+	}
+	q := query.NewQuery(playEventCollection).
+		Where(criteria).
+		Sort(query.SortOption{Field: "StartTime", Direction: -1}).
+		Skip(offset).Limit(limit)
+
+	return GetPlayEventList(adb.db.FindAll(q))
+}
+
+// Scrobble queue collection
+
+func (adb *AudioDatastore) SaveScrobble(scrobble *Scrobble) error {
+	id, err := adb.db.InsertOne(scrobbleCollection, document.NewDocumentOf(scrobble))
+	scrobble.id = id
+	return err
+}
+
+// returns the queued listens of the service, oldest first
+func (adb *AudioDatastore) GetScrobbles(service string, limit int) ([]*Scrobble, error) {
+	docs, err := adb.db.FindAll(query.NewQuery(scrobbleCollection).
+		Where(query.Field("Service").Eq(service)).
+		Sort(query.SortOption{Field: "ListenedAt", Direction: 1}).
+		Limit(limit))
+	if err != nil {
+		return nil, err
+	}
+
+	scrobbleList := make([]*Scrobble, len(docs))
+	for i, doc := range docs {
+		scrobbleList[i] = &Scrobble{id: doc.ObjectId()}
+		if err := doc.Unmarshal(scrobbleList[i]); err != nil {
+			return nil, err
+		}
+	}
+	return scrobbleList, nil
+}
+
+func (adb *AudioDatastore) CountScrobbles(service string) (int, error) {
+	return adb.db.Count(query.NewQuery(scrobbleCollection).Where(query.Field("Service").Eq(service)))
+}
+
+func (adb *AudioDatastore) IncrementScrobbleAttempts(scrobbleList []*Scrobble) error {
+	for _, scrobble := range scrobbleList {
+		err := adb.db.UpdateById(scrobbleCollection, scrobble.id, func(doc *document.Document) *document.Document {
+			doc.Set("Attempts", getDocInt(doc, "Attempts")+1)
+			return doc
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (adb *AudioDatastore) DeleteScrobbles(scrobbleList []*Scrobble) error {
+	for _, scrobble := range scrobbleList {
+		if err := adb.db.DeleteById(scrobbleCollection, scrobble.id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Lyrics collection
+
+// returns the saved lyrics of the audio, nil if not saved
+func (adb *AudioDatastore) GetLyrics(ytId string) (*Lyrics, error) {
+	doc, err := adb.db.FindFirst(query.NewQuery(lyricsCollection).Where(query.Field("YtId").Eq(ytId)))
+	if err != nil || doc == nil {
+		return nil, err
+	}
+
+	lyrics := &Lyrics{}
+	if err := doc.Unmarshal(lyrics); err != nil {
+		return nil, err
+	}
+	return lyrics, nil
+}
+
+func (adb *AudioDatastore) SaveLyrics(lyrics *Lyrics) error {
+	if err := adb.db.Delete(query.NewQuery(lyricsCollection).Where(query.Field("YtId").Eq(lyrics.YtId))); err != nil {
+		return err
+	}
+	_, err := adb.db.InsertOne(lyricsCollection, document.NewDocumentOf(lyrics))
+	return err
+}
+
+// Playlist collection
+
+// saves the smart playlist, replacing any playlist with the same name
+func (adb *AudioDatastore) SaveSmartPlaylist(name string, smartQuery string) error {
+	if name == "" {
+		return errors.New("empty playlist name")
+	}
+	if _, err := ParseSmartQuery(smartQuery); err != nil {
+		return err
+	}
+
+	if err := adb.db.Delete(query.NewQuery(playListCollection).Where(query.Field("Name").Eq(name))); err != nil {
+		return err
+	}
+	_, err := adb.db.InsertOne(playListCollection, document.NewDocumentOf(SmartPlaylist{Name: name, Query: smartQuery}))
+	return err
+}
+
+func (adb *AudioDatastore) DeleteSmartPlaylist(name string) error {
+	q := query.NewQuery(playListCollection).Where(query.Field("Name").Eq(name))
+	ok, err := adb.db.Exists(q)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("no smart playlist named '%s'", name)
+	}
+	return adb.db.Delete(q)
+}
+
+// returns the smart playlist, nil if it does not exist
+func (adb *AudioDatastore) GetSmartPlaylist(name string) (*SmartPlaylist, error) {
+	doc, err := adb.db.FindFirst(query.NewQuery(playListCollection).Where(query.Field("Name").Eq(name)))
+	if err != nil || doc == nil {
+		return nil, err
+	}
+
+	playlist := &SmartPlaylist{}
+	err = doc.Unmarshal(playlist)
+	return playlist, err
+}
+
+func (adb *AudioDatastore) GetSmartPlaylists() ([]*SmartPlaylist, error) {
+	docs, err := adb.db.FindAll(query.NewQuery(playListCollection).Sort(query.SortOption{Field: "Name", Direction: 1}))
+	if err != nil {
+		return nil, err
+	}
+
+	playlists := make([]*SmartPlaylist, len(docs))
+	for i, doc := range docs {
+		playlists[i] = &SmartPlaylist{}
+		if err := doc.Unmarshal(playlists[i]); err != nil {
+			return nil, err
+		}
+	}
+	return playlists, nil
+}
+
+// evaluates the smart playlist query, recently played first
+func (adb *AudioDatastore) GetSmartPlaylistAudio(name string, offset int, limit int) ([]*audioDoc, error) {
+	playlist, err := adb.GetSmartPlaylist(name)
+	if err != nil {
+		return nil, err
+	}
+	if playlist == nil {
+		return nil, fmt.Errorf("no smart playlist named '%s'", name)
+	}
+
+	crit, err := ParseSmartQuery(playlist.Query)
+	if err != nil {
+		return nil, err
+	}
+
+	q := query.NewQuery(audioDocCollection).Where(crit).
+		Sort(query.SortOption{Field: "LastPlay", Direction: -1}).
+		Skip(offset).Limit(limit)
+
+	return GetaudioDocList(adb.db.FindAll(q))
+}
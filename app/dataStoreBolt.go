@@ -0,0 +1,129 @@
+package app
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"time"
+
+	"github.com/ostafen/clover/v2"
+	"github.com/ostafen/clover/v2/store"
+	"go.etcd.io/bbolt"
+)
+
+// the file and bucket of the clover bbolt store, so that existing libraries open as is
+const (
+	boltFileName   = "data.db"
+	boltRootBucket = "root"
+	dbLockTimeout  = 3 * time.Second
+)
+
+// ErrLibraryLocked is returned when another ludo holds the library,
+// bbolt allows one process at a time
+var ErrLibraryLocked = errors.New("library is in use by another ludo, close it or use the daemon")
+
+// opens the clover db in dir like clover.Open, failing with ErrLibraryLocked
+// instead of waiting forever when another process holds the lock
+func openCloverDb(dir string, lockTimeout time.Duration) (*clover.DB, error) {
+	db, err := bbolt.Open(filepath.Join(dir, boltFileName), 0666, &bbolt.Options{Timeout: lockTimeout})
+	if errors.Is(err, bbolt.ErrTimeout) {
+		return nil, ErrLibraryLocked
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Update(func(tx *bbolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists([]byte(boltRootBucket))
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return clover.OpenWithStore(&boltStore{db: db})
+}
+
+// boltStore is the clover store of a bbolt db, the docs are kept in the root bucket
+type boltStore struct {
+	db *bbolt.DB
+}
+
+func (s *boltStore) Begin(update bool) (store.Tx, error) {
+	tx, err := s.db.Begin(update)
+	if err != nil {
+		return nil, err
+	}
+	return &boltTx{Tx: tx}, nil
+}
+
+func (s *boltStore) Close() error {
+	return s.db.Close()
+}
+
+type boltTx struct {
+	*bbolt.Tx
+}
+
+func (tx *boltTx) bucket() *bbolt.Bucket {
+	return tx.Bucket([]byte(boltRootBucket))
+}
+
+func (tx *boltTx) Set(key, value []byte) error {
+	return tx.bucket().Put(key, value)
+}
+
+func (tx *boltTx) Get(key []byte) ([]byte, error) {
+	return tx.bucket().Get(key), nil
+}
+
+func (tx *boltTx) Delete(key []byte) error {
+	return tx.bucket().Delete(key)
+}
+
+func (tx *boltTx) Cursor(forward bool) (store.Cursor, error) {
+	return &boltCursor{Cursor: tx.bucket().Cursor(), forward: forward}, nil
+}
+
+// boltCursor iterates the bucket forward or backward from the seeked key
+type boltCursor struct {
+	*bbolt.Cursor
+	forward  bool
+	currItem *store.Item
+}
+
+func (c *boltCursor) Seek(seek []byte) error {
+	key, value := c.Cursor.Seek(seek)
+	if key != nil && value != nil {
+		c.currItem = &store.Item{Key: key, Value: value}
+	}
+
+	// a backward cursor starts at the last key before the seeked one
+	if key != nil && !bytes.Equal(key, seek) && !c.forward {
+		key, value = c.Cursor.Prev()
+		c.currItem = &store.Item{Key: key, Value: value}
+	}
+	return nil
+}
+
+func (c *boltCursor) Next() {
+	var key, value []byte
+	if c.forward {
+		key, value = c.Cursor.Next()
+	} else {
+		key, value = c.Cursor.Prev()
+	}
+	c.currItem = &store.Item{Key: key, Value: value}
+}
+
+func (c *boltCursor) Valid() bool {
+	return c.currItem != nil && c.currItem.Key != nil && c.currItem.Value != nil
+}
+
+func (c *boltCursor) Item() (store.Item, error) {
+	return *c.currItem, nil
+}
+
+func (c *boltCursor) Close() error {
+	return nil
+}
@@ -1,59 +1,134 @@
-package app
-
-import (
-	"time"
-
-	"github.com/ostafen/clover/v2/document"
-)
-
-type AudioListCriteria uint8
-
-const (
-	RecentlyPlayed AudioListCriteria = iota
-	MostPlayed
-	MostLikes
-)
-
-type audioDoc struct {
-	AudioBasic
-	Likes     int
-	PlayCount int
-	LastPlay  time.Time
-}
-
-func NewaudioDoc(aud AudioBasic) audioDoc {
-	return audioDoc{
-		AudioBasic: aud,
-		LastPlay:   time.Now(),
-		PlayCount:  1,
-	}
-}
-
-func (audDoc *audioDoc) getDocument() *document.Document {
-	return document.NewDocumentOf(audDoc)
-}
-
-func GetaudioDoc(doc *document.Document, err error) (*audioDoc, error) {
-	if err != nil {
-		return nil, err
-	}
-	audDoc := &audioDoc{}
-	err = doc.Unmarshal(&audDoc)
-	return audDoc, err
-}
-
-func GetaudioDocList(docs []*document.Document, err error) ([]*audioDoc, error) {
-	if err != nil {
-		return nil, err
-	}
-	audioDocs := make([]*audioDoc, len(docs))
-	for i, doc := range docs {
-		audDoc := &audioDoc{}
-		err = doc.Unmarshal(audDoc)
-		if err != nil {
-			return nil, err
-		}
-		audioDocs[i] = audDoc
-	}
-	return audioDocs, err
-}
+package app
+
+import (
+	"strings"
+	"time"
+
+	"github.com/ostafen/clover/v2/document"
+)
+
+type AudioListCriteria uint8
+
+const (
+	RecentlyPlayed AudioListCriteria = iota
+	MostPlayed
+	MostLikes
+)
+
+type LikeState int
+
+const (
+	Disliked LikeState = -1
+	Neutral  LikeState = 0
+	Liked    LikeState = 1
+)
+
+const maxRating = 5
+
+type audioDoc struct {
+	AudioBasic
+	Like      LikeState
+	Rating    int
+	Tags      []string
+	PlayCount int
+	LastPlay  time.Time
+}
+
+func NewaudioDoc(aud AudioBasic) audioDoc {
+	return audioDoc{
+		AudioBasic: aud,
+		LastPlay:   time.Now(),
+		PlayCount:  1,
+	}
+}
+
+// returns a marker for like state and star rating, ex: ♥ ★★★☆☆
+func (audDoc *audioDoc) RatingMarker() string {
+	var marker []string
+	switch audDoc.Like {
+	case Liked:
+		marker = append(marker, "♥")
+	case Disliked:
+		marker = append(marker, "✗")
+	}
+	if audDoc.Rating > 0 {
+		marker = append(marker, strings.Repeat("★", audDoc.Rating)+strings.Repeat("☆", maxRating-audDoc.Rating))
+	}
+	return strings.Join(marker, " ")
+}
+
+func (audDoc *audioDoc) getDocument() *document.Document {
+	return document.NewDocumentOf(audDoc)
+}
+
+// returns the integer field of the document, whether stored as int, uint or float
+func getDocInt(doc *document.Document, field string) int {
+	switch value := doc.Get(field).(type) {
+	case int64:
+		return int(value)
+	case uint64:
+		return int(value)
+	case float64:
+		return int(value)
+	}
+	return 0
+}
+
+// increments the play count and sets the last play of the audio document
+func incrementListened(doc *document.Document) {
+	doc.Set("PlayCount", getDocInt(doc, "PlayCount")+1)
+	doc.Set("LastPlay", time.Now())
+}
+
+// returns the tags of the audio document
+func getDocTags(doc *document.Document) []string {
+	tags := make([]string, 0)
+	values, _ := doc.Get("Tags").([]interface{})
+	for _, value := range values {
+		if tag, ok := value.(string); ok {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// returns nil if the doc is not found
+func GetaudioDoc(doc *document.Document, err error) (*audioDoc, error) {
+	if err != nil || doc == nil {
+		return nil, err
+	}
+	audDoc := &audioDoc{}
+	err = doc.Unmarshal(&audDoc)
+	return audDoc, err
+}
+
+func GetaudioDocList(docs []*document.Document, err error) ([]*audioDoc, error) {
+	if err != nil {
+		return nil, err
+	}
+	audioDocs := make([]*audioDoc, len(docs))
+	for i, doc := range docs {
+		audDoc := &audioDoc{}
+		err = doc.Unmarshal(audDoc)
+		if err != nil {
+			return nil, err
+		}
+		audioDocs[i] = audDoc
+	}
+	return audioDocs, err
+}
+
+func GetPlayEventList(docs []*document.Document, err error) ([]*PlayEvent, error) {
+	if err != nil {
+		return nil, err
+	}
+	events := make([]*PlayEvent, len(docs))
+	for i, doc := range docs {
+		event := &PlayEvent{}
+		if err := doc.Unmarshal(event); err != nil {
+			return nil, err
+		}
+		events[i] = event
+	}
+	return events, nil
+}
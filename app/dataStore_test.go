@@ -0,0 +1,186 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ostafen/clover/v2/document"
+	"github.com/ostafen/clover/v2/query"
+)
+
+var benchmarkDocCounts = []int{10_000, 100_000}
+
+// opens a datastore in a temp dir, closed at the end of the test
+func newTestDatastore(tb testing.TB) *AudioDatastore {
+	tb.Helper()
+	dir := tb.TempDir()
+	adb := &AudioDatastore{}
+	if err := adb.InitDb(dir, filepath.Join(dir, "backup")); err != nil {
+		tb.Fatalf("init db: %v", err)
+	}
+	tb.Cleanup(func() { adb.CloseDb() })
+	return adb
+}
+
+func testAudio(i int) AudioBasic {
+	return AudioBasic{YtId: fmt.Sprintf("yt%09d", i), Title: fmt.Sprintf("Title %d", i), Uploader: "Uploader", Duration: 180}
+}
+
+// inserts count audio docs in batches
+func seedAudioDocs(tb testing.TB, adb *AudioDatastore, count int) {
+	tb.Helper()
+	const batchSize = 1000
+	for start := 0; start < count; start += batchSize {
+		docs := make([]*document.Document, 0, batchSize)
+		for i := start; i < count && i < start+batchSize; i++ {
+			audDoc := NewaudioDoc(testAudio(i))
+			docs = append(docs, audDoc.getDocument())
+		}
+		if err := adb.db.Insert(audioDocCollection, docs...); err != nil {
+			tb.Fatalf("seed docs: %v", err)
+		}
+	}
+}
+
+func TestSaveOrIncrementAudioDoc(t *testing.T) {
+	adb := newTestDatastore(t)
+	aud := testAudio(1)
+
+	const plays = 20
+	var wg sync.WaitGroup
+	for i := 0; i < plays; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := adb.SaveOrIncrementAudioDoc(aud); err != nil {
+				t.Errorf("save: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	count, err := adb.db.Count(query.NewQuery(audioDocCollection).Where(query.Field("YtId").Eq(aud.YtId)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Fatalf("docs = %d, want 1", count)
+	}
+
+	audDoc, err := adb.GetaudioDoc(aud.YtId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if audDoc.PlayCount != plays {
+		t.Errorf("play count = %d, want %d", audDoc.PlayCount, plays)
+	}
+}
+
+// a second open of a library in use fails instead of waiting for the lock
+func TestInitDbLocked(t *testing.T) {
+	dir := t.TempDir()
+	adb := &AudioDatastore{}
+	if err := adb.InitDb(dir, filepath.Join(dir, "backup")); err != nil {
+		t.Fatalf("init db: %v", err)
+	}
+	if err := adb.SaveAudioDoc(testAudio(1)); err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	if _, err := openCloverDb(dir, 100*time.Millisecond); !errors.Is(err, ErrLibraryLocked) {
+		t.Fatalf("open of a locked library = %v, want %v", err, ErrLibraryLocked)
+	}
+	if waited := time.Since(start); waited > 5*time.Second {
+		t.Errorf("waited %v for the lock", waited)
+	}
+
+	// the library opens once it is closed, with the docs saved before
+	adb.CloseDb()
+	other := &AudioDatastore{}
+	if err := other.InitDb(dir, filepath.Join(dir, "backup")); err != nil {
+		t.Fatalf("init db after close: %v", err)
+	}
+	defer other.CloseDb()
+	if audDoc, err := other.GetaudioDoc(testAudio(1).YtId); err != nil || audDoc == nil {
+		t.Errorf("saved doc = %v, %v", audDoc, err)
+	}
+}
+
+// opens a datastore with count audio docs, without the YtId index
+// to compare with the full scans done before it was added
+func newBenchDatastore(b *testing.B, count int, indexed bool) *AudioDatastore {
+	adb := newTestDatastore(b)
+	seedAudioDocs(b, adb, count)
+	if !indexed {
+		if err := adb.db.DropIndex(audioDocCollection, "YtId"); err != nil {
+			b.Fatal(err)
+		}
+	}
+	return adb
+}
+
+// increments existing docs, as on every track change
+func BenchmarkSaveOrIncrementAudioDoc(b *testing.B) {
+	for _, count := range benchmarkDocCounts {
+		for _, indexed := range []bool{true, false} {
+			b.Run(fmt.Sprintf("docs=%d/indexed=%v", count, indexed), func(b *testing.B) {
+				adb := newBenchDatastore(b, count, indexed)
+				b.ResetTimer()
+
+				for i := 0; i < b.N; i++ {
+					if err := adb.SaveOrIncrementAudioDoc(testAudio(i % count)); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
+
+// saves new docs, which takes the update and the insert transactions
+func BenchmarkSaveNewAudioDoc(b *testing.B) {
+	for _, count := range benchmarkDocCounts {
+		for _, indexed := range []bool{true, false} {
+			b.Run(fmt.Sprintf("docs=%d/indexed=%v", count, indexed), func(b *testing.B) {
+				adb := newBenchDatastore(b, count, indexed)
+				b.ResetTimer()
+
+				for i := 0; i < b.N; i++ {
+					if err := adb.SaveOrIncrementAudioDoc(testAudio(count + i)); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
+
+func BenchmarkGetaudioDoc(b *testing.B) {
+	for _, count := range benchmarkDocCounts {
+		for _, indexed := range []bool{true, false} {
+			b.Run(fmt.Sprintf("docs=%d/indexed=%v", count, indexed), func(b *testing.B) {
+				benchmarkGetaudioDoc(b, newBenchDatastore(b, count, indexed), count)
+			})
+		}
+	}
+}
+
+func benchmarkGetaudioDoc(b *testing.B, adb *AudioDatastore, count int) {
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		ytId := testAudio(i % count).YtId
+		audDoc, err := adb.GetaudioDoc(ytId)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if audDoc == nil || audDoc.YtId != ytId {
+			b.Fatalf("audio %s not found", ytId)
+		}
+	}
+}
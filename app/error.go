@@ -1,9 +0,0 @@
-package app
-
-import "log"
-
-func checkErr(err error) {
-	if err != nil {
-		log.Fatal(err)
-	}
-}
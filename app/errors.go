@@ -0,0 +1,31 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Recoverable errors of the app, matched with errors.Is.
+// The frontends report them and keep running
+var (
+	ErrNoResults         = errors.New("no results found")
+	ErrInvalidIndex      = errors.New("invalid index")
+	ErrSourceUnavailable = errors.New("source unavailable")
+	ErrMediaUnplayable   = errors.New("media unplayable")
+	ErrInvalidConfig     = errors.New("invalid config")
+)
+
+// returns true if the err is one of the recoverable errors of the app
+func IsRecoverable(err error) bool {
+	return errors.Is(err, ErrNoResults) || errors.Is(err, ErrInvalidIndex) ||
+		errors.Is(err, ErrSourceUnavailable) || errors.Is(err, ErrMediaUnplayable) ||
+		errors.Is(err, ErrNoLyrics) || errors.Is(err, ErrInvalidConfig)
+}
+
+// marks the err as the kind, keeping its message, ex: source unavailable: connection refused
+func wrapErr(kind error, err error) error {
+	if err == nil || errors.Is(err, kind) {
+		return err
+	}
+	return fmt.Errorf("%w: %w", kind, err)
+}
@@ -0,0 +1,471 @@
+package app
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"runtime/debug"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var httpLog = newLogger("httpApi")
+
+var httpApi httpApiServer
+
+const (
+	httpShutdownTimeout = 5 * time.Second
+	httpDefaultLimit    = 10
+	httpEventHeartbeat  = 15 * time.Second
+)
+
+// httpApiServer serves the json api for remote control of the player
+type httpApiServer struct {
+	server *http.Server
+}
+
+type httpError struct {
+	status int
+	err    error
+}
+
+func (e httpError) Error() string {
+	return e.err.Error()
+}
+
+type PlayerStatus struct {
+	Audio      AudioBasic `json:"audio"`
+	State      string     `json:"state"`
+	Position   int        `json:"position"`
+	Length     int        `json:"length"`
+	QueueIndex int        `json:"queueIndex"`
+	Format     string     `json:"format"`
+}
+
+type QueueStatus struct {
+	Index int          `json:"index"`
+	Queue []AudioBasic `json:"queue"`
+}
+
+func GetPlayerStatus() PlayerStatus {
+	audState := vlcPlayer.GetAudioState()
+	position, length := vlcPlayer.GetMediaPosition()
+
+	state, ok := PlayerStateString(vlcPlayer.FetchPlayerState())
+	if !ok {
+		state = "Invalid"
+	}
+
+	return PlayerStatus{
+		Audio:      audState.AudioBasic,
+		State:      state,
+		Position:   position,
+		Length:     length,
+		QueueIndex: vlcPlayer.GetQueueIndex(),
+		Format:     audState.StreamFormat.String(),
+	}
+}
+
+func GetQueueStatus() QueueStatus {
+	queue := vlcPlayer.GetQueue()
+	status := QueueStatus{Index: vlcPlayer.GetQueueIndex(), Queue: make([]AudioBasic, len(queue))}
+	for i, audio := range queue {
+		status.Queue[i] = audio.AudioBasic
+	}
+	return status
+}
+
+// starts the http api if a listen address is configured, a token is required
+func setHttpConfig(cfg *appConfig) error {
+	if cfg.httpListen == "" {
+		return nil
+	}
+
+	if cfg.httpToken == "" {
+		return fmt.Errorf("%s is required to start the http api", httpTokenKey)
+	}
+	return httpApi.start(cfg.httpListen, cfg.httpToken)
+}
+
+func (api *httpApiServer) start(listen string, token string) error {
+	listener, err := net.Listen("tcp", listen)
+	if err != nil {
+		return err
+	}
+
+	api.server = &http.Server{Handler: newHttpHandler(token), ReadHeaderTimeout: 10 * time.Second}
+	api.server.RegisterOnShutdown(playerEvents.closeAll)
+	go func() {
+		defer HandlePanic()
+		httpLog.Info("Listening", "addr", listener.Addr().String())
+		if err := api.server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			httpLog.Error("Server stopped", "err", err)
+		}
+	}()
+	return nil
+}
+
+func (api *httpApiServer) stop() {
+	if api.server == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), httpShutdownTimeout)
+	defer cancel()
+	if err := api.server.Shutdown(ctx); err != nil {
+		httpLog.Error("Error in shutting down", "err", err)
+	}
+	api.server = nil
+}
+
+// returns the handler of the api endpoints, requests need the bearer token
+//
+//	GET    /status                      current audio and player state
+//	GET    /queue                       audio queue with the current index
+//	POST   /queue                       appends audio by {"query": "..."} or {"ytId": "..."}
+//	DELETE /queue/{i}                   removes the audio at index i (from 0)
+//	POST   /player/pause                toggles pause/resume
+//	POST   /player/next, /player/prev   skips to the next or previous audio
+//	POST   /player/seek                 seeks to {"position": s} or by {"offset": +-s}
+//	POST   /player/volume               sets volume to {"volume": 0-100}
+//	GET    /search?q=&limit=            searches audio from the source
+//	GET    /library?criteria=&limit=    lists songs by recent, plays, likes or a smart playlist
+//	GET    /events                      server-sent events of the player changes
+func newHttpHandler(token string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/status", httpRoute(httpHandlers{http.MethodGet: getStatusHandler}))
+	mux.HandleFunc("/queue", httpRoute(httpHandlers{http.MethodGet: getQueueHandler, http.MethodPost: appendQueueHandler}))
+	mux.HandleFunc("/queue/", httpRoute(httpHandlers{http.MethodDelete: removeQueueHandler}))
+	mux.HandleFunc("/player/", httpRoute(httpHandlers{http.MethodPost: playerHandler}))
+	mux.HandleFunc("/search", httpRoute(httpHandlers{http.MethodGet: searchHandler}))
+	mux.HandleFunc("/library", httpRoute(httpHandlers{http.MethodGet: libraryHandler}))
+	mux.HandleFunc("/events", eventsHandler)
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		writeHttpError(w, httpError{http.StatusNotFound, errors.New("not found")})
+	})
+
+	return httpRecover(httpAuth(token, mux))
+}
+
+// recovers the panic of a request, which is logged and answered with a 500.
+// The server is not stopped, as a crash is only for the goroutine and callback boundaries
+func httpRecover(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			rec := recover()
+			if rec == nil {
+				return
+			}
+			// aborts the response, the server handles it
+			if rec == http.ErrAbortHandler {
+				panic(rec)
+			}
+			httpLog.Error("Request panicked", "method", r.Method, "path", r.URL.Path, "panic", fmt.Sprint(rec), "stack", string(debug.Stack()))
+			writeHttpError(w, errors.New("internal server error"))
+		}()
+		next.ServeHTTP(w, r)
+	})
+}
+
+// checks the bearer token of the request, the access_token query param
+// is accepted for clients like EventSource which cannot set headers
+func httpAuth(token string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reqToken, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok {
+			reqToken = r.URL.Query().Get("access_token")
+		}
+		if reqToken == "" || subtle.ConstantTimeCompare([]byte(reqToken), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeHttpError(w, httpError{http.StatusUnauthorized, errors.New("invalid token")})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// handlers of a route mapped by method, returning the result to be written as json
+type httpHandlers map[string]func(r *http.Request) (interface{}, error)
+
+// wraps the handlers of the route, writing their result or error as json
+func httpRoute(handlers httpHandlers) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		handler, ok := handlers[r.Method]
+		if !ok {
+			methods := make([]string, 0, len(handlers))
+			for method := range handlers {
+				methods = append(methods, method)
+			}
+			sort.Strings(methods)
+			w.Header().Set("Allow", strings.Join(methods, ", "))
+			writeHttpError(w, httpError{http.StatusMethodNotAllowed, errors.New("method not allowed")})
+			return
+		}
+
+		result, err := handler(r)
+		if err != nil {
+			httpLog.Warn("Request failed", "method", r.Method, "path", r.URL.Path, "err", err)
+			writeHttpError(w, err)
+			return
+		}
+		writeHttpJson(w, http.StatusOK, result)
+	}
+}
+
+func writeHttpJson(w http.ResponseWriter, status int, result interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		httpLog.Error("Error in writing response", "err", err)
+	}
+}
+
+func writeHttpError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	var httpErr httpError
+	switch {
+	case errors.As(err, &httpErr):
+		status = httpErr.status
+	case errors.Is(err, ErrNoResults):
+		status = http.StatusNotFound
+	case errors.Is(err, ErrInvalidIndex):
+		status = http.StatusBadRequest
+	case errors.Is(err, ErrSourceUnavailable):
+		status = http.StatusBadGateway
+	case errors.Is(err, ErrMediaUnplayable):
+		status = http.StatusUnprocessableEntity
+	}
+	writeHttpJson(w, status, map[string]string{"error": err.Error()})
+}
+
+func badRequest(err error) error {
+	return httpError{http.StatusBadRequest, err}
+}
+
+// Handlers
+
+func getStatusHandler(r *http.Request) (interface{}, error) {
+	return GetPlayerStatus(), nil
+}
+
+func getQueueHandler(r *http.Request) (interface{}, error) {
+	return GetQueueStatus(), nil
+}
+
+// appends the audio to the queue, the playback is started if not playing
+func appendQueueHandler(r *http.Request) (interface{}, error) {
+	var body struct {
+		Query string `json:"query"`
+		YtId  string `json:"ytId"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		return nil, badRequest(err)
+	}
+
+	search, isYtId := body.Query, false
+	if body.YtId != "" {
+		search, isYtId = body.YtId, true
+	}
+	if search == "" {
+		return nil, badRequest(errors.New("query or ytId is required"))
+	}
+
+	audio, err := GetSong(IsSourcePiped())(search, isYtId)
+	if err != nil {
+		return nil, err
+	}
+	if err := vlcPlayer.AppendAudio(audio); err != nil {
+		return nil, err
+	}
+
+	if !vlcPlayer.IsPlaying() {
+		if err := vlcPlayer.StartPlayback(); err != nil {
+			return nil, err
+		}
+	}
+	return audio.AudioBasic, nil
+}
+
+func removeQueueHandler(r *http.Request) (interface{}, error) {
+	index, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/queue/"))
+	if err != nil {
+		return nil, badRequest(errors.New("invalid queue index"))
+	}
+	if index < 0 || index >= len(vlcPlayer.GetQueue()) {
+		return nil, httpError{http.StatusNotFound, errors.New("index out of bounds")}
+	}
+
+	if err := vlcPlayer.RemoveAudioFromIndex(index); err != nil {
+		return nil, err
+	}
+	return getQueueHandler(r)
+}
+
+func playerHandler(r *http.Request) (interface{}, error) {
+	var body struct {
+		Position *int `json:"position"`
+		Offset   int  `json:"offset"`
+		Volume   *int `json:"volume"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			return nil, badRequest(err)
+		}
+	}
+
+	var err error
+	switch action := strings.TrimPrefix(r.URL.Path, "/player/"); action {
+	case "pause":
+		err = vlcPlayer.PauseResume()
+	case "next":
+		err = vlcPlayer.SkipToNext()
+	case "prev":
+		err = vlcPlayer.SkipToPrevious()
+	case "seek":
+		switch {
+		case body.Position != nil:
+			err = vlcPlayer.SeekToSeconds(*body.Position)
+		case body.Offset >= 0:
+			err = vlcPlayer.ForwardBySeconds(body.Offset)
+		default:
+			err = vlcPlayer.RewindBySeconds(-body.Offset)
+		}
+	case "volume":
+		if body.Volume == nil {
+			return nil, badRequest(errors.New("volume is required"))
+		}
+		if *body.Volume < 0 || *body.Volume > 100 {
+			return nil, badRequest(errors.New("volume must be between 0 and 100"))
+		}
+		err = vlcPlayer.SetVol(*body.Volume)
+	default:
+		return nil, httpError{http.StatusNotFound, fmt.Errorf("invalid player action: %s", action)}
+	}
+
+	if err != nil {
+		return nil, err
+	}
+	return getStatusHandler(r)
+}
+
+func searchHandler(r *http.Request) (interface{}, error) {
+	query := r.URL.Query().Get("q")
+	if query == "" {
+		return nil, badRequest(errors.New("q is required"))
+	}
+	limit, err := getLimitParam(r)
+	if err != nil {
+		return nil, err
+	}
+
+	audioList, err := GetSearchList(IsSourcePiped())(query, 0, limit)
+	if err != nil {
+		return nil, err
+	}
+	return audioList, nil
+}
+
+func libraryHandler(r *http.Request) (interface{}, error) {
+	criteria := r.URL.Query().Get("criteria")
+	if criteria == "" {
+		return nil, badRequest(errors.New("criteria is required"))
+	}
+	limit, err := getLimitParam(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return audioDb.GetNamedAudioList(criteria, 0, limit)
+}
+
+func getLimitParam(r *http.Request) (int, error) {
+	limitParam := r.URL.Query().Get("limit")
+	if limitParam == "" {
+		return httpDefaultLimit, nil
+	}
+
+	limit, err := strconv.Atoi(limitParam)
+	if err != nil || limit <= 0 {
+		return 0, badRequest(errors.New("invalid limit"))
+	}
+	return limit, nil
+}
+
+// streams the player events as server-sent events. A snapshot is sent first,
+// or the missed events when resuming with the Last-Event-ID header (or lastEventId param)
+func eventsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		writeHttpError(w, httpError{http.StatusMethodNotAllowed, errors.New("method not allowed")})
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeHttpError(w, errors.New("streaming not supported"))
+		return
+	}
+
+	lastIdParam := r.Header.Get("Last-Event-ID")
+	if lastIdParam == "" {
+		lastIdParam = r.URL.Query().Get("lastEventId")
+	}
+	lastId, err := strconv.ParseInt(lastIdParam, 10, 64)
+	resume := lastIdParam != "" && err == nil
+
+	events, replay, snapshot := playerEvents.subscribe(lastId, resume)
+	defer playerEvents.unsubscribe(events)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	if snapshot != nil {
+		replay = []PlayerEvent{*snapshot}
+	}
+	for _, event := range replay {
+		if err := writeSseEvent(w, event); err != nil {
+			return
+		}
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(httpEventHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			if err := writeSseEvent(w, event); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if _, err := fmt.Fprintf(w, "event: heartbeat\ndata: {\"time\":%q}\n\n", time.Now().Format(time.RFC3339)); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
+
+func writeSseEvent(w io.Writer, event PlayerEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.Id, event.Type, data)
+	return err
+}
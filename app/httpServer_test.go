@@ -0,0 +1,226 @@
+package app
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const testHttpToken = "secret"
+
+// sets the player queue, the current index is the first audio
+func setTestQueue(t *testing.T, titles ...string) {
+	t.Helper()
+	vlcPlayer.mu.Lock()
+	defer vlcPlayer.mu.Unlock()
+
+	vlcPlayer.audioQueue = nil
+	for i, title := range titles {
+		audio := AudioDetails{AudioBasic: AudioBasic{Title: title, YtId: strings.Repeat(string(rune('a'+i)), 11)}}
+		vlcPlayer.audioQueue = append(vlcPlayer.audioQueue, audio)
+	}
+	vlcPlayer.audioState = AudioState{}
+	vlcPlayer.audioState.currentTrackIndex = -1
+	if len(titles) > 0 {
+		vlcPlayer.audioState.currentTrackIndex = 0
+		vlcPlayer.audioState.updateAudioState(&vlcPlayer.audioQueue[0])
+	}
+
+	t.Cleanup(func() {
+		vlcPlayer.mu.Lock()
+		defer vlcPlayer.mu.Unlock()
+		vlcPlayer.audioQueue, vlcPlayer.audioState = nil, AudioState{}
+	})
+}
+
+func serveTestRequest(method string, target string, body string, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	} else {
+		req.ContentLength = 0
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	rec := httptest.NewRecorder()
+	newHttpHandler(testHttpToken).ServeHTTP(rec, req)
+	return rec
+}
+
+func decodeTestJson(t *testing.T, rec *httptest.ResponseRecorder, result interface{}) {
+	t.Helper()
+	if contentType := rec.Header().Get("Content-Type"); contentType != "application/json" {
+		t.Fatalf("content type = %q, want application/json", contentType)
+	}
+	if err := json.NewDecoder(rec.Body).Decode(result); err != nil {
+		t.Fatalf("invalid json response: %v", err)
+	}
+}
+
+func TestHttpAuth(t *testing.T) {
+	tests := []struct {
+		name   string
+		target string
+		token  string
+		status int
+	}{
+		{"no token", "/status", "", http.StatusUnauthorized},
+		{"wrong token", "/status", "wrong", http.StatusUnauthorized},
+		{"bearer token", "/status", testHttpToken, http.StatusOK},
+		{"query token", "/status?access_token=" + testHttpToken, "", http.StatusOK},
+		{"wrong query token", "/status?access_token=wrong", "", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serveTestRequest(http.MethodGet, tt.target, "", tt.token)
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.status)
+			}
+			if tt.status == http.StatusUnauthorized && rec.Header().Get("WWW-Authenticate") != "Bearer" {
+				t.Errorf("missing WWW-Authenticate header")
+			}
+		})
+	}
+}
+
+func TestHttpRouteErrors(t *testing.T) {
+	setTestQueue(t, "first", "second", "third")
+
+	tests := []struct {
+		name   string
+		method string
+		target string
+		body   string
+		status int
+	}{
+		{"unknown route", http.MethodGet, "/unknown", "", http.StatusNotFound},
+		{"status method", http.MethodPost, "/status", "", http.StatusMethodNotAllowed},
+		{"queue method", http.MethodPut, "/queue", "", http.StatusMethodNotAllowed},
+		{"player method", http.MethodGet, "/player/pause", "", http.StatusMethodNotAllowed},
+		{"events method", http.MethodPost, "/events", "", http.StatusMethodNotAllowed},
+		{"player action", http.MethodPost, "/player/rewind", "", http.StatusNotFound},
+		{"invalid json", http.MethodPost, "/queue", "{", http.StatusBadRequest},
+		{"missing query", http.MethodPost, "/queue", "{}", http.StatusBadRequest},
+		{"missing volume", http.MethodPost, "/player/volume", "{}", http.StatusBadRequest},
+		{"invalid volume", http.MethodPost, "/player/volume", `{"volume": 101}`, http.StatusBadRequest},
+		{"missing search", http.MethodGet, "/search", "", http.StatusBadRequest},
+		{"invalid limit", http.MethodGet, "/library?criteria=recent&limit=0", "", http.StatusBadRequest},
+		{"non numeric index", http.MethodDelete, "/queue/first", "", http.StatusBadRequest},
+		{"current index", http.MethodDelete, "/queue/0", "", http.StatusBadRequest},
+		{"negative index", http.MethodDelete, "/queue/-1", "", http.StatusNotFound},
+		{"index out of bounds", http.MethodDelete, "/queue/3", "", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serveTestRequest(tt.method, tt.target, tt.body, testHttpToken)
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.status, rec.Body)
+			}
+
+			var result map[string]string
+			decodeTestJson(t, rec, &result)
+			if result["error"] == "" {
+				t.Errorf("missing error message: %v", result)
+			}
+			if tt.status == http.StatusMethodNotAllowed && rec.Header().Get("Allow") == "" {
+				t.Errorf("missing Allow header")
+			}
+		})
+	}
+}
+
+func TestHttpQueue(t *testing.T) {
+	setTestQueue(t, "first", "second")
+
+	rec := serveTestRequest(http.MethodGet, "/queue", "", testHttpToken)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+
+	var result map[string]json.RawMessage
+	decodeTestJson(t, rec, &result)
+	var index int
+	if err := json.Unmarshal(result["index"], &index); err != nil || index != 0 {
+		t.Errorf("index = %s, want 0", result["index"])
+	}
+
+	var queue []map[string]interface{}
+	if err := json.Unmarshal(result["queue"], &queue); err != nil {
+		t.Fatalf("invalid queue: %v", err)
+	}
+	if len(queue) != 2 {
+		t.Fatalf("queue length = %d, want 2", len(queue))
+	}
+	for i, title := range []string{"first", "second"} {
+		if queue[i]["Title"] != title {
+			t.Errorf("queue[%d] title = %v, want %s", i, queue[i]["Title"], title)
+		}
+		if _, ok := queue[i]["YtId"]; !ok {
+			t.Errorf("queue[%d] has no YtId: %v", i, queue[i])
+		}
+	}
+}
+
+func TestHttpStatus(t *testing.T) {
+	setTestQueue(t, "first")
+
+	rec := serveTestRequest(http.MethodGet, "/status", "", testHttpToken)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+
+	var result map[string]json.RawMessage
+	decodeTestJson(t, rec, &result)
+	for _, key := range []string{"audio", "state", "position", "length", "queueIndex", "format"} {
+		if _, ok := result[key]; !ok {
+			t.Errorf("status has no %s: %v", key, result)
+		}
+	}
+
+	var audio AudioBasic
+	if err := json.Unmarshal(result["audio"], &audio); err != nil || audio.Title != "first" {
+		t.Errorf("audio = %s, want the first audio", result["audio"])
+	}
+}
+
+// a panic of a request is answered with a 500, the app keeps running
+func TestHttpRecover(t *testing.T) {
+	handler := httpRecover(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("handler failed")
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/status", nil))
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusInternalServerError)
+	}
+
+	var result map[string]string
+	decodeTestJson(t, rec, &result)
+	if result["error"] != "internal server error" {
+		t.Errorf("error = %q, want internal server error", result["error"])
+	}
+	if crashing.Load() {
+		t.Errorf("the panic crashed the app")
+	}
+}
+
+// an aborted response is left to the server
+func TestHttpRecoverAbort(t *testing.T) {
+	handler := httpRecover(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	}))
+
+	defer func() {
+		if rec := recover(); rec != http.ErrAbortHandler {
+			t.Errorf("recovered %v, want %v", rec, http.ErrAbortHandler)
+		}
+	}()
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/status", nil))
+}
@@ -0,0 +1,280 @@
+package app
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	lastFmName           = "lastfm"
+	lastFmBatchSize      = 50
+	lastFmTimeout        = 30 * time.Second
+	lastFmSessionMetaKey = "lastFmSession"
+)
+
+// last.fm api error codes
+const (
+	lastFmInvalidParams  = 6
+	lastFmInvalidSession = 9
+)
+
+// lastFmConfig holds the api credentials and the user credentials used for authentication,
+// a session key, a username and password, or an authorized token
+type lastFmConfig struct {
+	apiRoot    string
+	apiKey     string
+	apiSecret  string
+	sessionKey string
+	username   string
+	password   string
+	token      string
+}
+
+// lastFmScrobbler submits listens to the Last.fm api with signed calls
+type lastFmScrobbler struct {
+	config     lastFmConfig
+	client     *http.Client
+	mu         sync.Mutex
+	sessionKey string
+}
+
+type lastFmError struct {
+	Code    int    `json:"error"`
+	Message string `json:"message"`
+}
+
+func (e lastFmError) Error() string {
+	return fmt.Sprintf("[lastFm] error %d: %s", e.Code, e.Message)
+}
+
+type lastFmSessionResp struct {
+	Session struct {
+		Name string `json:"name"`
+		Key  string `json:"key"`
+	} `json:"session"`
+}
+
+type lastFmScrobbleResp struct {
+	Scrobbles struct {
+		Attr struct {
+			Accepted int `json:"accepted"`
+			Ignored  int `json:"ignored"`
+		} `json:"@attr"`
+	} `json:"scrobbles"`
+}
+
+func newLastFmScrobbler(config lastFmConfig) *lastFmScrobbler {
+	return &lastFmScrobbler{
+		config:     config,
+		client:     &http.Client{Timeout: lastFmTimeout},
+		sessionKey: config.sessionKey,
+	}
+}
+
+func (lf *lastFmScrobbler) Name() string {
+	return lastFmName
+}
+
+func (lf *lastFmScrobbler) BatchSize() int {
+	return lastFmBatchSize
+}
+
+func (lf *lastFmScrobbler) NowPlaying(audio AudioBasic) error {
+	artist, track := getArtistTrack(audio)
+	params := map[string]string{
+		"method": "track.updateNowPlaying",
+		"artist": artist,
+		"track":  track,
+	}
+	if audio.Duration > 0 {
+		params["duration"] = strconv.Itoa(audio.Duration)
+	}
+
+	return lf.callWithSession(params, nil)
+}
+
+func (lf *lastFmScrobbler) Submit(batch []*Scrobble) error {
+	params := map[string]string{"method": "track.scrobble"}
+	for i, scrobble := range batch {
+		artist, track := getArtistTrack(scrobble.AudioBasic)
+		params[fmt.Sprintf("artist[%d]", i)] = artist
+		params[fmt.Sprintf("track[%d]", i)] = track
+		params[fmt.Sprintf("timestamp[%d]", i)] = strconv.FormatInt(scrobble.ListenedAt.Unix(), 10)
+		if scrobble.Duration > 0 {
+			params[fmt.Sprintf("duration[%d]", i)] = strconv.Itoa(scrobble.Duration)
+		}
+	}
+
+	var resp lastFmScrobbleResp
+	err := lf.callWithSession(params, &resp)
+
+	// invalid listens will fail again, others are retried
+	var apiErr lastFmError
+	if errors.As(err, &apiErr) && apiErr.Code == lastFmInvalidParams {
+		return scrobbleError{err}
+	}
+	if err != nil {
+		return err
+	}
+
+	scrobbleLog.Debug("Last.fm scrobbles", "accepted", resp.Scrobbles.Attr.Accepted, "ignored", resp.Scrobbles.Attr.Ignored)
+	return nil
+}
+
+// makes the signed call with the user session, an invalid session is dropped
+// so that the next call authenticates again
+func (lf *lastFmScrobbler) callWithSession(params map[string]string, result interface{}) error {
+	sessionKey, err := lf.getSessionKey()
+	if err != nil {
+		return err
+	}
+	params["sk"] = sessionKey
+
+	err = lf.call(params, result)
+	var apiErr lastFmError
+	if errors.As(err, &apiErr) && apiErr.Code == lastFmInvalidSession {
+		lf.resetSessionKey()
+	}
+	return err
+}
+
+// returns the session key from the config or the datastore,
+// else authenticates with the user credentials and stores the new session key
+func (lf *lastFmScrobbler) getSessionKey() (string, error) {
+	lf.mu.Lock()
+	defer lf.mu.Unlock()
+
+	if lf.sessionKey != "" {
+		return lf.sessionKey, nil
+	}
+
+	if sessionKey, err := audioDb.getMetadataString(lf.sessionMetaKey()); err != nil {
+		return "", err
+	} else if sessionKey != "" {
+		lf.sessionKey = sessionKey
+		return sessionKey, nil
+	}
+
+	var params map[string]string
+	switch {
+	case lf.config.username != "" && lf.config.password != "":
+		params = map[string]string{
+			"method":   "auth.getMobileSession",
+			"username": lf.config.username,
+			"password": lf.config.password,
+		}
+	case lf.config.token != "":
+		params = map[string]string{
+			"method": "auth.getSession",
+			"token":  lf.config.token,
+		}
+	default:
+		return "", errors.New("[lastFm] no session key, username and password or token configured")
+	}
+
+	var resp lastFmSessionResp
+	if err := lf.call(params, &resp); err != nil {
+		return "", err
+	}
+	if resp.Session.Key == "" {
+		return "", errors.New("[lastFm] no session key in response")
+	}
+
+	scrobbleLog.Info("Last.fm session created", "user", resp.Session.Name)
+	if err := audioDb.setMetadata(lf.sessionMetaKey(), resp.Session.Key); err != nil {
+		scrobbleLog.Error("Error in saving Last.fm session", "err", err)
+	}
+	lf.sessionKey = resp.Session.Key
+	return lf.sessionKey, nil
+}
+
+func (lf *lastFmScrobbler) resetSessionKey() {
+	lf.mu.Lock()
+	defer lf.mu.Unlock()
+
+	scrobbleLog.Warn("Last.fm session is invalid, resetting")
+	lf.sessionKey = ""
+	if err := audioDb.setMetadata(lf.sessionMetaKey(), ""); err != nil {
+		scrobbleLog.Error("Error in resetting Last.fm session", "err", err)
+	}
+}
+
+// returns the metadata key of the stored session of the configured user, so that
+// a session is not used for another account. Without a username it is keyed by the token
+func (lf *lastFmScrobbler) sessionMetaKey() string {
+	if lf.config.username != "" {
+		return lastFmSessionMetaKey + "." + strings.ToLower(lf.config.username)
+	}
+	hash := md5.Sum([]byte(lf.config.token))
+	return lastFmSessionMetaKey + ".token." + hex.EncodeToString(hash[:8])
+}
+
+// posts the signed call to the api and decodes the response into result
+func (lf *lastFmScrobbler) call(params map[string]string, result interface{}) error {
+	params["api_key"] = lf.config.apiKey
+	params["api_sig"] = signLastFmParams(params, lf.config.apiSecret)
+
+	form := url.Values{}
+	for key, value := range params {
+		form.Set(key, value)
+	}
+	form.Set("format", "json")
+
+	resp, err := lf.client.PostForm(lf.config.apiRoot, form)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	scrobbleLog.Debug("Last.fm response", "method", params["method"], "status", resp.Status)
+
+	var body json.RawMessage
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return fmt.Errorf("[lastFm] bad response: %s", resp.Status)
+	}
+
+	var apiErr lastFmError
+	if err := json.Unmarshal(body, &apiErr); err == nil && apiErr.Code != 0 {
+		return apiErr
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("[lastFm] bad response: %s", resp.Status)
+	}
+
+	if result == nil {
+		return nil
+	}
+	return json.Unmarshal(body, result)
+}
+
+// signs the params as md5 of the sorted key values followed by the secret
+func signLastFmParams(params map[string]string, secret string) string {
+	keys := make([]string, 0, len(params))
+	for key := range params {
+		if key == "format" || key == "callback" || key == "api_sig" {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var sig strings.Builder
+	for _, key := range keys {
+		sig.WriteString(key)
+		sig.WriteString(params[key])
+	}
+	sig.WriteString(secret)
+
+	hash := md5.Sum([]byte(sig.String()))
+	return hex.EncodeToString(hash[:])
+}
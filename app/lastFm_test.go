@@ -0,0 +1,246 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+const testLastFmSecret = "secret"
+
+// lastFmStub is a Last.fm api checking the signatures, which answers the calls by method
+type lastFmStub struct {
+	mu       sync.Mutex
+	calls    map[string]int
+	sessions []string
+	params   map[string]string
+	respond  map[string]func(w http.ResponseWriter, params map[string]string)
+}
+
+func newLastFmStub(t *testing.T) (*lastFmStub, *httptest.Server) {
+	stub := &lastFmStub{calls: map[string]int{}, respond: map[string]func(w http.ResponseWriter, params map[string]string){}}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Errorf("invalid form: %v", err)
+		}
+		params := map[string]string{}
+		for key := range r.PostForm {
+			params[key] = r.PostForm.Get(key)
+		}
+		if sig := signLastFmParams(params, testLastFmSecret); params["api_sig"] != sig {
+			t.Errorf("%s: api_sig = %q, want %q", params["method"], params["api_sig"], sig)
+		}
+
+		stub.mu.Lock()
+		method := params["method"]
+		stub.calls[method]++
+		stub.params = params
+		if sk, ok := params["sk"]; ok {
+			stub.sessions = append(stub.sessions, sk)
+		}
+		respond := stub.respond[method]
+		stub.mu.Unlock()
+
+		if respond == nil {
+			t.Errorf("unexpected method %q", method)
+			return
+		}
+		respond(w, params)
+	}))
+	t.Cleanup(server.Close)
+	return stub, server
+}
+
+func newTestLastFm(apiRoot string, username string) *lastFmScrobbler {
+	return newLastFmScrobbler(lastFmConfig{apiRoot: apiRoot, apiKey: "key", apiSecret: testLastFmSecret, username: username, password: "password"})
+}
+
+func respondLastFmSession(key string) func(w http.ResponseWriter, params map[string]string) {
+	return func(w http.ResponseWriter, params map[string]string) {
+		fmt.Fprintf(w, `{"session": {"name": %q, "key": %q}}`, params["username"], key)
+	}
+}
+
+func respondLastFmError(status int, code int) func(w http.ResponseWriter, params map[string]string) {
+	return func(w http.ResponseWriter, params map[string]string) {
+		w.WriteHeader(status)
+		fmt.Fprintf(w, `{"error": %d, "message": "stub error"}`, code)
+	}
+}
+
+func respondLastFmScrobbles(w http.ResponseWriter, params map[string]string) {
+	w.Write([]byte(`{"scrobbles": {"@attr": {"accepted": 1, "ignored": 0}}}`))
+}
+
+func TestLastFmSubmit(t *testing.T) {
+	setTestAudioDb(t)
+	stub, server := newLastFmStub(t)
+	stub.respond["auth.getMobileSession"] = respondLastFmSession("session")
+	stub.respond["track.scrobble"] = respondLastFmScrobbles
+
+	lf := newTestLastFm(server.URL, "user")
+	audio := AudioBasic{YtId: "abcdefghijk", Title: "Get Lucky", Uploader: "Daft Punk - Topic", Duration: 248}
+	listenedAt := time.Unix(1_700_000_000, 0)
+	if err := lf.Submit([]*Scrobble{{AudioBasic: audio, ListenedAt: listenedAt}}); err != nil {
+		t.Fatalf("submit: %v", err)
+	}
+
+	want := map[string]string{
+		"api_key":      "key",
+		"sk":           "session",
+		"artist[0]":    "Daft Punk",
+		"track[0]":     "Get Lucky",
+		"timestamp[0]": "1700000000",
+		"duration[0]":  "248",
+	}
+	for key, value := range want {
+		if stub.params[key] != value {
+			t.Errorf("%s = %q, want %q", key, stub.params[key], value)
+		}
+	}
+
+	// the session is stored for the user and reused
+	if sessionKey, _ := audioDb.getMetadataString(lf.sessionMetaKey()); sessionKey != "session" {
+		t.Errorf("stored session = %q, want session", sessionKey)
+	}
+	if err := newTestLastFm(server.URL, "User").Submit([]*Scrobble{{AudioBasic: audio, ListenedAt: listenedAt}}); err != nil {
+		t.Fatalf("submit: %v", err)
+	}
+	if stub.calls["auth.getMobileSession"] != 1 {
+		t.Errorf("sessions created = %d, want 1", stub.calls["auth.getMobileSession"])
+	}
+
+	// another account authenticates again
+	stub.respond["auth.getMobileSession"] = respondLastFmSession("other session")
+	if err := newTestLastFm(server.URL, "other").Submit([]*Scrobble{{AudioBasic: audio, ListenedAt: listenedAt}}); err != nil {
+		t.Fatalf("submit: %v", err)
+	}
+	if stub.calls["auth.getMobileSession"] != 2 || stub.params["sk"] != "other session" {
+		t.Errorf("another user used session %q", stub.params["sk"])
+	}
+}
+
+func TestLastFmSubmitQueue(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		code     int
+		pending  int
+		rejected bool
+	}{
+		{"server error is retried", http.StatusInternalServerError, 0, 3, false},
+		{"api error is retried", http.StatusServiceUnavailable, 16, 3, false},
+		{"invalid listens are dropped", http.StatusBadRequest, lastFmInvalidParams, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setTestAudioDb(t)
+			queueTestScrobbles(t, lastFmName, 3)
+
+			stub, server := newLastFmStub(t)
+			stub.respond["auth.getMobileSession"] = respondLastFmSession("session")
+			stub.respond["track.scrobble"] = func(w http.ResponseWriter, params map[string]string) {
+				if tt.code == 0 {
+					w.WriteHeader(tt.status)
+					w.Write([]byte("stub error"))
+					return
+				}
+				respondLastFmError(tt.status, tt.code)(w, params)
+			}
+
+			lf := newTestLastFm(server.URL, "user")
+			var service scrobbleService
+			if err := service.submitQueue(lf); (err != nil) != (tt.pending > 0) {
+				t.Errorf("submit queue: %v", err)
+			}
+			if _, ok := stub.params["timestamp[2]"]; !ok {
+				t.Errorf("the batch has not the 3 listens: %v", stub.params)
+			}
+
+			pending := getTestScrobbles(t, lastFmName)
+			if len(pending) != tt.pending {
+				t.Fatalf("pending listens = %d, want %d", len(pending), tt.pending)
+			}
+			for _, scrobble := range pending {
+				if scrobble.Attempts != 1 {
+					t.Errorf("attempts = %d, want 1", scrobble.Attempts)
+				}
+			}
+
+			// a retry submits the queue once the api is back
+			stub.respond["track.scrobble"] = respondLastFmScrobbles
+			if err := service.submitQueue(lf); err != nil {
+				t.Errorf("retry: %v", err)
+			}
+			if pending := getTestScrobbles(t, lastFmName); len(pending) != 0 {
+				t.Errorf("pending listens after retry = %d, want 0", len(pending))
+			}
+		})
+	}
+}
+
+func TestLastFmAuthFailure(t *testing.T) {
+	setTestAudioDb(t)
+	stub, server := newLastFmStub(t)
+	stub.respond["auth.getMobileSession"] = respondLastFmError(http.StatusForbidden, 4)
+	audio := testAudio(1)
+
+	lf := newTestLastFm(server.URL, "user")
+	err := lf.Submit([]*Scrobble{{AudioBasic: audio, ListenedAt: time.Now()}})
+	var apiErr lastFmError
+	if !errors.As(err, &apiErr) || apiErr.Code != 4 {
+		t.Fatalf("submit error = %v, want the auth error", err)
+	}
+	if errors.As(err, &scrobbleError{}) {
+		t.Errorf("auth failure is not retried: %v", err)
+	}
+	if stub.calls["track.scrobble"] != 0 {
+		t.Errorf("scrobbled without a session")
+	}
+
+	// an invalid session is dropped and the next call authenticates again
+	stub.respond["auth.getMobileSession"] = respondLastFmSession("revoked")
+	stub.respond["track.scrobble"] = respondLastFmError(http.StatusForbidden, lastFmInvalidSession)
+	if err := lf.Submit([]*Scrobble{{AudioBasic: audio, ListenedAt: time.Now()}}); err == nil {
+		t.Fatal("submit with an invalid session gave no error")
+	}
+	if sessionKey, _ := audioDb.getMetadataString(lf.sessionMetaKey()); sessionKey != "" {
+		t.Errorf("stored session = %q, want it reset", sessionKey)
+	}
+
+	stub.respond["auth.getMobileSession"] = respondLastFmSession("session")
+	stub.respond["track.scrobble"] = respondLastFmScrobbles
+	if err := lf.Submit([]*Scrobble{{AudioBasic: audio, ListenedAt: time.Now()}}); err != nil {
+		t.Fatalf("submit after reauth: %v", err)
+	}
+	if stub.calls["auth.getMobileSession"] != 3 || stub.params["sk"] != "session" {
+		t.Errorf("sessions created = %d, last session %q, want a new session", stub.calls["auth.getMobileSession"], stub.params["sk"])
+	}
+}
+
+func TestLastFmSessionMetaKey(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b lastFmConfig
+		same bool
+	}{
+		{"same user", lastFmConfig{username: "user"}, lastFmConfig{username: "User"}, true},
+		{"other user", lastFmConfig{username: "user"}, lastFmConfig{username: "other"}, false},
+		{"same token", lastFmConfig{token: "token"}, lastFmConfig{token: "token"}, true},
+		{"other token", lastFmConfig{token: "token"}, lastFmConfig{token: "other"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := newLastFmScrobbler(tt.a).sessionMetaKey(), newLastFmScrobbler(tt.b).sessionMetaKey()
+			if (a == b) != tt.same {
+				t.Errorf("keys %q and %q, want same = %v", a, b, tt.same)
+			}
+		})
+	}
+}
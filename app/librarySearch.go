@@ -0,0 +1,254 @@
+package app
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/ostafen/clover/v2/query"
+	"golang.org/x/text/unicode/norm"
+)
+
+const searchGramSize = 3
+
+// match quality scores of a search term, title matches count double
+const (
+	substringMatchScore = 1
+	prefixMatchScore    = 2
+	wordMatchScore      = 3
+	titleMatchWeight    = 2
+)
+
+// searchIndex is an in memory trigram index over the title and uploader of audio docs
+type searchIndex struct {
+	mu    sync.RWMutex
+	built bool
+	texts map[string]indexedText
+	grams map[string]map[string]bool
+}
+
+type indexedText struct {
+	title    string
+	uploader string
+}
+
+type searchMatch struct {
+	ytId  string
+	score int
+}
+
+// finds audio docs whose title or uploader match all words of the text,
+// best matches first, ties broken by play count
+func (adb *AudioDatastore) FindAudio(text string, limit int) ([]*audioDoc, error) {
+	terms := strings.Fields(foldText(text))
+	if len(terms) == 0 {
+		return []*audioDoc{}, nil
+	}
+
+	if err := adb.buildSearchIndex(); err != nil {
+		return nil, err
+	}
+
+	matches := adb.searchIdx.search(terms)
+	if len(matches) == 0 {
+		return []*audioDoc{}, nil
+	}
+
+	ytIds := make([]string, len(matches))
+	for i, match := range matches {
+		ytIds[i] = match.ytId
+	}
+	audDocMap, err := adb.GetAudioDocMap(ytIds)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].score != matches[j].score {
+			return matches[i].score > matches[j].score
+		}
+		return getPlayCount(audDocMap, matches[i].ytId) > getPlayCount(audDocMap, matches[j].ytId)
+	})
+
+	audDocs := make([]*audioDoc, 0, len(matches))
+	for _, match := range matches {
+		if audDoc, ok := audDocMap[match.ytId]; ok {
+			audDocs = append(audDocs, audDoc)
+		}
+	}
+	return trimList(audDocs, 0, limit), nil
+}
+
+// builds the search index from all audio docs, once per session
+func (adb *AudioDatastore) buildSearchIndex() error {
+	idx := &adb.searchIdx
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	if idx.built {
+		return nil
+	}
+
+	audDocs, err := GetaudioDocList(adb.db.FindAll(query.NewQuery(audioDocCollection)))
+	if err != nil {
+		return err
+	}
+
+	idx.texts = make(map[string]indexedText, len(audDocs))
+	idx.grams = make(map[string]map[string]bool)
+	for _, audDoc := range audDocs {
+		idx.addLocked(audDoc.AudioBasic)
+	}
+	idx.built = true
+
+	dbLog.Debug("Search index built", "docs", len(audDocs))
+	return nil
+}
+
+// drops the index, to be rebuilt on next search
+func (idx *searchIndex) reset() {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.built = false
+	idx.texts = nil
+	idx.grams = nil
+}
+
+// adds the audio to the index, if already built
+func (idx *searchIndex) add(aud AudioBasic) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	if idx.built {
+		idx.addLocked(aud)
+	}
+}
+
+func (idx *searchIndex) addLocked(aud AudioBasic) {
+	if _, ok := idx.texts[aud.YtId]; ok {
+		return
+	}
+
+	text := indexedText{title: foldText(aud.Title), uploader: foldText(aud.Uploader)}
+	idx.texts[aud.YtId] = text
+
+	for _, gram := range getGrams(text.title + " " + text.uploader) {
+		if idx.grams[gram] == nil {
+			idx.grams[gram] = make(map[string]bool)
+		}
+		idx.grams[gram][aud.YtId] = true
+	}
+}
+
+// returns the scored matches of the folded search terms
+func (idx *searchIndex) search(terms []string) []searchMatch {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	matches := make([]searchMatch, 0)
+	for _, ytId := range idx.getCandidates(terms) {
+		text := idx.texts[ytId]
+
+		score := 0
+		for _, term := range terms {
+			termScore := titleMatchWeight*getMatchScore(text.title, term) + getMatchScore(text.uploader, term)
+			if termScore == 0 {
+				score = 0
+				break
+			}
+			score += termScore
+		}
+
+		if score > 0 {
+			matches = append(matches, searchMatch{ytId: ytId, score: score})
+		}
+	}
+	return matches
+}
+
+// returns the ids having every trigram of the terms,
+// all ids if the terms are too short for trigrams
+func (idx *searchIndex) getCandidates(terms []string) []string {
+	var candidates map[string]bool
+	for _, term := range terms {
+		for _, gram := range getGrams(term) {
+			ids := idx.grams[gram]
+			if candidates == nil {
+				candidates = make(map[string]bool, len(ids))
+				for ytId := range ids {
+					candidates[ytId] = true
+				}
+				continue
+			}
+			for ytId := range candidates {
+				if !ids[ytId] {
+					delete(candidates, ytId)
+				}
+			}
+		}
+	}
+
+	ytIds := make([]string, 0)
+	if candidates == nil {
+		for ytId := range idx.texts {
+			ytIds = append(ytIds, ytId)
+		}
+		return ytIds
+	}
+	for ytId := range candidates {
+		ytIds = append(ytIds, ytId)
+	}
+	return ytIds
+}
+
+func getMatchScore(text string, term string) int {
+	pos := strings.Index(text, term)
+	if pos < 0 {
+		return 0
+	}
+
+	score := substringMatchScore
+	for _, word := range strings.Fields(text) {
+		if word == term {
+			return wordMatchScore
+		}
+		if strings.HasPrefix(word, term) {
+			score = prefixMatchScore
+		}
+	}
+	return score
+}
+
+func getGrams(text string) []string {
+	runes := []rune(text)
+	grams := make([]string, 0)
+	for i := 0; i+searchGramSize <= len(runes); i++ {
+		gram := string(runes[i : i+searchGramSize])
+		if !strings.Contains(gram, " ") {
+			grams = append(grams, gram)
+		}
+	}
+	return grams
+}
+
+func getPlayCount(audDocMap map[string]*audioDoc, ytId string) int {
+	if audDoc, ok := audDocMap[ytId]; ok {
+		return audDoc.PlayCount
+	}
+	return 0
+}
+
+// lower cases the text and removes accents, ex: Beyoncé -> beyonce.
+// The decomposed marks are dropped, letters like ø or ß are kept as is
+func foldText(text string) string {
+	var folded strings.Builder
+	for _, r := range norm.NFD.String(strings.ToLower(text)) {
+		if unicode.Is(unicode.Mn, r) {
+			continue
+		}
+		folded.WriteRune(r)
+	}
+	return folded.String()
+}
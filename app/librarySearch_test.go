@@ -0,0 +1,57 @@
+package app
+
+import "testing"
+
+func TestFoldText(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"Beyoncé", "beyonce"},
+		{"Sigur Rós", "sigur ros"},
+		{"Motörhead", "motorhead"},
+		{"Ñandú Çà", "nandu ca"},
+		{"Dvořák", "dvorak"},
+		{"été", "ete"},
+		{"Røyksopp", "røyksopp"},
+		{"東京事変", "東京事変"},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := foldText(tt.text); got != tt.want {
+			t.Errorf("foldText(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestFindAudioAccents(t *testing.T) {
+	adb := newTestDatastore(t)
+	for _, aud := range []AudioBasic{
+		{YtId: "beyonce0001", Title: "Halo", Uploader: "Beyoncé"},
+		{YtId: "sigurros001", Title: "Hoppípolla", Uploader: "Sigur Rós"},
+	} {
+		if err := adb.SaveOrIncrementAudioDoc(aud); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		text string
+		ytId string
+	}{
+		{"beyonce", "beyonce0001"},
+		{"BEYONCÉ halo", "beyonce0001"},
+		{"hoppipolla", "sigurros001"},
+		{"sigur rós", "sigurros001"},
+	}
+	for _, tt := range tests {
+		audDocs, err := adb.FindAudio(tt.text, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(audDocs) != 1 || audDocs[0].YtId != tt.ytId {
+			t.Errorf("FindAudio(%q) = %d docs, want %s", tt.text, len(audDocs), tt.ytId)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	listenBrainzName      = "listenbrainz"
+	listenBrainzBatchSize = 100
+	listenBrainzTimeout   = 30 * time.Second
+)
+
+// listenBrainzScrobbler submits listens to the ListenBrainz api
+type listenBrainzScrobbler struct {
+	apiRoot string
+	token   string
+	client  *http.Client
+}
+
+type listenBrainzSubmission struct {
+	ListenType string               `json:"listen_type"`
+	Payload    []listenBrainzListen `json:"payload"`
+}
+
+type listenBrainzListen struct {
+	ListenedAt    int64                     `json:"listened_at,omitempty"`
+	TrackMetadata listenBrainzTrackMetadata `json:"track_metadata"`
+}
+
+type listenBrainzTrackMetadata struct {
+	ArtistName     string                 `json:"artist_name"`
+	TrackName      string                 `json:"track_name"`
+	AdditionalInfo map[string]interface{} `json:"additional_info"`
+}
+
+func newListenBrainzScrobbler(apiRoot string, token string) *listenBrainzScrobbler {
+	return &listenBrainzScrobbler{
+		apiRoot: strings.TrimSuffix(apiRoot, "/"),
+		token:   token,
+		client:  &http.Client{Timeout: listenBrainzTimeout},
+	}
+}
+
+func (lb *listenBrainzScrobbler) Name() string {
+	return listenBrainzName
+}
+
+func (lb *listenBrainzScrobbler) BatchSize() int {
+	return listenBrainzBatchSize
+}
+
+func (lb *listenBrainzScrobbler) NowPlaying(audio AudioBasic) error {
+	return lb.submitListens("playing_now", []listenBrainzListen{{TrackMetadata: getListenBrainzMetadata(audio)}})
+}
+
+func (lb *listenBrainzScrobbler) Submit(batch []*Scrobble) error {
+	listens := make([]listenBrainzListen, len(batch))
+	for i, scrobble := range batch {
+		listens[i] = listenBrainzListen{
+			ListenedAt:    scrobble.ListenedAt.Unix(),
+			TrackMetadata: getListenBrainzMetadata(scrobble.AudioBasic),
+		}
+	}
+
+	listenType := "import"
+	if len(listens) == 1 {
+		listenType = "single"
+	}
+	return lb.submitListens(listenType, listens)
+}
+
+func (lb *listenBrainzScrobbler) submitListens(listenType string, listens []listenBrainzListen) error {
+	body, err := json.Marshal(listenBrainzSubmission{ListenType: listenType, Payload: listens})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, lb.apiRoot+"/1/submit-listens", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Token "+lb.token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := lb.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	scrobbleLog.Debug("ListenBrainz response", "type", listenType, "status", resp.Status)
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	err = fmt.Errorf("[listenBrainz] bad response: %s %s", resp.Status, strings.TrimSpace(string(respBody)))
+
+	// invalid listens will fail again, others like an invalid token are retried
+	if resp.StatusCode == http.StatusBadRequest {
+		return scrobbleError{err}
+	}
+	return err
+}
+
+func getListenBrainzMetadata(audio AudioBasic) listenBrainzTrackMetadata {
+	info := map[string]interface{}{
+		"origin_url":                getAudioSourceUrl(audio.YtId),
+		"submission_client":         "ludo",
+		"submission_client_version": Version,
+	}
+	if audio.Duration > 0 {
+		info["duration_ms"] = audio.Duration * 1000
+	}
+
+	artist, track := getArtistTrack(audio)
+	return listenBrainzTrackMetadata{
+		ArtistName:     artist,
+		TrackName:      track,
+		AdditionalInfo: info,
+	}
+}
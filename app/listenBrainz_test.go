@@ -0,0 +1,139 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+const testScrobbleToken = "token"
+
+// points the global datastore to a temp datastore for the test
+func setTestAudioDb(t *testing.T) {
+	t.Helper()
+	prevDb := audioDb.db
+	audioDb.db = newTestDatastore(t).db
+	t.Cleanup(func() { audioDb.db = prevDb })
+}
+
+// queues count listens of the scrobbler
+func queueTestScrobbles(t *testing.T, service string, count int) {
+	t.Helper()
+	for i := 0; i < count; i++ {
+		scrobble := &Scrobble{AudioBasic: testAudio(i), Service: service, ListenedAt: time.Unix(int64(1_700_000_000+i), 0)}
+		if err := audioDb.SaveScrobble(scrobble); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func getTestScrobbles(t *testing.T, service string) []*Scrobble {
+	t.Helper()
+	scrobbleList, err := audioDb.GetScrobbles(service, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return scrobbleList
+}
+
+func TestListenBrainzSubmit(t *testing.T) {
+	var submission listenBrainzSubmission
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/1/submit-listens" {
+			t.Errorf("request = %s %s, want POST /1/submit-listens", r.Method, r.URL.Path)
+		}
+		if auth := r.Header.Get("Authorization"); auth != "Token "+testScrobbleToken {
+			t.Errorf("authorization = %q", auth)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&submission); err != nil {
+			t.Errorf("invalid body: %v", err)
+		}
+		w.Write([]byte(`{"status": "ok"}`))
+	}))
+	defer server.Close()
+
+	lb := newListenBrainzScrobbler(server.URL+"/", testScrobbleToken)
+	listenedAt := time.Unix(1_700_000_000, 0)
+	audio := AudioBasic{YtId: "abcdefghijk", Title: "Daft Punk - Get Lucky", Uploader: "Uploader", Duration: 248}
+	if err := lb.Submit([]*Scrobble{{AudioBasic: audio, ListenedAt: listenedAt}}); err != nil {
+		t.Fatalf("submit: %v", err)
+	}
+
+	if submission.ListenType != "single" || len(submission.Payload) != 1 {
+		t.Fatalf("submission = %+v, want a single listen", submission)
+	}
+	listen := submission.Payload[0]
+	if listen.ListenedAt != listenedAt.Unix() {
+		t.Errorf("listened at = %d, want %d", listen.ListenedAt, listenedAt.Unix())
+	}
+	if listen.TrackMetadata.ArtistName != "Daft Punk" || listen.TrackMetadata.TrackName != "Get Lucky" {
+		t.Errorf("track = %q - %q, want Daft Punk - Get Lucky", listen.TrackMetadata.ArtistName, listen.TrackMetadata.TrackName)
+	}
+	info := listen.TrackMetadata.AdditionalInfo
+	if info["origin_url"] != getAudioSourceUrl(audio.YtId) || info["duration_ms"] != 248000.0 {
+		t.Errorf("additional info = %v", info)
+	}
+}
+
+func TestListenBrainzSubmitQueue(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		pending  int
+		attempts int
+		rejected bool
+	}{
+		{"accepted", http.StatusOK, 0, 0, false},
+		{"server error is retried", http.StatusInternalServerError, 3, 1, false},
+		{"invalid token is retried", http.StatusUnauthorized, 3, 1, false},
+		{"invalid listens are dropped", http.StatusBadRequest, 0, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setTestAudioDb(t)
+			queueTestScrobbles(t, listenBrainzName, 3)
+
+			var payload int
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var submission listenBrainzSubmission
+				json.NewDecoder(r.Body).Decode(&submission)
+				payload = len(submission.Payload)
+				w.WriteHeader(tt.status)
+				w.Write([]byte(`{"code": 0, "error": "stub"}`))
+			}))
+			defer server.Close()
+
+			lb := newListenBrainzScrobbler(server.URL, testScrobbleToken)
+			var service scrobbleService
+			err := service.submitQueue(lb)
+			if payload != 3 {
+				t.Errorf("submitted listens = %d, want 3", payload)
+			}
+			if tt.pending > 0 && err == nil {
+				t.Errorf("submit queue gave no error for status %d", tt.status)
+			}
+			if tt.pending == 0 && err != nil {
+				t.Errorf("submit queue: %v", err)
+			}
+
+			err = lb.Submit(nil)
+			if rejected := errors.As(err, &scrobbleError{}); rejected != tt.rejected {
+				t.Errorf("rejected = %v, want %v: %v", rejected, tt.rejected, err)
+			}
+
+			pending := getTestScrobbles(t, listenBrainzName)
+			if len(pending) != tt.pending {
+				t.Fatalf("pending listens = %d, want %d", len(pending), tt.pending)
+			}
+			for _, scrobble := range pending {
+				if scrobble.Attempts != tt.attempts {
+					t.Errorf("attempts = %d, want %d", scrobble.Attempts, tt.attempts)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,264 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// the subsystem loggers share the output and levels, set from the config on init
+var (
+	logLevels  = &levelConfig{defaultLevel: slog.LevelInfo, levels: map[string]slog.Level{}}
+	logOut     = &logOutput{}
+	logHandler = slog.NewTextHandler(logOut, &slog.HandlerOptions{Level: slog.LevelDebug})
+)
+
+var logOptions LogOptions
+
+// LogOptions are the logging flags of the command line, they take precedence over the config
+type LogOptions struct {
+	Debug bool   // log everything at debug level
+	File  string // log file, default is config.log.file
+}
+
+// sets the logging flags, to be called before Init
+func SetLogOptions(options LogOptions) {
+	logOptions = options
+}
+
+// returns the last log entries at or above the level, oldest first
+func RecentLogs(count int, minLevel slog.Level) []string {
+	var lines []string
+	for _, line := range logTail.tail() {
+		if LogLineLevel(line) >= minLevel {
+			lines = append(lines, line)
+		}
+	}
+	if count > 0 && count < len(lines) {
+		lines = lines[len(lines)-count:]
+	}
+	return lines
+}
+
+// returns the level of a log entry written by the text handler, ex: time=... level=WARN msg=...
+func LogLineLevel(line string) slog.Level {
+	var level slog.Level
+	if _, rest, ok := strings.Cut(line, " level="); ok {
+		value, _, _ := strings.Cut(rest, " ")
+		level.UnmarshalText([]byte(value))
+	}
+	return level
+}
+
+// returns a logger of the subsystem, its level can be set by config.log.level.<subsystem>
+func newLogger(subsystem string) *slog.Logger {
+	return slog.New(&subsystemHandler{
+		subsystem: subsystem,
+		handler:   logHandler.WithAttrs([]slog.Attr{slog.String("subsystem", subsystem)}),
+	})
+}
+
+// returns a logger of the subsystem for the frontends, see newLogger
+func NewLogger(subsystem string) *slog.Logger {
+	return newLogger(subsystem)
+}
+
+// sets the levels and the log file from the config,
+// the global log package is also written to the log
+func setLogConfig(cfg *appConfig) error {
+	// the levels are validated by the config
+	var defaultLevel slog.Level
+	defaultLevel.UnmarshalText([]byte(cfg.logLevel))
+	levels := make(map[string]slog.Level)
+	for subsystem, value := range cfg.logLevels {
+		var level slog.Level
+		level.UnmarshalText([]byte(value))
+		levels[subsystem] = level
+	}
+	if logOptions.Debug {
+		defaultLevel, levels = slog.LevelDebug, map[string]slog.Level{}
+	}
+	logLevels.set(defaultLevel, levels)
+
+	logPath := logOptions.File
+	if logPath == "" {
+		localDr, _ := getLudoDir()
+		logPath = cfg.logFile
+		if logPath == "" {
+			logPath = filepath.Join(localDr, defaultLogFile)
+		}
+	}
+	file, err := openRotatingFile(logPath, int64(cfg.logMaxSize)<<20, cfg.logBackups)
+	if err != nil {
+		return fmt.Errorf("error in opening log file: %w", err)
+	}
+	logOut.setFile(file)
+
+	slog.SetDefault(newLogger("main"))
+	return nil
+}
+
+func closeLog() {
+	logOut.setFile(nil)
+}
+
+// levelConfig holds the default level and the overrides of the subsystems
+type levelConfig struct {
+	mu           sync.RWMutex
+	defaultLevel slog.Level
+	levels       map[string]slog.Level
+}
+
+func (config *levelConfig) set(defaultLevel slog.Level, levels map[string]slog.Level) {
+	config.mu.Lock()
+	defer config.mu.Unlock()
+	config.defaultLevel, config.levels = defaultLevel, levels
+}
+
+func (config *levelConfig) level(subsystem string) slog.Level {
+	config.mu.RLock()
+	defer config.mu.RUnlock()
+	if level, ok := config.levels[subsystem]; ok {
+		return level
+	}
+	return config.defaultLevel
+}
+
+// subsystemHandler drops the records below the level of its subsystem
+type subsystemHandler struct {
+	subsystem string
+	handler   slog.Handler
+}
+
+func (h *subsystemHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= logLevels.level(h.subsystem)
+}
+
+func (h *subsystemHandler) Handle(ctx context.Context, record slog.Record) error {
+	return h.handler.Handle(ctx, record)
+}
+
+func (h *subsystemHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &subsystemHandler{subsystem: h.subsystem, handler: h.handler.WithAttrs(attrs)}
+}
+
+func (h *subsystemHandler) WithGroup(name string) slog.Handler {
+	return &subsystemHandler{subsystem: h.subsystem, handler: h.handler.WithGroup(name)}
+}
+
+// logOutput writes the log to the crash report tail and the log file
+type logOutput struct {
+	mu   sync.Mutex
+	file *rotatingFile
+}
+
+func (out *logOutput) setFile(file *rotatingFile) {
+	out.mu.Lock()
+	defer out.mu.Unlock()
+	if out.file != nil {
+		out.file.Close()
+	}
+	out.file = file
+}
+
+func (out *logOutput) Write(p []byte) (int, error) {
+	logTail.Write(p)
+
+	out.mu.Lock()
+	defer out.mu.Unlock()
+	if out.file == nil {
+		return len(p), nil
+	}
+	return out.file.Write(p)
+}
+
+// rotatingFile is a log file which is moved to <path>.1 when it reaches the max size,
+// the older files are shifted up to the backup count. If the file cannot be reopened
+// after a rotation, the log goes to stderr until it can
+type rotatingFile struct {
+	path    string
+	maxSize int64
+	backups int
+	file    *os.File
+	size    int64
+}
+
+func openRotatingFile(path string, maxSize int64, backups int) (*rotatingFile, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+
+	rf := &rotatingFile{path: path, maxSize: maxSize, backups: backups}
+	if err := rf.open(); err != nil {
+		return nil, err
+	}
+	return rf, nil
+}
+
+func (rf *rotatingFile) open() error {
+	file, err := os.OpenFile(rf.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	rf.file, rf.size = file, info.Size()
+	return nil
+}
+
+func (rf *rotatingFile) Write(p []byte) (int, error) {
+	if rf.file != nil && rf.maxSize > 0 && rf.size > 0 && rf.size+int64(len(p)) > rf.maxSize {
+		// on a failed move the writes continue in the same file
+		rf.rotate()
+	}
+	if rf.file == nil {
+		if err := rf.open(); err != nil {
+			return os.Stderr.Write(p)
+		}
+	}
+
+	n, err := rf.file.Write(p)
+	rf.size += int64(n)
+	return n, err
+}
+
+// the file is closed before it is moved, as open files cannot be renamed on windows
+func (rf *rotatingFile) rotate() error {
+	err := rf.file.Close()
+	rf.file = nil
+	if err != nil {
+		return err
+	}
+
+	if rf.backups > 0 {
+		for i := rf.backups - 1; i > 0; i-- {
+			os.Rename(rf.backupPath(i), rf.backupPath(i+1))
+		}
+		err = os.Rename(rf.path, rf.backupPath(1))
+	} else {
+		err = os.Remove(rf.path)
+	}
+
+	if openErr := rf.open(); openErr != nil {
+		return openErr
+	}
+	return err
+}
+
+func (rf *rotatingFile) backupPath(i int) string {
+	return fmt.Sprintf("%s.%d", rf.path, i)
+}
+
+func (rf *rotatingFile) Close() error {
+	if rf.file == nil {
+		return nil
+	}
+	return rf.file.Close()
+}
@@ -1,76 +1,137 @@
 package app
 
 import (
-	"io"
-	"log"
+	"errors"
+	"fmt"
 	"path/filepath"
+	"strconv"
 )
 
 var Version string
 
-var appLog = log.New(io.Discard, "App:", log.LstdFlags|log.Lmsgprefix)
+var appLog = newLogger("app")
 
 var isRunning = false
 
+var isLibraryOpen = false
+
 func Init() error {
 	if isRunning {
 		return nil
 	}
+	if err := InitLibrary(); err != nil {
+		return err
+	}
+
+	cfg := currentConfig()
+
+	// start scrobbling
+	setScrobbleConfig(cfg)
+
+	// load audio player
+	if err := vlcPlayer.InitPlayer(); err != nil {
+		return err
+	}
+
+	// start http api
+	if err := setHttpConfig(cfg); err != nil {
+		return err
+	}
+
+	// expose the player on dbus
+	if err := startMpris(cfg); err != nil {
+		return err
+	}
+
+	isRunning = true
+	return nil
+}
+
+// loads the properties, library and cache without the player,
+// for one-shot operations like search and library queries
+func InitLibrary() error {
+	if isLibraryOpen {
+		return nil
+	}
 	// Load properties file
 	lprops, err := loadProperties()
 	if err != nil {
 		return err
 	}
-	props = *lprops
+	setProps(lprops)
+	cfg := currentConfig()
+
+	// Set log config
+	if err := setLogConfig(cfg); err != nil {
+		return err
+	}
+	checkConfig(lprops)
 
 	// Set Piped config
-	setPipedConfig(props)
+	setPipedConfig(cfg)
 
 	// Load database
 	localDr, _ := getLudoDir()
-	dbPath := props.GetString(dataStoreKey, localDr)
+	dbPath := cfg.dataStore
+	if dbPath == "" {
+		dbPath = localDr
+	}
 
-	if err := audioDb.InitDb(dbPath); err != nil {
+	backupDir := filepath.Join(localDr, defaultBackupDir)
+	if err := audioDb.InitDb(dbPath, backupDir); err != nil {
 		return err
 	}
 
 	// load Cache
-	defCachePath := filepath.Join(localDr, defaultCacheDir)
-	cachePath := props.GetString(cacheDirKey, defCachePath)
-	audioCache.isEnabled = props.GetBool(isCacheEnabledKey, true)
-	if err := audioCache.Init(cachePath); err != nil {
-		return err
+	cachePath := cfg.cacheDir
+	if cachePath == "" {
+		cachePath = filepath.Join(localDr, defaultCacheDir)
 	}
-
-	// load audio player
-	if err := vlcPlayer.InitPlayer(); err != nil {
+	audioCache.isEnabled = cfg.isCacheEnabled
+	if err := audioCache.Init(cachePath); err != nil {
 		return err
 	}
 
-	isRunning = true
+	isLibraryOpen = true
 	return nil
 }
 
+// closes the player and the library, the teardown continues after
+// an error and all the errors are returned
 func Close() error {
-	if !isRunning {
+	if !isLibraryOpen {
 		return nil
 	}
-	if err := vlcPlayer.ClosePlayer(); err != nil {
-		return err
-	}
-	localDr, _ := getLudoDir()
-	dumpPath := filepath.Join(localDr, "dumps.json")
 
-	audioDb.db.ExportCollection(audioDocCollection, dumpPath)
+	var errs []error
+	// backups are taken only on exit of the player, not after one-shot operations
+	if isRunning {
+		httpApi.stop()
+		stopMpris()
+		if err := vlcPlayer.ClosePlayer(); err != nil {
+			appLog.Error("Error in closing player", "err", err)
+			errs = append(errs, err)
+		}
+		scrobbles.stop()
+
+		if _, err := BackupDb(); err != nil {
+			appLog.Error("Error in backing up database", "err", err)
+		}
+	}
 
 	if err := audioDb.CloseDb(); err != nil {
-		return err
+		appLog.Error("Error in closing database", "err", err)
+		errs = append(errs, err)
 	}
 
-	audioCache.Close()
+	if err := audioCache.Close(); err != nil {
+		appLog.Error("Error in closing cache", "err", err)
+		errs = append(errs, err)
+	}
+	closeLog()
 
-	isRunning = false
-	return nil
+	isRunning, isLibraryOpen = false, false
+	return errors.Join(errs...)
 }
 
 func MediaPlayer() *VlcPlayer {
@@ -81,8 +142,102 @@ func AudioDb() *AudioDatastore {
 	return &audioDb
 }
 
+func AudioCache() *CacheStore {
+	return &audioCache
+}
+
 // App Functions
 
+// returns the unix socket path of the daemon from the properties,
+// default is ludo.sock in the ludo dir. Works without Init for clients of the daemon
+func GetDaemonSocket() (string, error) {
+	localDr, err := getLudoDir()
+	if err != nil {
+		return "", err
+	}
+
+	cfg := currentConfig()
+	if !isLibraryOpen {
+		lprops, err := loadProperties()
+		if err != nil {
+			return "", err
+		}
+		cfg = loadConfig(lprops)
+	}
+	if cfg.daemonSocket == "" {
+		return filepath.Join(localDr, defaultSocketFile), nil
+	}
+	return cfg.daemonSocket, nil
+}
+
 func IsSourcePiped() bool {
-	return props.GetBool(isSourcePiped, true)
+	return currentConfig().isSourcePiped
+}
+
+// sets the source for audio searching and saves it to the properties file
+func SetSourcePiped(isPiped bool) error {
+	_, err := SetConfig(isSourcePiped, strconv.FormatBool(isPiped))
+	return err
+}
+
+// sets the piped api and saves it to the properties file
+func SetPipedApi(apiUrl string) error {
+	_, err := SetConfig(pipedApiKey, apiUrl)
+	return err
+}
+
+func ExportCache(exportDir string) (int, error) {
+	return audioCache.ExportCache(exportDir, currentConfig().exportTemplate)
+}
+
+// exports the library into the backup dir of ludo,
+// keeping the latest backups as per config
+func BackupDb() (string, error) {
+	localDr, _ := getLudoDir()
+	backupDir := filepath.Join(localDr, defaultBackupDir)
+	return audioDb.BackupDb(backupDir, currentConfig().backupCount)
+}
+
+func ExportDb(filePath string) error {
+	return audioDb.ExportDb(filePath)
+}
+
+// imports the library from the dump file, after taking a backup.
+// The library is restored from the backup if the import fails midway
+func ImportDb(filePath string, merge bool) (ImportReport, error) {
+	backupPath, err := BackupDb()
+	if err != nil {
+		return ImportReport{}, err
+	}
+
+	report, err := audioDb.ImportDb(filePath, merge)
+	if err != nil {
+		appLog.Error("Import failed, restoring backup", "path", backupPath, "err", err)
+		if _, restoreErr := audioDb.ImportDb(backupPath, false); restoreErr != nil {
+			return report, fmt.Errorf("%w, restoring the backup %s failed: %v", err, backupPath, restoreErr)
+		}
+		return ImportReport{}, fmt.Errorf("%w, the library was restored", err)
+	}
+	return report, nil
+}
+
+// requests a submission of the queued listens,
+// returns the number of listens pending for each scrobbler
+func GetScrobbleStatus() ([]ScrobbleStatus, error) {
+	scrobbles.flush()
+	return scrobbles.status()
+}
+
+// returns the rating marker of the given audio ids, mapped by id
+func GetRatingMarkers(ytIds ...string) map[string]string {
+	markers := make(map[string]string, len(ytIds))
+	audDocMap, err := audioDb.GetAudioDocMap(ytIds)
+	if err != nil {
+		appLog.Error("Error in fetching ratings", "err", err)
+		return markers
+	}
+	for ytId, audDoc := range audDocMap {
+		markers[ytId] = audDoc.RatingMarker()
+	}
+	return markers
 }
@@ -0,0 +1,191 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var lyricsLog = newLogger("lyrics")
+
+const (
+	lyricsTimeout       = 30 * time.Second
+	lyricsDurationDelta = 5 // seconds
+)
+
+var ErrNoLyrics = errors.New("no lyrics found")
+
+// matches the time tags of a lrc line, ex: [01:02.34]
+var lrcTimeTag = regexp.MustCompile(`\[(\d+):(\d{1,2}(?:[.:]\d{1,3})?)\]`)
+
+// Lyrics of an audio as returned by the lrclib api
+type Lyrics struct {
+	YtId         string
+	TrackName    string  `json:"trackName"`
+	ArtistName   string  `json:"artistName"`
+	Duration     float64 `json:"duration"`
+	Instrumental bool    `json:"instrumental"`
+	PlainLyrics  string  `json:"plainLyrics"`
+	SyncedLyrics string  `json:"syncedLyrics"`
+}
+
+// LyricLine is a line of synced lyrics starting at Time (milliseconds)
+type LyricLine struct {
+	Time int
+	Text string
+}
+
+// returns the lyrics of the current audio
+func GetCurrentLyrics() (*Lyrics, error) {
+	audState := vlcPlayer.GetAudioState()
+	if audState.YtId == "" {
+		return nil, errors.New("no song is playing")
+	}
+	return GetLyrics(audState.AudioBasic)
+}
+
+// returns the lyrics of the audio from the datastore,
+// else fetches them from the lyrics api and saves them
+func GetLyrics(audio AudioBasic) (*Lyrics, error) {
+	lyrics, err := audioDb.GetLyrics(audio.YtId)
+	if err != nil {
+		return nil, err
+	}
+	if lyrics != nil {
+		lyricsLog.Debug("Lyrics from datastore", "ytId", audio.YtId)
+		return lyrics, nil
+	}
+
+	lyrics, err = fetchLyrics(currentConfig().lyricsApi, audio)
+	if err != nil {
+		return nil, err
+	}
+	lyrics.YtId = audio.YtId
+
+	if err := audioDb.SaveLyrics(lyrics); err != nil {
+		lyricsLog.Error("Error in saving lyrics", "err", err)
+	}
+	return lyrics, nil
+}
+
+// fetches the lyrics by title, artist and duration, falling back to
+// a search by title and artist when there is no exact match
+func fetchLyrics(apiUrl string, audio AudioBasic) (*Lyrics, error) {
+	artist, track := getArtistTrack(audio)
+	apiUrl = strings.TrimSuffix(apiUrl, "/")
+
+	params := url.Values{}
+	params.Set("track_name", track)
+	params.Set("artist_name", artist)
+	if audio.Duration > 0 {
+		params.Set("duration", strconv.Itoa(audio.Duration))
+	}
+
+	var lyrics Lyrics
+	err := getLyricsJson(apiUrl+"/api/get?"+params.Encode(), &lyrics)
+	if err == nil {
+		return &lyrics, nil
+	}
+	if !errors.Is(err, ErrNoLyrics) {
+		return nil, err
+	}
+
+	params.Del("duration")
+	var lyricsList []Lyrics
+	if err := getLyricsJson(apiUrl+"/api/search?"+params.Encode(), &lyricsList); err != nil {
+		return nil, err
+	}
+
+	// picks the first result close in duration, preferring synced lyrics
+	sort.SliceStable(lyricsList, func(i, j int) bool {
+		return (lyricsList[i].SyncedLyrics != "") && (lyricsList[j].SyncedLyrics == "")
+	})
+	for i := range lyricsList {
+		if audio.Duration <= 0 || absInt(int(lyricsList[i].Duration)-audio.Duration) <= lyricsDurationDelta {
+			return &lyricsList[i], nil
+		}
+	}
+	return nil, ErrNoLyrics
+}
+
+func getLyricsJson(reqUrl string, result interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, reqUrl, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", "ludo "+Version+" (https://github.com/johnrijoy/ludo-go)")
+
+	client := http.Client{Timeout: lyricsTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return wrapErr(ErrSourceUnavailable, err)
+	}
+	defer resp.Body.Close()
+
+	lyricsLog.Debug("Response", "status", resp.Status)
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrNoLyrics
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%w: [getLyrics] bad response from api: %s", ErrSourceUnavailable, resp.Status)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return wrapErr(ErrSourceUnavailable, err)
+	}
+	return nil
+}
+
+// parses the synced lyrics in lrc format into lines sorted by time
+func (lyrics *Lyrics) SyncedLines() []LyricLine {
+	lines := make([]LyricLine, 0)
+	for _, lrcLine := range strings.Split(lyrics.SyncedLyrics, "\n") {
+		tags := lrcTimeTag.FindAllStringSubmatchIndex(lrcLine, -1)
+		if len(tags) == 0 {
+			continue
+		}
+
+		text := strings.TrimSpace(lrcLine[tags[len(tags)-1][1]:])
+		for _, tag := range tags {
+			minutes, _ := strconv.Atoi(lrcLine[tag[2]:tag[3]])
+			sec, _ := strconv.ParseFloat(strings.Replace(lrcLine[tag[4]:tag[5]], ":", ".", 1), 64)
+			lines = append(lines, LyricLine{Time: minutes*60000 + int(sec*1000), Text: text})
+		}
+	}
+
+	sort.SliceStable(lines, func(i, j int) bool { return lines[i].Time < lines[j].Time })
+	return lines
+}
+
+// returns the plain lyrics as lines, taken from the synced lyrics if there are no plain lyrics
+func (lyrics *Lyrics) PlainLines() []string {
+	if lyrics.PlainLyrics != "" {
+		return strings.Split(strings.TrimSpace(lyrics.PlainLyrics), "\n")
+	}
+
+	syncedLines := lyrics.SyncedLines()
+	lines := make([]string, len(syncedLines))
+	for i, line := range syncedLines {
+		lines[i] = line.Text
+	}
+	return lines
+}
+
+// returns the index of the line being sung at the position (seconds), -1 before the first line
+func CurrentLyricLine(lines []LyricLine, pos int) int {
+	return sort.Search(len(lines), func(i int) bool { return lines[i].Time > pos*1000 }) - 1
+}
+
+func absInt(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
@@ -0,0 +1,97 @@
+package app
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestFetchLyrics(t *testing.T) {
+	audio := AudioBasic{YtId: "abcdefghijk", Title: "Get Lucky", Uploader: "Daft Punk - Topic", Duration: 248}
+
+	tests := []struct {
+		name   string
+		get    func(w http.ResponseWriter)
+		search func(w http.ResponseWriter)
+		lyrics string
+		err    error
+	}{
+		{
+			name:   "exact match",
+			get:    func(w http.ResponseWriter) { w.Write([]byte(`{"trackName": "Get Lucky", "plainLyrics": "exact"}`)) },
+			lyrics: "exact",
+		},
+		{
+			name: "search close in duration",
+			get:  func(w http.ResponseWriter) { w.WriteHeader(http.StatusNotFound) },
+			search: func(w http.ResponseWriter) {
+				w.Write([]byte(`[{"duration": 300, "plainLyrics": "far"}, {"duration": 250, "plainLyrics": "close"}]`))
+			},
+			lyrics: "close",
+		},
+		{
+			name:   "no lyrics",
+			get:    func(w http.ResponseWriter) { w.WriteHeader(http.StatusNotFound) },
+			search: func(w http.ResponseWriter) { w.Write([]byte(`[{"duration": 300, "plainLyrics": "far"}]`)) },
+			err:    ErrNoLyrics,
+		},
+		{
+			name: "server error",
+			get:  func(w http.ResponseWriter) { w.WriteHeader(http.StatusInternalServerError) },
+			err:  ErrSourceUnavailable,
+		},
+		{
+			name:   "server error on search",
+			get:    func(w http.ResponseWriter) { w.WriteHeader(http.StatusNotFound) },
+			search: func(w http.ResponseWriter) { w.WriteHeader(http.StatusBadGateway) },
+			err:    ErrSourceUnavailable,
+		},
+		{
+			name: "invalid json",
+			get:  func(w http.ResponseWriter) { w.Write([]byte(`<html>`)) },
+			err:  ErrSourceUnavailable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch {
+				case r.URL.Path == "/api/get" && tt.get != nil:
+					tt.get(w)
+				case r.URL.Path == "/api/search" && tt.search != nil:
+					tt.search(w)
+				default:
+					t.Errorf("unexpected request %s", r.URL)
+					w.WriteHeader(http.StatusNotFound)
+				}
+			}))
+			defer server.Close()
+
+			lyrics, err := fetchLyrics(server.URL+"/", audio)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("err = %v, want %v", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("fetch: %v", err)
+			}
+			if lyrics.PlainLyrics != tt.lyrics {
+				t.Errorf("lyrics = %q, want %q", lyrics.PlainLyrics, tt.lyrics)
+			}
+		})
+	}
+}
+
+func TestFetchLyricsUnreachable(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+
+	_, err := fetchLyrics(server.URL, AudioBasic{Title: "Title", Uploader: "Uploader"})
+	if !errors.Is(err, ErrSourceUnavailable) || !IsRecoverable(err) {
+		t.Errorf("err = %v, want %v", err, ErrSourceUnavailable)
+	}
+}
@@ -0,0 +1,473 @@
+//go:build linux
+
+package app
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"strings"
+
+	vlc "github.com/adrg/libvlc-go/v3"
+	"github.com/godbus/dbus/v5"
+	"github.com/godbus/dbus/v5/introspect"
+	"github.com/godbus/dbus/v5/prop"
+)
+
+var mprisLog = newLogger("mpris")
+
+var mpris mprisServer
+
+const (
+	mprisPath           = "/org/mpris/MediaPlayer2"
+	mprisBusName        = "org.mpris.MediaPlayer2.ludo"
+	mprisRootIface      = "org.mpris.MediaPlayer2"
+	mprisPlayerIface    = "org.mpris.MediaPlayer2.Player"
+	mprisTrackListIface = "org.mpris.MediaPlayer2.TrackList"
+	mprisTrackPrefix    = "/io/github/johnrijoy/ludo/track/"
+	mprisNoTrack        = dbus.ObjectPath("/org/mpris/MediaPlayer2/TrackList/NoTrack")
+)
+
+// methods exported under another name, ex: go vet expects a Go method
+// named Seek to have the signature of io.Seeker
+var mprisMethodNames = map[string]string{"SeekBy": "Seek"}
+
+var errMprisNotSupported = dbus.NewError("org.mpris.MediaPlayer2.ludo.Error.NotSupported", []interface{}{"not supported"})
+
+// mprisServer exposes the player on the session bus for media keys, desktop widgets and playerctl.
+// The properties follow the player events
+type mprisServer struct {
+	conn    *dbus.Conn
+	props   *prop.Properties
+	stopSig chan struct{}
+	done    chan struct{}
+}
+
+// org.mpris.MediaPlayer2
+type mprisRoot struct{}
+
+// org.mpris.MediaPlayer2.Player
+type mprisPlayer struct{}
+
+// org.mpris.MediaPlayer2.TrackList
+type mprisTrackList struct{}
+
+// starts the mpris server unless disabled, a missing session bus is not an error
+func startMpris(cfg *appConfig) error {
+	if !cfg.mprisEnabled {
+		return nil
+	}
+
+	if err := mpris.start(); err != nil {
+		mprisLog.Warn("Not started", "err", err)
+	}
+	return nil
+}
+
+func stopMpris() {
+	mpris.stop()
+}
+
+func (server *mprisServer) start() error {
+	conn, err := dbus.ConnectSessionBus()
+	if err != nil {
+		return err
+	}
+
+	objects := []struct {
+		object interface{}
+		iface  string
+	}{
+		{mprisRoot{}, mprisRootIface},
+		{mprisPlayer{}, mprisPlayerIface},
+		{mprisTrackList{}, mprisTrackListIface},
+	}
+	node := &introspect.Node{Name: mprisPath, Interfaces: []introspect.Interface{introspect.IntrospectData, prop.IntrospectData}}
+	for _, obj := range objects {
+		if err := conn.ExportWithMap(obj.object, mprisMethodNames, mprisPath, obj.iface); err != nil {
+			conn.Close()
+			return err
+		}
+		methods := introspect.Methods(obj.object)
+		for i, method := range methods {
+			if name, ok := mprisMethodNames[method.Name]; ok {
+				methods[i].Name = name
+			}
+		}
+		node.Interfaces = append(node.Interfaces, introspect.Interface{Name: obj.iface, Methods: methods})
+	}
+
+	snapshot := getPlayerSnapshot()
+	props, err := prop.Export(conn, mprisPath, getMprisProps(snapshot))
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	signals := map[string][]introspect.Signal{
+		mprisPlayerIface: {{Name: "Seeked", Args: []introspect.Arg{{Name: "Position", Type: "x"}}}},
+		mprisTrackListIface: {{Name: "TrackListReplaced", Args: []introspect.Arg{
+			{Name: "Tracks", Type: "ao"}, {Name: "CurrentTrack", Type: "o"},
+		}}},
+	}
+	for i := range node.Interfaces[2:] {
+		iface := &node.Interfaces[i+2]
+		iface.Properties = props.Introspection(iface.Name)
+		iface.Signals = signals[iface.Name]
+	}
+	if err := conn.Export(introspect.NewIntrospectable(node), mprisPath, "org.freedesktop.DBus.Introspectable"); err != nil {
+		conn.Close()
+		return err
+	}
+
+	// another ludo owns the name, ex: the daemon and a tui
+	busName := mprisBusName
+	reply, err := conn.RequestName(busName, dbus.NameFlagDoNotQueue)
+	if err == nil && reply != dbus.RequestNameReplyPrimaryOwner {
+		busName = fmt.Sprintf("%s.instance%d", mprisBusName, os.Getpid())
+		reply, err = conn.RequestName(busName, dbus.NameFlagDoNotQueue)
+	}
+	if err != nil || reply != dbus.RequestNameReplyPrimaryOwner {
+		conn.Close()
+		return fmt.Errorf("could not own bus name %s: %v", busName, err)
+	}
+	mprisLog.Info("Owning bus name", "name", busName)
+
+	server.conn, server.props = conn, props
+	server.stopSig = make(chan struct{})
+	server.done = make(chan struct{})
+	go server.watch()
+	return nil
+}
+
+func (server *mprisServer) stop() {
+	if server.conn == nil {
+		return
+	}
+	close(server.stopSig)
+	<-server.done
+	server.conn.Close()
+	server.conn = nil
+}
+
+// updates the properties from the player events until stopped
+func (server *mprisServer) watch() {
+	defer HandlePanic()
+	defer close(server.done)
+
+	events, _, snapshot := playerEvents.subscribe(0, false)
+	server.setSnapshot(snapshot.Data.(PlayerSnapshot))
+	lastId := snapshot.Id
+
+	for {
+		select {
+		case <-server.stopSig:
+			playerEvents.unsubscribe(events)
+			return
+		case event, ok := <-events:
+			if !ok {
+				// dropped as a slow subscriber, resume from the last event
+				var replay []PlayerEvent
+				events, replay, snapshot = playerEvents.subscribe(lastId, true)
+				if snapshot != nil {
+					server.setSnapshot(snapshot.Data.(PlayerSnapshot))
+					lastId = snapshot.Id
+				}
+				for _, event := range replay {
+					server.handleEvent(event)
+					lastId = event.Id
+				}
+				continue
+			}
+			server.handleEvent(event)
+			lastId = event.Id
+		}
+	}
+}
+
+func (server *mprisServer) handleEvent(event PlayerEvent) {
+	switch data := event.Data.(type) {
+	case PlayerStatus:
+		server.setStatus(data)
+	case QueueStatus:
+		server.setQueue(data)
+	case PositionChange:
+		position := int64(data.Position) * 1e6
+		server.props.SetMust(mprisPlayerIface, "Position", position)
+		if data.Seek {
+			if err := server.conn.Emit(mprisPath, mprisPlayerIface+".Seeked", position); err != nil {
+				mprisLog.Error("Error in emitting Seeked", "err", err)
+			}
+		}
+	case map[string]string:
+		server.props.SetMust(mprisPlayerIface, "PlaybackStatus", getMprisPlaybackStatus(data["state"]))
+	case map[string]int:
+		server.props.SetMust(mprisPlayerIface, "Volume", float64(data["volume"])/100)
+	}
+}
+
+func (server *mprisServer) setSnapshot(snapshot PlayerSnapshot) {
+	server.setStatus(snapshot.Status)
+	server.setQueue(snapshot.Queue)
+	server.props.SetMust(mprisPlayerIface, "PlaybackStatus", getMprisPlaybackStatus(snapshot.Status.State))
+	server.props.SetMust(mprisPlayerIface, "Position", int64(snapshot.Status.Position)*1e6)
+	server.props.SetMust(mprisPlayerIface, "Volume", float64(snapshot.Volume)/100)
+}
+
+func (server *mprisServer) setStatus(status PlayerStatus) {
+	server.props.SetMust(mprisPlayerIface, "Metadata", getMprisCurrentMetadata())
+	server.props.SetMust(mprisPlayerIface, "Position", int64(status.Position)*1e6)
+}
+
+func (server *mprisServer) setQueue(queue QueueStatus) {
+	trackIds := getMprisTrackIds(queue.Queue)
+	server.props.SetMust(mprisPlayerIface, "CanGoNext", queue.Index+1 < len(queue.Queue))
+	server.props.SetMust(mprisPlayerIface, "CanGoPrevious", queue.Index > 0)
+	server.props.SetMust(mprisPlayerIface, "CanPlay", len(queue.Queue) > 0)
+	server.props.SetMust(mprisTrackListIface, "Tracks", trackIds)
+
+	current := mprisNoTrack
+	if queue.Index >= 0 && queue.Index < len(trackIds) {
+		current = trackIds[queue.Index]
+	}
+	if err := server.conn.Emit(mprisPath, mprisTrackListIface+".TrackListReplaced", trackIds, current); err != nil {
+		mprisLog.Error("Error in emitting TrackListReplaced", "err", err)
+	}
+}
+
+func getMprisProps(snapshot PlayerSnapshot) prop.Map {
+	return prop.Map{
+		mprisRootIface: {
+			"CanQuit":             {Value: false, Emit: prop.EmitConst},
+			"CanRaise":            {Value: false, Emit: prop.EmitConst},
+			"HasTrackList":        {Value: true, Emit: prop.EmitConst},
+			"Identity":            {Value: "Ludo Go", Emit: prop.EmitConst},
+			"SupportedUriSchemes": {Value: []string{}, Emit: prop.EmitConst},
+			"SupportedMimeTypes":  {Value: []string{}, Emit: prop.EmitConst},
+		},
+		mprisPlayerIface: {
+			"PlaybackStatus": {Value: getMprisPlaybackStatus(snapshot.Status.State), Emit: prop.EmitTrue},
+			"Rate":           {Value: 1.0, Emit: prop.EmitConst},
+			"MinimumRate":    {Value: 1.0, Emit: prop.EmitConst},
+			"MaximumRate":    {Value: 1.0, Emit: prop.EmitConst},
+			"Metadata":       {Value: getMprisCurrentMetadata(), Emit: prop.EmitTrue},
+			"Volume":         {Value: float64(snapshot.Volume) / 100, Writable: true, Emit: prop.EmitTrue, Callback: setMprisVolume},
+			"Position":       {Value: int64(snapshot.Status.Position) * 1e6, Emit: prop.EmitFalse},
+			"CanGoNext":      {Value: false, Emit: prop.EmitTrue},
+			"CanGoPrevious":  {Value: false, Emit: prop.EmitTrue},
+			"CanPlay":        {Value: false, Emit: prop.EmitTrue},
+			"CanPause":       {Value: true, Emit: prop.EmitConst},
+			"CanSeek":        {Value: true, Emit: prop.EmitConst},
+			"CanControl":     {Value: true, Emit: prop.EmitConst},
+		},
+		mprisTrackListIface: {
+			"Tracks":        {Value: []dbus.ObjectPath{}, Emit: prop.EmitInvalidates},
+			"CanEditTracks": {Value: false, Emit: prop.EmitConst},
+		},
+	}
+}
+
+func setMprisVolume(change *prop.Change) *dbus.Error {
+	defer HandlePanic()
+	value, ok := change.Value.(float64)
+	if !ok {
+		return prop.ErrInvalidArg
+	}
+	volume := int(math.Round(value * 100))
+	if volume < 0 {
+		volume = 0
+	} else if volume > 100 {
+		volume = 100
+	}
+	return mprisError(vlcPlayer.SetVol(volume))
+}
+
+// Root methods
+
+func (mprisRoot) Raise() *dbus.Error {
+	return nil
+}
+
+func (mprisRoot) Quit() *dbus.Error {
+	return errMprisNotSupported
+}
+
+// Player methods
+
+func (mprisPlayer) Next() *dbus.Error {
+	defer HandlePanic()
+	return mprisError(vlcPlayer.SkipToNext())
+}
+
+func (mprisPlayer) Previous() *dbus.Error {
+	defer HandlePanic()
+	return mprisError(vlcPlayer.SkipToPrevious())
+}
+
+func (mprisPlayer) Pause() *dbus.Error {
+	defer HandlePanic()
+	if vlcPlayer.FetchPlayerState() != int(vlc.MediaPlaying) {
+		return nil
+	}
+	return mprisError(vlcPlayer.PauseResume())
+}
+
+func (mprisPlayer) PlayPause() *dbus.Error {
+	defer HandlePanic()
+	switch vlcPlayer.FetchPlayerState() {
+	case int(vlc.MediaPlaying), int(vlc.MediaPaused):
+		return mprisError(vlcPlayer.PauseResume())
+	}
+	return mprisError(vlcPlayer.StartPlayback())
+}
+
+func (mprisPlayer) Play() *dbus.Error {
+	defer HandlePanic()
+	switch vlcPlayer.FetchPlayerState() {
+	case int(vlc.MediaPlaying):
+		return nil
+	case int(vlc.MediaPaused):
+		return mprisError(vlcPlayer.PauseResume())
+	}
+	return mprisError(vlcPlayer.StartPlayback())
+}
+
+func (mprisPlayer) Stop() *dbus.Error {
+	defer HandlePanic()
+	return mprisError(vlcPlayer.StopPlayback())
+}
+
+// seeks by the offset in microseconds, exported as Seek
+func (mprisPlayer) SeekBy(offset int64) *dbus.Error {
+	defer HandlePanic()
+	seconds := int(math.Round(float64(offset) / 1e6))
+	if seconds < 0 {
+		return mprisError(vlcPlayer.RewindBySeconds(-seconds))
+	}
+	return mprisError(vlcPlayer.ForwardBySeconds(seconds))
+}
+
+// seeks to the position in microseconds, if the track is still the current one
+func (mprisPlayer) SetPosition(trackId dbus.ObjectPath, position int64) *dbus.Error {
+	defer HandlePanic()
+	if trackId != getMprisCurrentTrackId() || position < 0 {
+		return nil
+	}
+	return mprisError(vlcPlayer.SeekToSeconds(int(position / 1e6)))
+}
+
+func (mprisPlayer) OpenUri(uri string) *dbus.Error {
+	return errMprisNotSupported
+}
+
+// TrackList methods
+
+func (mprisTrackList) GetTracksMetadata(trackIds []dbus.ObjectPath) ([]map[string]dbus.Variant, *dbus.Error) {
+	defer HandlePanic()
+	queue := GetQueueStatus().Queue
+	queueIds := getMprisTrackIds(queue)
+
+	metadataList := make([]map[string]dbus.Variant, 0, len(trackIds))
+	for _, trackId := range trackIds {
+		for i, queueId := range queueIds {
+			if queueId == trackId {
+				metadataList = append(metadataList, getMprisMetadata(queue[i], trackId))
+				break
+			}
+		}
+	}
+	return metadataList, nil
+}
+
+func (mprisTrackList) AddTrack(uri string, afterTrack dbus.ObjectPath, setAsCurrent bool) *dbus.Error {
+	return errMprisNotSupported
+}
+
+func (mprisTrackList) RemoveTrack(trackId dbus.ObjectPath) *dbus.Error {
+	return errMprisNotSupported
+}
+
+func (mprisTrackList) GoTo(trackId dbus.ObjectPath) *dbus.Error {
+	defer HandlePanic()
+	for i, queueId := range getMprisTrackIds(GetQueueStatus().Queue) {
+		if queueId == trackId {
+			return mprisError(vlcPlayer.SkipToIndex(i))
+		}
+	}
+	return nil
+}
+
+// Helpers
+
+func mprisError(err error) *dbus.Error {
+	if err == nil {
+		return nil
+	}
+	return dbus.MakeFailedError(err)
+}
+
+func getMprisPlaybackStatus(state string) string {
+	switch state {
+	case playerStateMap[int(vlc.MediaPlaying)]:
+		return "Playing"
+	case playerStateMap[int(vlc.MediaPaused)]:
+		return "Paused"
+	}
+	return "Stopped"
+}
+
+// returns the track ids of the queue, made from the YtId.
+// Repeated audio get a suffix to keep the ids unique
+func getMprisTrackIds(queue []AudioBasic) []dbus.ObjectPath {
+	trackIds := make([]dbus.ObjectPath, len(queue))
+	seen := make(map[string]int)
+	for i, audio := range queue {
+		trackId := mprisTrackPrefix + escapeObjectPathElem(audio.YtId)
+		if count := seen[audio.YtId]; count > 0 {
+			trackId += fmt.Sprintf("_%d", count)
+		}
+		seen[audio.YtId]++
+		trackIds[i] = dbus.ObjectPath(trackId)
+	}
+	return trackIds
+}
+
+func getMprisCurrentTrackId() dbus.ObjectPath {
+	queue := GetQueueStatus()
+	if queue.Index < 0 || queue.Index >= len(queue.Queue) {
+		return mprisNoTrack
+	}
+	return getMprisTrackIds(queue.Queue)[queue.Index]
+}
+
+func getMprisCurrentMetadata() map[string]dbus.Variant {
+	queue := GetQueueStatus()
+	if queue.Index < 0 || queue.Index >= len(queue.Queue) {
+		return map[string]dbus.Variant{"mpris:trackid": dbus.MakeVariant(mprisNoTrack)}
+	}
+	return getMprisMetadata(queue.Queue[queue.Index], getMprisTrackIds(queue.Queue)[queue.Index])
+}
+
+func getMprisMetadata(audio AudioBasic, trackId dbus.ObjectPath) map[string]dbus.Variant {
+	artist, track := getArtistTrack(audio)
+	return map[string]dbus.Variant{
+		"mpris:trackid": dbus.MakeVariant(trackId),
+		"mpris:length":  dbus.MakeVariant(int64(audio.Duration) * 1e6),
+		"mpris:artUrl":  dbus.MakeVariant(getAudioThumbnailUrl(audio.YtId)),
+		"xesam:title":   dbus.MakeVariant(track),
+		"xesam:artist":  dbus.MakeVariant([]string{artist}),
+		"xesam:url":     dbus.MakeVariant(getAudioSourceUrl(audio.YtId)),
+	}
+}
+
+// escapes the characters not allowed in object paths, ex: a-b_c -> a_2db_5fc
+func escapeObjectPathElem(elem string) string {
+	var escaped strings.Builder
+	for _, c := range []byte(elem) {
+		if (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') {
+			escaped.WriteByte(c)
+		} else {
+			fmt.Fprintf(&escaped, "_%02x", c)
+		}
+	}
+	return escaped.String()
+}
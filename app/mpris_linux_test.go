@@ -0,0 +1,107 @@
+package app
+
+import (
+	"bufio"
+	"errors"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// starts a private session bus for the test, skipped if dbus-daemon is missing
+func startTestSessionBus(t *testing.T) {
+	t.Helper()
+	daemonPath, err := exec.LookPath("dbus-daemon")
+	if err != nil {
+		t.Skip("dbus-daemon not found")
+	}
+
+	cmd := exec.Command(daemonPath, "--session", "--nofork", "--print-address")
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Skipf("dbus-daemon not started: %v", err)
+	}
+	t.Cleanup(func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+	})
+
+	address, err := bufio.NewReader(stdout).ReadString('\n')
+	if err != nil {
+		t.Fatalf("reading bus address: %v", err)
+	}
+	t.Setenv("DBUS_SESSION_BUS_ADDRESS", strings.TrimSpace(address))
+}
+
+func TestMprisServer(t *testing.T) {
+	startTestSessionBus(t)
+	setTestQueue(t, "Artist - Title")
+
+	if err := mpris.start(); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	defer mpris.stop()
+
+	conn, err := dbus.ConnectSessionBus()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	obj := conn.Object(mprisBusName, mprisPath)
+
+	var xml string
+	if err := obj.Call("org.freedesktop.DBus.Introspectable.Introspect", 0).Store(&xml); err != nil {
+		t.Fatalf("introspect: %v", err)
+	}
+	if !strings.Contains(xml, `<method name="Seek">`) || strings.Contains(xml, "SeekBy") {
+		t.Errorf("introspection does not have the Seek method:\n%s", xml)
+	}
+
+	// the player may not be initialised, only the unknown methods are failures
+	for _, call := range []struct {
+		method string
+		args   []interface{}
+	}{
+		{"Seek", []interface{}{int64(5e6)}},
+		{"Seek", []interface{}{int64(-5e6)}},
+		{"SetPosition", []interface{}{mprisNoTrack, int64(0)}},
+	} {
+		err := obj.Call(mprisPlayerIface+"."+call.method, 0, call.args...).Err
+		var dbusErr dbus.Error
+		if errors.As(err, &dbusErr) && dbusErr.Name == "org.freedesktop.DBus.Error.UnknownMethod" {
+			t.Errorf("%s: %v", call.method, err)
+		}
+	}
+
+	err = obj.Call(mprisPlayerIface+".SeekBy", 0, int64(5e6)).Err
+	if err == nil {
+		t.Errorf("SeekBy is exported")
+	}
+	err = obj.Call(mprisPlayerIface+".OpenUri", 0, "https://example.com").Err
+	var dbusErr dbus.Error
+	if !errors.As(err, &dbusErr) || dbusErr.Name != errMprisNotSupported.Name {
+		t.Errorf("OpenUri: %v, want not supported", err)
+	}
+
+	canSeek, err := obj.GetProperty(mprisPlayerIface + ".CanSeek")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if canSeek.Value() != true {
+		t.Errorf("CanSeek = %v, want true", canSeek.Value())
+	}
+
+	metadata, err := obj.GetProperty(mprisPlayerIface + ".Metadata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields, _ := metadata.Value().(map[string]dbus.Variant)
+	if title := fields["xesam:title"].Value(); title != "Title" {
+		t.Errorf("title = %v, want Title", title)
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+
+package app
+
+// mpris is only available on linux
+func startMpris(cfg *appConfig) error {
+	return nil
+}
+
+func stopMpris() {}
@@ -1,110 +1,132 @@
-package app
-
-import (
-	"encoding/json"
-	"errors"
-	"fmt"
-	"log"
-	"net/http"
-
-	"github.com/magiconair/properties"
-)
-
-var Piped PipedConfig
-
-type PipedConfig struct {
-	apiUrl          string
-	instanceListApi string
-	oldApiUrl       string
-	pipedList       []PipedInstance
-}
-
-type PipedInstance struct {
-	Name     string
-	ApiUrl   string
-	ProxyUrl string
-}
-
-func (instance PipedInstance) String() string {
-	return fmt.Sprintf("%-30s| %-50s| %-50s", instance.Name, instance.ApiUrl, instance.ProxyUrl)
-}
-
-func (p *PipedConfig) SetPipedApi(val string) error {
-	p.oldApiUrl = p.apiUrl
-	p.apiUrl = val
-	return nil
-}
-
-func (p *PipedConfig) GetPipedApi() string {
-	return p.apiUrl
-}
-
-func (p *PipedConfig) GetOldPipedApi() string {
-	return p.oldApiUrl
-}
-
-func (p *PipedConfig) GetPipedInstanceList() ([]PipedInstance, error) {
-	if len(p.pipedList) > 0 {
-		log.Println("Instance already loaded")
-		return p.pipedList, nil
-	}
-
-	log.Println("Fetching instance list")
-	resp, err := http.Get(p.instanceListApi)
-	if err != nil {
-		return nil, err
-	}
-
-	log.Println("Resp status: ", resp.Status)
-
-	if resp.StatusCode != 200 {
-		return nil, errors.New("[GetPipedInstanceList] bad response from api")
-	}
-
-	var response interface{}
-
-	err = json.NewDecoder(resp.Body).Decode(&response)
-	if err != nil {
-		return nil, err
-	}
-
-	instList, ok := response.([]interface{})
-	if !ok {
-		return nil, errors.New("response is not of expected format")
-	}
-
-	apiList := make([]PipedInstance, 0)
-	for _, inst := range instList {
-		var pipedInstance PipedInstance
-		if name, ok := getValue(inst, path{"name"}).(string); ok {
-			pipedInstance.Name = name
-		}
-		if url, ok := getValue(inst, path{"api_url"}).(string); ok {
-			pipedInstance.ApiUrl = url
-		}
-		if proxy, ok := getValue(inst, path{"image_proxy_url"}).(string); ok {
-			pipedInstance.ProxyUrl = proxy
-		}
-		apiList = append(apiList, pipedInstance)
-	}
-
-	log.Println("Instance loaded")
-	if len(apiList) > 0 {
-		p.pipedList = apiList
-	}
-
-	return apiList, nil
-}
-
-func setPipedConfig(props properties.Properties) {
-	Piped.apiUrl = props.GetString(pipedApiKey, defaultPipedApi)
-	Piped.instanceListApi = props.GetString(instanceListApiKey, defaultInstanceListApi)
-}
-
-func SetPipedAllFilterType(allow bool) {
-	allowAllMusicFilter = allow
-}
-
-func GetPipedAllFilterType() bool {
-	return allowAllMusicFilter
-}
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+var Piped PipedConfig
+
+// PipedConfig is changed by the config commands while the api is used, mu guards the fields
+type PipedConfig struct {
+	mu              sync.RWMutex
+	apiUrl          string
+	instanceListApi string
+	oldApiUrl       string
+	pipedList       []PipedInstance
+}
+
+type PipedInstance struct {
+	Name     string
+	ApiUrl   string
+	ProxyUrl string
+}
+
+func (instance PipedInstance) String() string {
+	return fmt.Sprintf("%-30s| %-50s| %-50s", instance.Name, instance.ApiUrl, instance.ProxyUrl)
+}
+
+func (p *PipedConfig) SetPipedApi(val string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.oldApiUrl = p.apiUrl
+	p.apiUrl = val
+	return nil
+}
+
+func (p *PipedConfig) GetPipedApi() string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.apiUrl
+}
+
+func (p *PipedConfig) GetOldPipedApi() string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.oldApiUrl
+}
+
+// sets the instance list api, the list loaded from the previous api is dropped
+func (p *PipedConfig) setInstanceListApi(val string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.instanceListApi = val
+	p.pipedList = nil
+}
+
+func (p *PipedConfig) GetPipedInstanceList() ([]PipedInstance, error) {
+	p.mu.RLock()
+	instanceListApi, pipedList := p.instanceListApi, p.pipedList
+	p.mu.RUnlock()
+
+	if len(pipedList) > 0 {
+		pipedLog.Debug("Instance list already loaded")
+		return pipedList, nil
+	}
+
+	pipedLog.Debug("Fetching instance list")
+	resp, err := http.Get(instanceListApi)
+	if err != nil {
+		return nil, wrapErr(ErrSourceUnavailable, err)
+	}
+
+	pipedLog.Debug("Response", "status", resp.Status)
+
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("%w: [GetPipedInstanceList] bad response from api: %s", ErrSourceUnavailable, resp.Status)
+	}
+
+	var response interface{}
+
+	err = json.NewDecoder(resp.Body).Decode(&response)
+	if err != nil {
+		return nil, wrapErr(ErrSourceUnavailable, err)
+	}
+
+	instList, ok := response.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%w: response is not of expected format", ErrSourceUnavailable)
+	}
+
+	apiList := make([]PipedInstance, 0)
+	for _, inst := range instList {
+		var pipedInstance PipedInstance
+		if name, ok := getValue(inst, path{"name"}).(string); ok {
+			pipedInstance.Name = name
+		}
+		if url, ok := getValue(inst, path{"api_url"}).(string); ok {
+			pipedInstance.ApiUrl = url
+		}
+		if proxy, ok := getValue(inst, path{"image_proxy_url"}).(string); ok {
+			pipedInstance.ProxyUrl = proxy
+		}
+		apiList = append(apiList, pipedInstance)
+	}
+
+	pipedLog.Debug("Instance list loaded", "instances", len(apiList))
+	// the list is not kept if the api changed while fetching
+	p.mu.Lock()
+	if len(apiList) > 0 && p.instanceListApi == instanceListApi {
+		p.pipedList = apiList
+	}
+	p.mu.Unlock()
+
+	return apiList, nil
+}
+
+func setPipedConfig(cfg *appConfig) {
+	Piped.mu.Lock()
+	defer Piped.mu.Unlock()
+	Piped.apiUrl = cfg.pipedApi
+	Piped.instanceListApi = cfg.instanceListApi
+}
+
+func SetPipedAllFilterType(allow bool) {
+	allowAllMusicFilter = allow
+}
+
+func GetPipedAllFilterType() bool {
+	return allowAllMusicFilter
+}
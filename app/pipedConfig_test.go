@@ -0,0 +1,58 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+// the instance list of the previous api is dropped when the api changes
+func TestPipedInstanceListApi(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"name": "` + r.URL.Path[1:] + `", "api_url": "https://api.example.com"}]`))
+	}))
+	defer server.Close()
+
+	var p PipedConfig
+	for _, name := range []string{"first", "second"} {
+		p.setInstanceListApi(server.URL + "/" + name)
+		for i := 0; i < 2; i++ {
+			instances, err := p.GetPipedInstanceList()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(instances) != 1 || instances[0].Name != name {
+				t.Errorf("instances = %v, want %s", instances, name)
+			}
+		}
+	}
+}
+
+// the config is changed while the api is used, run with -race
+func TestPipedConfigConcurrent(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"name": "instance"}]`))
+	}))
+	defer server.Close()
+
+	var p PipedConfig
+	p.setInstanceListApi(server.URL)
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			p.SetPipedApi(server.URL)
+			p.setInstanceListApi(server.URL)
+		}()
+		go func() {
+			defer wg.Done()
+			p.GetPipedApi()
+			if _, err := p.GetPipedInstanceList(); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+}
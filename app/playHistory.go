@@ -0,0 +1,173 @@
+package app
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"time"
+)
+
+var historyLog = newLogger("playHistory")
+
+var playHistory playTracker
+
+type PlayStatus string
+
+const (
+	PlayCompleted PlayStatus = "completed"
+	PlaySkipped   PlayStatus = "skipped"
+	PlayErrored   PlayStatus = "errored"
+)
+
+const (
+	historyDateLayout = "2006-01-02"
+	historyRangeSep   = ".."
+	sessionGap        = 30 * time.Minute
+)
+
+// PlayEvent is a single play of a track, stored in the play event log
+type PlayEvent struct {
+	AudioBasic
+	StartTime time.Time
+	EndTime   time.Time
+	Listened  int
+	Status    PlayStatus
+}
+
+// PlaySession groups play events without long gaps in between
+type PlaySession struct {
+	Start    time.Time
+	End      time.Time
+	Listened int
+	Events   []*PlayEvent
+}
+
+// playTracker builds play events from player state transitions
+type playTracker struct {
+	mu           sync.Mutex
+	current      *PlayEvent
+	playingSince time.Time
+}
+
+// starts a new play event, the ongoing one is closed as skipped
+func (tracker *playTracker) startTrack(audio AudioBasic) {
+	tracker.finishTrack(PlaySkipped)
+
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+
+	historyLog.Debug("Track started", "ytId", audio.YtId)
+	tracker.current = &PlayEvent{AudioBasic: audio, StartTime: time.Now()}
+	tracker.playingSince = time.Time{}
+
+	scrobbles.nowPlaying(audio)
+}
+
+func (tracker *playTracker) resume() {
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+
+	if tracker.current != nil && tracker.playingSince.IsZero() {
+		tracker.playingSince = time.Now()
+	}
+}
+
+func (tracker *playTracker) pause() {
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+
+	tracker.addListened()
+}
+
+// closes the ongoing play event with the status and saves it
+func (tracker *playTracker) finishTrack(status PlayStatus) {
+	tracker.mu.Lock()
+	event := tracker.current
+	if event == nil {
+		tracker.mu.Unlock()
+		return
+	}
+	tracker.addListened()
+	event.EndTime = time.Now()
+	event.Status = status
+	tracker.current = nil
+	tracker.mu.Unlock()
+
+	historyLog.Debug("Track finished", "ytId", event.YtId, "status", event.Status, "listened", event.Listened)
+	if err := audioDb.SavePlayEvent(event); err != nil {
+		historyLog.Error("Error in saving play event", "err", err)
+	}
+	scrobbles.queueListen(event)
+}
+
+func (tracker *playTracker) addListened() {
+	if tracker.current == nil || tracker.playingSince.IsZero() {
+		return
+	}
+	tracker.current.Listened += int(time.Since(tracker.playingSince).Seconds())
+	tracker.playingSince = time.Time{}
+}
+
+// parses the history range, which can be empty (all), today, yesterday,
+// week, month, a date (2006-01-02) or a range of dates (from..to).
+// the returned range includes from and excludes to
+func ParseHistoryRange(arg string) (time.Time, time.Time, error) {
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	tomorrow := today.AddDate(0, 0, 1)
+
+	switch arg {
+	case "", "all":
+		return time.Time{}, tomorrow, nil
+	case "today", "day":
+		return today, tomorrow, nil
+	case "yesterday":
+		return today.AddDate(0, 0, -1), today, nil
+	case "week":
+		return today.AddDate(0, 0, -6), tomorrow, nil
+	case "month":
+		return today.AddDate(0, -1, 0), tomorrow, nil
+	}
+
+	fromArg, toArg, isRange := strings.Cut(arg, historyRangeSep)
+	from, err := time.ParseInLocation(historyDateLayout, fromArg, time.Local)
+	if err != nil {
+		return time.Time{}, time.Time{}, errors.New("invalid date, expected " + historyDateLayout)
+	}
+	if !isRange {
+		return from, from.AddDate(0, 0, 1), nil
+	}
+
+	to, err := time.ParseInLocation(historyDateLayout, toArg, time.Local)
+	if err != nil {
+		return time.Time{}, time.Time{}, errors.New("invalid date, expected " + historyDateLayout)
+	}
+	if to.Before(from) {
+		return time.Time{}, time.Time{}, errors.New("invalid range, end date is before start date")
+	}
+	return from, to.AddDate(0, 0, 1), nil
+}
+
+// groups play events, latest first, into sessions
+func GroupPlaySessions(events []*PlayEvent) []PlaySession {
+	sessions := make([]PlaySession, 0)
+
+	for _, event := range events {
+		n := len(sessions)
+		if n > 0 && sessions[n-1].Start.Sub(event.EndTime) < sessionGap {
+			sessions[n-1].Start = event.StartTime
+			sessions[n-1].Listened += event.Listened
+			sessions[n-1].Events = append(sessions[n-1].Events, event)
+			continue
+		}
+
+		sessions = append(sessions, PlaySession{
+			Start:    event.StartTime,
+			End:      event.EndTime,
+			Listened: event.Listened,
+			Events:   []*PlayEvent{event},
+		})
+	}
+
+	return sessions
+}
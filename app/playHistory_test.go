@@ -0,0 +1,210 @@
+package app
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseHistoryRange(t *testing.T) {
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	tomorrow := today.AddDate(0, 0, 1)
+	date := func(value string) time.Time {
+		d, err := time.ParseInLocation(historyDateLayout, value, time.Local)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return d
+	}
+
+	tests := []struct {
+		arg  string
+		from time.Time
+		to   time.Time
+	}{
+		{"", time.Time{}, tomorrow},
+		{"all", time.Time{}, tomorrow},
+		{"today", today, tomorrow},
+		{"day", today, tomorrow},
+		{"yesterday", today.AddDate(0, 0, -1), today},
+		{"week", today.AddDate(0, 0, -6), tomorrow},
+		{"month", today.AddDate(0, -1, 0), tomorrow},
+		{"2024-02-28", date("2024-02-28"), date("2024-02-29")},
+		{"2024-02-28..2024-03-01", date("2024-02-28"), date("2024-03-02")},
+		{"2024-03-01..2024-03-01", date("2024-03-01"), date("2024-03-02")},
+		{"2023-12-31..2024-01-01", date("2023-12-31"), date("2024-01-02")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.arg, func(t *testing.T) {
+			from, to, err := ParseHistoryRange(tt.arg)
+			if err != nil {
+				t.Fatalf("parse: %v", err)
+			}
+			if !from.Equal(tt.from) || !to.Equal(tt.to) {
+				t.Errorf("range = %v..%v, want %v..%v", from, to, tt.from, tt.to)
+			}
+		})
+	}
+}
+
+func TestParseHistoryRangeInvalid(t *testing.T) {
+	tests := []struct {
+		arg string
+		err string
+	}{
+		{"tomorrow", "invalid date, expected 2006-01-02"},
+		{"2024-13-01", "invalid date, expected 2006-01-02"},
+		{"2024-02-30", "invalid date, expected 2006-01-02"},
+		{"01-02-2024", "invalid date, expected 2006-01-02"},
+		{"2024-01-01..", "invalid date, expected 2006-01-02"},
+		{"..2024-01-01", "invalid date, expected 2006-01-02"},
+		{"2024-01-01..week", "invalid date, expected 2006-01-02"},
+		{"2024-03-02..2024-03-01", "invalid range, end date is before start date"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.arg, func(t *testing.T) {
+			_, _, err := ParseHistoryRange(tt.arg)
+			if err == nil || err.Error() != tt.err {
+				t.Errorf("err = %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestGroupPlaySessions(t *testing.T) {
+	base := time.Date(2024, 3, 1, 20, 0, 0, 0, time.Local)
+	// an event of 3 minutes, starting at the minute offset from the base
+	event := func(minute float64, listened int) *PlayEvent {
+		start := base.Add(time.Duration(minute * float64(time.Minute)))
+		return &PlayEvent{StartTime: start, EndTime: start.Add(3 * time.Minute), Listened: listened}
+	}
+
+	tests := []struct {
+		name     string
+		events   []*PlayEvent
+		sessions []int // events per session
+		listened []int
+	}{
+		{"no events", nil, []int{}, []int{}},
+		{"single event", []*PlayEvent{event(0, 100)}, []int{1}, []int{100}},
+		{"back to back", []*PlayEvent{event(6, 180), event(3, 180), event(0, 90)}, []int{3}, []int{450}},
+		{"gap just under", []*PlayEvent{event(32.99, 60), event(0, 60)}, []int{2}, []int{120}},
+		{"gap of 30 minutes", []*PlayEvent{event(33, 60), event(0, 60)}, []int{1, 1}, []int{60, 60}},
+		{"two sessions", []*PlayEvent{event(120, 10), event(117, 20), event(3, 30), event(0, 40)}, []int{2, 2}, []int{30, 70}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sessions := GroupPlaySessions(tt.events)
+			if len(sessions) != len(tt.sessions) {
+				t.Fatalf("sessions = %d, want %d", len(sessions), len(tt.sessions))
+			}
+
+			i := 0
+			for s, session := range sessions {
+				if len(session.Events) != tt.sessions[s] || session.Listened != tt.listened[s] {
+					t.Errorf("session %d: events = %d listened = %d, want %d and %d", s, len(session.Events), session.Listened, tt.sessions[s], tt.listened[s])
+				}
+				// latest first, spanning from the first start to the last end
+				first, last := tt.events[i+tt.sessions[s]-1], tt.events[i]
+				if !session.Start.Equal(first.StartTime) || !session.End.Equal(last.EndTime) {
+					t.Errorf("session %d: %v..%v, want %v..%v", s, session.Start, session.End, first.StartTime, last.EndTime)
+				}
+				i += tt.sessions[s]
+			}
+		})
+	}
+}
+
+// the listened time counts only the playing spans between resume and pause
+func TestPlayTrackerListened(t *testing.T) {
+	setTestAudioDb(t)
+	var tracker playTracker
+	// moves the start of the playing span back, as if playing for the seconds
+	playFor := func(seconds int) {
+		tracker.resume()
+		tracker.mu.Lock()
+		tracker.playingSince = tracker.playingSince.Add(-time.Duration(seconds) * time.Second)
+		tracker.mu.Unlock()
+	}
+
+	// no play event before a track starts
+	tracker.resume()
+	tracker.pause()
+	tracker.finishTrack(PlayCompleted)
+
+	tracker.startTrack(testAudio(1))
+	playFor(10)
+	tracker.pause()
+	tracker.pause()
+	playFor(20)
+	tracker.resume()
+	tracker.finishTrack(PlayCompleted)
+
+	// a started track is closed as skipped by the next one, a paused track adds nothing more
+	tracker.startTrack(testAudio(2))
+	playFor(5)
+	tracker.pause()
+	tracker.startTrack(testAudio(3))
+	tracker.finishTrack(PlayErrored)
+
+	events, err := audioDb.GetPlayEvents(time.Time{}, time.Now().Add(time.Minute), 0, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]struct {
+		listened int
+		status   PlayStatus
+	}{
+		testAudio(1).YtId: {30, PlayCompleted},
+		testAudio(2).YtId: {5, PlaySkipped},
+		testAudio(3).YtId: {0, PlayErrored},
+	}
+	if len(events) != len(want) {
+		t.Fatalf("events = %d, want %d", len(events), len(want))
+	}
+	for _, event := range events {
+		if w := want[event.YtId]; event.Listened != w.listened || event.Status != w.status {
+			t.Errorf("%s: listened = %d status = %s, want %d and %s", event.YtId, event.Listened, event.Status, w.listened, w.status)
+		}
+	}
+}
+
+// both days of a range are included, and all includes every event
+func TestGetPlayEventsRange(t *testing.T) {
+	setTestAudioDb(t)
+	for i, start := range []string{"2024-02-29 23:59", "2024-03-01 00:00", "2024-03-02 23:59", "2024-03-03 00:00"} {
+		startTime, err := time.ParseInLocation("2006-01-02 15:04", start, time.Local)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := audioDb.SavePlayEvent(&PlayEvent{AudioBasic: testAudio(i), StartTime: startTime, EndTime: startTime}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		arg    string
+		events int
+	}{
+		{"all", 4},
+		{"2024-03-01..2024-03-02", 2},
+		{"2024-03-01", 1},
+		{"2024-02-29..2024-03-03", 4},
+	}
+	for _, tt := range tests {
+		from, to, err := ParseHistoryRange(tt.arg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		events, err := audioDb.GetPlayEvents(from, to, 0, 100)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(events) != tt.events {
+			t.Errorf("%s: events = %d, want %d", tt.arg, len(events), tt.events)
+		}
+	}
+}
@@ -0,0 +1,210 @@
+package app
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+)
+
+type TrackStat struct {
+	AudioBasic
+	Plays    int
+	Listened int
+}
+
+type UploaderStat struct {
+	Uploader string
+	Plays    int
+	Listened int
+}
+
+// ListeningStats is a report built from the play events of a period
+type ListeningStats struct {
+	Period         string
+	From           time.Time
+	To             time.Time
+	TotalPlays     int
+	TotalListened  int
+	TopTracks      []TrackStat
+	TopUploaders   []UploaderStat
+	HourlyListened [24]int
+	CurrentStreak  int
+	LongestStreak  int
+}
+
+// builds the listening stats for the period (day, week, month, all),
+// top lists are trimmed to limit entries
+func GetListeningStats(period string, limit int) (*ListeningStats, error) {
+	from, to, err := ParseHistoryRange(period)
+	if err != nil {
+		return nil, err
+	}
+
+	events, err := audioDb.GetPlayEvents(from, to, 0, -1)
+	if err != nil {
+		return nil, err
+	}
+
+	if period == "" {
+		period = "all"
+	}
+	stats := &ListeningStats{Period: period, From: from, To: to}
+
+	trackMap := make(map[string]*TrackStat)
+	uploaderMap := make(map[string]*UploaderStat)
+	for _, event := range events {
+		stats.TotalPlays++
+		stats.TotalListened += event.Listened
+		stats.HourlyListened[event.StartTime.Hour()] += event.Listened
+
+		track, ok := trackMap[event.YtId]
+		if !ok {
+			track = &TrackStat{AudioBasic: event.AudioBasic}
+			trackMap[event.YtId] = track
+		}
+		track.Plays++
+		track.Listened += event.Listened
+
+		uploader, ok := uploaderMap[event.Uploader]
+		if !ok {
+			uploader = &UploaderStat{Uploader: event.Uploader}
+			uploaderMap[event.Uploader] = uploader
+		}
+		uploader.Plays++
+		uploader.Listened += event.Listened
+	}
+
+	for _, track := range trackMap {
+		stats.TopTracks = append(stats.TopTracks, *track)
+	}
+	sort.Slice(stats.TopTracks, func(i, j int) bool {
+		a, b := stats.TopTracks[i], stats.TopTracks[j]
+		if a.Plays != b.Plays {
+			return a.Plays > b.Plays
+		}
+		return a.Listened > b.Listened
+	})
+	stats.TopTracks = trimList(stats.TopTracks, 0, limit)
+
+	for _, uploader := range uploaderMap {
+		stats.TopUploaders = append(stats.TopUploaders, *uploader)
+	}
+	sort.Slice(stats.TopUploaders, func(i, j int) bool {
+		a, b := stats.TopUploaders[i], stats.TopUploaders[j]
+		if a.Plays != b.Plays {
+			return a.Plays > b.Plays
+		}
+		return a.Listened > b.Listened
+	})
+	stats.TopUploaders = trimList(stats.TopUploaders, 0, limit)
+
+	stats.CurrentStreak, stats.LongestStreak = getListeningStreaks(events)
+
+	return stats, nil
+}
+
+// returns the current and longest count of consecutive days with listening,
+// events are expected latest first
+func getListeningStreaks(events []*PlayEvent) (int, int) {
+	days := make([]time.Time, 0)
+	for _, event := range events {
+		t := event.StartTime.Local()
+		day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.Local)
+		if n := len(days); n == 0 || !days[n-1].Equal(day) {
+			days = append(days, day)
+		}
+	}
+	if len(days) == 0 {
+		return 0, 0
+	}
+
+	longest, streak := 1, 1
+	current := 0
+	for i := 1; i < len(days); i++ {
+		if days[i].AddDate(0, 0, 1).Equal(days[i-1]) {
+			streak++
+		} else {
+			if current == 0 {
+				current = streak
+			}
+			streak = 1
+		}
+		if streak > longest {
+			longest = streak
+		}
+	}
+	if current == 0 {
+		current = streak
+	}
+
+	// current streak counts only if listened today or yesterday
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	if days[0].Before(today.AddDate(0, 0, -1)) {
+		current = 0
+	}
+
+	return current, longest
+}
+
+// writes the stats to the file in the given format (json, csv)
+func (stats *ListeningStats) ExportFile(format string, filePath string) error {
+	var export func(io.Writer) error
+	switch format {
+	case "json":
+		export = stats.ExportJSON
+	case "csv":
+		export = stats.ExportCSV
+	default:
+		return errors.New("invalid export format (json, csv)")
+	}
+
+	file, err := os.Create(filePath)
+	if err != nil {
+		return err
+	}
+
+	if err := export(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func (stats *ListeningStats) ExportJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(stats)
+}
+
+// writes the stats as csv rows of: section, key, name, plays, seconds
+func (stats *ListeningStats) ExportCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	itoa := strconv.Itoa
+
+	rows := [][]string{
+		{"section", "key", "name", "plays", "seconds"},
+		{"summary", "total", stats.Period, itoa(stats.TotalPlays), itoa(stats.TotalListened)},
+		{"summary", "currentStreak", "days", itoa(stats.CurrentStreak), ""},
+		{"summary", "longestStreak", "days", itoa(stats.LongestStreak), ""},
+	}
+	for _, track := range stats.TopTracks {
+		rows = append(rows, []string{"track", track.YtId, track.Title + " - " + track.Uploader, itoa(track.Plays), itoa(track.Listened)})
+	}
+	for _, uploader := range stats.TopUploaders {
+		rows = append(rows, []string{"uploader", uploader.Uploader, uploader.Uploader, itoa(uploader.Plays), itoa(uploader.Listened)})
+	}
+	for hour, listened := range stats.HourlyListened {
+		rows = append(rows, []string{"hour", itoa(hour), "", "", itoa(listened)})
+	}
+
+	if err := writer.WriteAll(rows); err != nil {
+		return err
+	}
+	return writer.Error()
+}
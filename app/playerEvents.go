@@ -0,0 +1,238 @@
+package app
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	vlc "github.com/adrg/libvlc-go/v3"
+)
+
+var playerEventLog = newLogger("playerEvents")
+
+var playerEvents playerEventHub
+
+const (
+	playerEventPollInterval = 250 * time.Millisecond
+	playerEventPosInterval  = time.Second
+	playerEventHistorySize  = 256
+	playerEventBufferSize   = 64
+	playerEventSeekDelta    = 2 // seconds
+)
+
+// player event types
+const (
+	SnapshotEvent = "snapshot"
+	TrackEvent    = "track"
+	StateEvent    = "state"
+	PositionEvent = "position"
+	QueueEvent    = "queue"
+	VolumeEvent   = "volume"
+)
+
+// PlayerEvent is a change of the player state pushed to subscribers
+type PlayerEvent struct {
+	Id   int64       `json:"id"`
+	Type string      `json:"type"`
+	Time time.Time   `json:"time"`
+	Data interface{} `json:"data"`
+}
+
+// PositionChange is the data of a position event, Seek is set when the position jumped
+type PositionChange struct {
+	Position int  `json:"position"`
+	Length   int  `json:"length"`
+	Seek     bool `json:"seek"`
+}
+
+type PlayerSnapshot struct {
+	Status PlayerStatus `json:"status"`
+	Queue  QueueStatus  `json:"queue"`
+	Volume int          `json:"volume"`
+}
+
+// playerEventHub watches the player while there are subscribers and publishes its changes.
+// Recent events are kept so that subscribers can resume from the last event they got.
+// Changes while nobody is subscribed are not recorded, so resuming from before
+// the last start of the watcher gets a snapshot
+type playerEventHub struct {
+	mu          sync.Mutex
+	lastId      int64
+	restartId   int64 // id of the last start of the watcher
+	history     []PlayerEvent
+	subscribers map[chan PlayerEvent]struct{}
+	stopSig     chan struct{}
+}
+
+// player state compared by the watcher to find changes
+type playerWatchState struct {
+	ytId       string
+	queueIndex int
+	state      string
+	queueKey   string
+	volume     int
+	position   int
+	posTime    time.Time
+}
+
+// subscribes to the events after lastEventId. If resume is false or the events are
+// no longer kept, the returned snapshot is to be sent instead of the missed events
+func (hub *playerEventHub) subscribe(lastEventId int64, resume bool) (chan PlayerEvent, []PlayerEvent, *PlayerEvent) {
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+
+	ch := make(chan PlayerEvent, playerEventBufferSize)
+	if hub.subscribers == nil {
+		hub.subscribers = make(map[chan PlayerEvent]struct{})
+	}
+	hub.subscribers[ch] = struct{}{}
+	if hub.stopSig == nil {
+		// the events before are stale, the start gets an id of its own
+		hub.lastId++
+		hub.restartId = hub.lastId
+		hub.history = nil
+		hub.stopSig = make(chan struct{})
+		go hub.watch(hub.stopSig)
+	}
+
+	oldestId := hub.lastId + 1
+	if len(hub.history) > 0 {
+		oldestId = hub.history[0].Id
+	}
+	if resume && lastEventId >= hub.restartId && lastEventId >= oldestId-1 && lastEventId <= hub.lastId {
+		replay := make([]PlayerEvent, 0)
+		for _, event := range hub.history {
+			if event.Id > lastEventId {
+				replay = append(replay, event)
+			}
+		}
+		return ch, replay, nil
+	}
+
+	snapshot := &PlayerEvent{Id: hub.lastId, Type: SnapshotEvent, Time: time.Now(), Data: getPlayerSnapshot()}
+	return ch, nil, snapshot
+}
+
+func (hub *playerEventHub) unsubscribe(ch chan PlayerEvent) {
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+
+	if _, ok := hub.subscribers[ch]; ok {
+		delete(hub.subscribers, ch)
+		close(ch)
+	}
+
+	if len(hub.subscribers) == 0 && hub.stopSig != nil {
+		close(hub.stopSig)
+		hub.stopSig = nil
+	}
+}
+
+// closes all subscriptions, ex: on shutdown of the server
+func (hub *playerEventHub) closeAll() {
+	hub.mu.Lock()
+	subscribers := make([]chan PlayerEvent, 0, len(hub.subscribers))
+	for ch := range hub.subscribers {
+		subscribers = append(subscribers, ch)
+	}
+	hub.mu.Unlock()
+
+	for _, ch := range subscribers {
+		hub.unsubscribe(ch)
+	}
+}
+
+// sends the event to all subscribers, slow subscribers are dropped
+// and can resume from their last event
+func (hub *playerEventHub) publish(eventType string, data interface{}) {
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+
+	hub.lastId++
+	event := PlayerEvent{Id: hub.lastId, Type: eventType, Time: time.Now(), Data: data}
+
+	hub.history = append(hub.history, event)
+	if len(hub.history) > playerEventHistorySize {
+		hub.history = hub.history[len(hub.history)-playerEventHistorySize:]
+	}
+
+	for ch := range hub.subscribers {
+		select {
+		case ch <- event:
+		default:
+			playerEventLog.Warn("Dropping slow subscriber")
+			delete(hub.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// polls the player and publishes the changes until stopped
+func (hub *playerEventHub) watch(stopSig chan struct{}) {
+	defer HandlePanic()
+	ticker := time.NewTicker(playerEventPollInterval)
+	defer ticker.Stop()
+
+	prev := getPlayerWatchState(GetPlayerStatus())
+	for {
+		select {
+		case <-stopSig:
+			return
+		case <-ticker.C:
+		}
+
+		status := GetPlayerStatus()
+		curr := getPlayerWatchState(status)
+
+		if curr.ytId != prev.ytId || curr.queueIndex != prev.queueIndex {
+			hub.publish(TrackEvent, status)
+		}
+		if curr.state != prev.state {
+			hub.publish(StateEvent, map[string]string{"state": curr.state})
+		}
+		if curr.queueKey != prev.queueKey || curr.queueIndex != prev.queueIndex {
+			hub.publish(QueueEvent, GetQueueStatus())
+		}
+		if curr.volume != prev.volume {
+			hub.publish(VolumeEvent, map[string]int{"volume": curr.volume})
+		}
+
+		// position is sent once per interval while playing, and at once on a seek.
+		// prev keeps the last sent position while playing
+		isPlaying := curr.state == playerStateMap[int(vlc.MediaPlaying)]
+		posChanged := curr.position != prev.position
+		elapsed := curr.posTime.Sub(prev.posTime)
+		expected := prev.position + int(elapsed.Seconds())
+		isSeek := posChanged && (!isPlaying || curr.position < prev.position || curr.position-expected > playerEventSeekDelta)
+
+		if isSeek || (isPlaying && posChanged && elapsed >= playerEventPosInterval) {
+			hub.publish(PositionEvent, PositionChange{Position: curr.position, Length: status.Length, Seek: isSeek})
+		} else if isPlaying {
+			curr.position, curr.posTime = prev.position, prev.posTime
+		}
+
+		prev = curr
+	}
+}
+
+func getPlayerWatchState(status PlayerStatus) playerWatchState {
+	queue := vlcPlayer.GetQueue()
+	uids := make([]string, len(queue))
+	for i, audio := range queue {
+		uids[i] = audio.uid
+	}
+
+	return playerWatchState{
+		ytId:       status.Audio.YtId,
+		queueIndex: status.QueueIndex,
+		state:      status.State,
+		queueKey:   strings.Join(uids, ","),
+		volume:     vlcPlayer.GetVol(),
+		position:   status.Position,
+		posTime:    time.Now(),
+	}
+}
+
+func getPlayerSnapshot() PlayerSnapshot {
+	return PlayerSnapshot{Status: GetPlayerStatus(), Queue: GetQueueStatus(), Volume: vlcPlayer.GetVol()}
+}
@@ -0,0 +1,271 @@
+package app
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"time"
+)
+
+var scrobbleLog = newLogger("scrobbler")
+
+var scrobbles scrobbleService
+
+const (
+	scrobbleMinDuration      = 30  // seconds
+	scrobbleMaxThreshold     = 240 // seconds
+	scrobbleRetryInterval    = time.Minute
+	scrobbleMaxRetryInterval = 30 * time.Minute
+)
+
+// Scrobble is a listen waiting in the queue to be submitted to a service
+type Scrobble struct {
+	AudioBasic
+	Service    string
+	ListenedAt time.Time
+	Attempts   int
+	id         string
+}
+
+// scrobbler submits listens to a scrobbling service
+type scrobbler interface {
+	Name() string
+	BatchSize() int
+	NowPlaying(audio AudioBasic) error
+	Submit(batch []*Scrobble) error
+}
+
+// scrobbleError is returned by scrobblers for rejected submissions,
+// which are dropped from the queue instead of being retried
+type scrobbleError struct {
+	err error
+}
+
+func (e scrobbleError) Error() string {
+	return e.err.Error()
+}
+
+func (e scrobbleError) Unwrap() error {
+	return e.err
+}
+
+type ScrobbleStatus struct {
+	Service string
+	Pending int
+}
+
+// scrobbleService queues listens for all scrobblers and submits them in the background
+type scrobbleService struct {
+	mu         sync.Mutex
+	scrobblers []scrobbler
+	flushSig   chan struct{}
+	stopSig    chan struct{}
+	done       chan struct{}
+}
+
+// starts the scrobblers having credentials in the config
+func setScrobbleConfig(cfg *appConfig) {
+	scrobblerList := make([]scrobbler, 0)
+
+	if cfg.listenBrainzToken != "" {
+		scrobblerList = append(scrobblerList, newListenBrainzScrobbler(cfg.listenBrainzApiRoot, cfg.listenBrainzToken))
+	}
+
+	if cfg.lastFm.apiKey != "" {
+		scrobblerList = append(scrobblerList, newLastFmScrobbler(cfg.lastFm))
+	}
+
+	scrobbles.start(scrobblerList...)
+}
+
+func (service *scrobbleService) start(scrobblers ...scrobbler) {
+	service.mu.Lock()
+	defer service.mu.Unlock()
+
+	if len(scrobblers) == 0 || service.stopSig != nil {
+		return
+	}
+
+	service.scrobblers = scrobblers
+	service.flushSig = make(chan struct{}, 1)
+	service.stopSig = make(chan struct{})
+	service.done = make(chan struct{})
+
+	go service.flushLoop()
+	service.flush()
+}
+
+func (service *scrobbleService) stop() {
+	service.mu.Lock()
+	defer service.mu.Unlock()
+
+	if service.stopSig == nil {
+		return
+	}
+	close(service.stopSig)
+	<-service.done
+
+	service.scrobblers = nil
+	service.stopSig = nil
+}
+
+func (service *scrobbleService) getScrobblers() []scrobbler {
+	service.mu.Lock()
+	defer service.mu.Unlock()
+	return service.scrobblers
+}
+
+// sends playing now to all scrobblers, failures are not retried
+func (service *scrobbleService) nowPlaying(audio AudioBasic) {
+	for _, s := range service.getScrobblers() {
+		go func(s scrobbler) {
+			defer HandlePanic()
+			if err := s.NowPlaying(audio); err != nil {
+				scrobbleLog.Warn("Playing now failed", "scrobbler", s.Name(), "err", err)
+			}
+		}(s)
+	}
+}
+
+// queues the play event as a listen for all scrobblers, if listened long enough
+func (service *scrobbleService) queueListen(event *PlayEvent) {
+	scrobblers := service.getScrobblers()
+	if len(scrobblers) == 0 || !isScrobbleable(event) {
+		return
+	}
+
+	for _, s := range scrobblers {
+		scrobble := &Scrobble{AudioBasic: event.AudioBasic, Service: s.Name(), ListenedAt: event.StartTime}
+		if err := audioDb.SaveScrobble(scrobble); err != nil {
+			scrobbleLog.Error("Error in queueing listen", "scrobbler", s.Name(), "err", err)
+		}
+	}
+	service.flush()
+}
+
+// requests a submission of the queued listens
+func (service *scrobbleService) flush() {
+	select {
+	case service.flushSig <- struct{}{}:
+	default:
+	}
+}
+
+// returns the number of queued listens of each scrobbler
+func (service *scrobbleService) status() ([]ScrobbleStatus, error) {
+	statusList := make([]ScrobbleStatus, 0)
+	for _, s := range service.getScrobblers() {
+		pending, err := audioDb.CountScrobbles(s.Name())
+		if err != nil {
+			return nil, err
+		}
+		statusList = append(statusList, ScrobbleStatus{Service: s.Name(), Pending: pending})
+	}
+	return statusList, nil
+}
+
+// submits the queue on request, retrying failures with a growing interval
+func (service *scrobbleService) flushLoop() {
+	defer HandlePanic()
+	defer close(service.done)
+
+	interval := scrobbleRetryInterval
+	timer := time.NewTimer(interval)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-service.stopSig:
+			return
+		case <-service.flushSig:
+		case <-timer.C:
+		}
+
+		failed := false
+		for _, s := range service.scrobblers {
+			if err := service.submitQueue(s); err != nil {
+				scrobbleLog.Warn("Submission failed", "scrobbler", s.Name(), "err", err)
+				failed = true
+			}
+		}
+
+		if failed {
+			interval *= 2
+			if interval > scrobbleMaxRetryInterval {
+				interval = scrobbleMaxRetryInterval
+			}
+		} else {
+			interval = scrobbleRetryInterval
+		}
+
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(interval)
+	}
+}
+
+// submits the queued listens of the scrobbler in batches, oldest first
+func (service *scrobbleService) submitQueue(s scrobbler) error {
+	for {
+		select {
+		case <-service.stopSig:
+			return nil
+		default:
+		}
+
+		batch, err := audioDb.GetScrobbles(s.Name(), s.BatchSize())
+		if err != nil || len(batch) == 0 {
+			return err
+		}
+
+		err = s.Submit(batch)
+		var rejected scrobbleError
+		if errors.As(err, &rejected) {
+			scrobbleLog.Warn("Dropping rejected listens", "scrobbler", s.Name(), "err", err)
+		} else if err != nil {
+			if err := audioDb.IncrementScrobbleAttempts(batch); err != nil {
+				scrobbleLog.Error("Error in counting attempts", "scrobbler", s.Name(), "err", err)
+			}
+			return err
+		}
+
+		if err := audioDb.DeleteScrobbles(batch); err != nil {
+			return err
+		}
+		scrobbleLog.Info("Submitted listens", "scrobbler", s.Name(), "count", len(batch))
+	}
+}
+
+// a listen counts after half the duration or 4 minutes, whichever is earlier
+func isScrobbleable(event *PlayEvent) bool {
+	if event.Duration > 0 && event.Duration < scrobbleMinDuration {
+		return false
+	}
+
+	threshold := scrobbleMaxThreshold
+	if event.Duration > 0 && event.Duration/2 < threshold {
+		threshold = event.Duration / 2
+	}
+	return event.Listened >= threshold
+}
+
+// splits the audio into artist and track name. Topic channels are named after the artist,
+// ex: Daft Punk - Topic, other uploads are split by the title, ex: Daft Punk - Get Lucky
+func getArtistTrack(audio AudioBasic) (string, string) {
+	uploader := strings.TrimSpace(audio.Uploader)
+	if strings.HasSuffix(uploader, " - Topic") {
+		return strings.TrimSpace(strings.TrimSuffix(uploader, " - Topic")), audio.Title
+	}
+
+	if artist, track, ok := strings.Cut(audio.Title, " - "); ok {
+		artist, track = strings.TrimSpace(artist), strings.TrimSpace(track)
+		if artist != "" && track != "" {
+			return artist, track
+		}
+	}
+	return uploader, audio.Title
+}
@@ -0,0 +1,263 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/ostafen/clover/v2/query"
+)
+
+// SmartPlaylist is a saved query over the audio docs,
+// ex: tag=chill AND PlayCount>3, LastPlay older than 30d, uploader~Daft Punk
+type SmartPlaylist struct {
+	Name  string
+	Query string
+}
+
+type smartFieldType uint8
+
+const (
+	stringField smartFieldType = iota
+	numberField
+	timeField
+	tagField
+)
+
+type smartField struct {
+	name      string
+	fieldType smartFieldType
+}
+
+var smartFieldMap = map[string]smartField{
+	"tag":       {"Tags", tagField},
+	"tags":      {"Tags", tagField},
+	"id":        {"YtId", stringField},
+	"ytid":      {"YtId", stringField},
+	"title":     {"Title", stringField},
+	"uploader":  {"Uploader", stringField},
+	"artist":    {"Uploader", stringField},
+	"duration":  {"Duration", numberField},
+	"playcount": {"PlayCount", numberField},
+	"plays":     {"PlayCount", numberField},
+	"like":      {"Like", numberField},
+	"likes":     {"Like", numberField},
+	"rating":    {"Rating", numberField},
+	"lastplay":  {"LastPlay", timeField},
+}
+
+var (
+	smartAgeRegex      = regexp.MustCompile(`(?i)^(\w+)\s+(older|newer)\s+than\s+(\d+)([hdwmy])$`)
+	smartCompareRegex  = regexp.MustCompile(`^(\w+)\s*(>=|<=|!=|=|>|<|~)\s*(.+)$`)
+	smartDateLayout    = "2006-01-02"
+	smartAgeUnitDurMap = map[string]time.Duration{
+		"h": time.Hour,
+		"d": 24 * time.Hour,
+		"w": 7 * 24 * time.Hour,
+		"m": 30 * 24 * time.Hour,
+		"y": 365 * 24 * time.Hour,
+	}
+)
+
+// parses the smart playlist query into clover criteria,
+// terms are joined by upper case AND/OR, where AND binds tighter than OR.
+// Values with AND/OR in them can be quoted, ex: title="Rock AND Roll"
+func ParseSmartQuery(smartQuery string) (query.Criteria, error) {
+	smartQuery = strings.TrimSpace(smartQuery)
+	if smartQuery == "" {
+		return nil, errors.New("empty smart playlist query")
+	}
+
+	terms, joins, err := splitSmartQuery(smartQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	var orCrit, andCrit query.Criteria
+	for i, term := range terms {
+		termCrit, err := parseSmartTerm(term)
+		if err != nil {
+			return nil, err
+		}
+
+		if andCrit == nil {
+			andCrit = termCrit
+		} else {
+			andCrit = andCrit.And(termCrit)
+		}
+
+		// close the AND group on OR or at the end
+		if i == len(joins) || joins[i] == "OR" {
+			if orCrit == nil {
+				orCrit = andCrit
+			} else {
+				orCrit = orCrit.Or(andCrit)
+			}
+			andCrit = nil
+		}
+	}
+
+	return orCrit, nil
+}
+
+// splits the query into the terms and the AND/OR joins between them,
+// the words in quotes are never joins
+func splitSmartQuery(smartQuery string) ([]string, []string, error) {
+	var terms, joins []string
+	var quote rune
+	termStart, termEnd, wordStart := -1, -1, -1
+
+	endWord := func(end int) error {
+		word := smartQuery[wordStart:end]
+		wordStart = -1
+		if word != "AND" && word != "OR" {
+			if termStart < 0 {
+				termStart = end - len(word)
+			}
+			termEnd = end
+			return nil
+		}
+
+		if termStart < 0 {
+			return fmt.Errorf("missing term before '%s'", word)
+		}
+		terms, joins = append(terms, smartQuery[termStart:termEnd]), append(joins, word)
+		termStart = -1
+		return nil
+	}
+
+	for i, r := range smartQuery {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case unicode.IsSpace(r):
+			if wordStart >= 0 {
+				if err := endWord(i); err != nil {
+					return nil, nil, err
+				}
+			}
+			continue
+		case r == '"' || r == '\'':
+			quote = r
+		}
+		if wordStart < 0 {
+			wordStart = i
+		}
+	}
+
+	if quote != 0 {
+		return nil, nil, fmt.Errorf("unterminated %c quote", quote)
+	}
+	if wordStart >= 0 {
+		if err := endWord(len(smartQuery)); err != nil {
+			return nil, nil, err
+		}
+	}
+	if termStart < 0 {
+		return nil, nil, fmt.Errorf("missing term after '%s'", joins[len(joins)-1])
+	}
+	return append(terms, smartQuery[termStart:termEnd]), joins, nil
+}
+
+func parseSmartTerm(term string) (query.Criteria, error) {
+	term = strings.TrimSpace(term)
+
+	if match := smartAgeRegex.FindStringSubmatch(term); match != nil {
+		field, err := getSmartField(match[1])
+		if err != nil {
+			return nil, err
+		}
+		if field.fieldType != timeField {
+			return nil, fmt.Errorf("'%s' is not a time field", match[1])
+		}
+
+		count, _ := strconv.Atoi(match[3])
+		limit := time.Now().Add(-time.Duration(count) * smartAgeUnitDurMap[strings.ToLower(match[4])])
+		if strings.EqualFold(match[2], "older") {
+			return query.Field(field.name).Lt(limit), nil
+		}
+		return query.Field(field.name).Gt(limit), nil
+	}
+
+	match := smartCompareRegex.FindStringSubmatch(term)
+	if match == nil {
+		return nil, fmt.Errorf("invalid term '%s'", term)
+	}
+
+	field, err := getSmartField(match[1])
+	if err != nil {
+		return nil, err
+	}
+	op, value := match[2], strings.Trim(strings.TrimSpace(match[3]), `"'`)
+
+	switch field.fieldType {
+	case tagField:
+		tagCrit := query.Field(field.name).Contains(normalizeTag(value))
+		switch op {
+		case "=":
+			return tagCrit, nil
+		case "!=":
+			return tagCrit.Not(), nil
+		}
+	case stringField:
+		switch op {
+		case "=":
+			return query.Field(field.name).Eq(value), nil
+		case "!=":
+			return query.Field(field.name).Neq(value), nil
+		case "~":
+			return query.Field(field.name).Like("(?i)" + regexp.QuoteMeta(value)), nil
+		}
+	case numberField:
+		num, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("'%s' is not a number in term '%s'", value, term)
+		}
+		return compareCriteria(field.name, op, num)
+	case timeField:
+		date, err := time.ParseInLocation(smartDateLayout, value, time.Local)
+		if err != nil {
+			return nil, fmt.Errorf("'%s' is not a date (%s) in term '%s'", value, smartDateLayout, term)
+		}
+		return compareCriteria(field.name, op, date)
+	}
+
+	return nil, fmt.Errorf("operator '%s' is not supported for '%s'", op, match[1])
+}
+
+func compareCriteria(fieldName string, op string, value interface{}) (query.Criteria, error) {
+	field := query.Field(fieldName)
+	switch op {
+	case "=":
+		return field.Eq(value), nil
+	case "!=":
+		return field.Neq(value), nil
+	case ">":
+		return field.Gt(value), nil
+	case ">=":
+		return field.GtEq(value), nil
+	case "<":
+		return field.Lt(value), nil
+	case "<=":
+		return field.LtEq(value), nil
+	}
+	return nil, fmt.Errorf("operator '%s' is not supported for '%s'", op, fieldName)
+}
+
+func getSmartField(name string) (smartField, error) {
+	field, ok := smartFieldMap[strings.ToLower(name)]
+	if !ok {
+		return smartField{}, fmt.Errorf("unknown field '%s'", name)
+	}
+	return field, nil
+}
+
+func normalizeTag(tag string) string {
+	return strings.ToLower(strings.TrimSpace(tag))
+}
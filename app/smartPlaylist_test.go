@@ -0,0 +1,77 @@
+package app
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseSmartQuery(t *testing.T) {
+	chillDoc := audioDoc{AudioBasic: AudioBasic{YtId: "chill000001", Title: "Rock and Roll", Uploader: "Daft Punk"}, Tags: []string{"chill"}, PlayCount: 1, Rating: 2, LastPlay: time.Now()}
+	playedDoc := audioDoc{AudioBasic: AudioBasic{YtId: "played00001", Title: "Rock AND Roll", Uploader: "Justice"}, Tags: []string{}, PlayCount: 5, LastPlay: time.Now().Add(-60 * 24 * time.Hour)}
+	ratedDoc := audioDoc{AudioBasic: AudioBasic{YtId: "rated000001", Title: "Digital Love", Uploader: "Daft Punk"}, Tags: []string{"chill"}, PlayCount: 1, Rating: 5, LastPlay: time.Now()}
+	docs := []audioDoc{chillDoc, playedDoc, ratedDoc}
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"tag=chill", []string{"chill000001", "rated000001"}},
+		{"tag=chill AND rating>=4", []string{"rated000001"}},
+		{"tag=chill OR plays>3", []string{"chill000001", "played00001", "rated000001"}},
+		// AND binds tighter than OR: plays>3 OR (tag=chill AND rating>=4)
+		{"plays>3 OR tag=chill AND rating>=4", []string{"played00001", "rated000001"}},
+		{"tag=chill AND rating>=4 OR plays>3", []string{"played00001", "rated000001"}},
+		{"rating>=4 AND tag=chill OR uploader=Justice AND plays>3", []string{"played00001", "rated000001"}},
+		// quoted values are not split on AND/OR
+		{`title="Rock and Roll"`, []string{"chill000001"}},
+		{`title="Rock AND Roll"`, []string{"played00001"}},
+		{`title~'rock AND roll' OR title~"digital OR love"`, []string{"chill000001", "played00001"}},
+		{`uploader="Daft Punk" AND title~"AND"`, []string{"chill000001"}},
+		// lower case and/or are part of the value
+		{"title~rock and roll", []string{"chill000001", "played00001"}},
+		{"lastplay older than 30d", []string{"played00001"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			crit, err := ParseSmartQuery(tt.query)
+			if tt.want == nil {
+				if err == nil {
+					t.Fatalf("no error for an invalid query")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := []string{}
+			for _, doc := range docs {
+				if crit.Satisfy(doc.getDocument()) {
+					got = append(got, doc.YtId)
+				}
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("matched %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSmartQueryErrors(t *testing.T) {
+	for _, smartQuery := range []string{
+		"",
+		"AND tag=chill",
+		"tag=chill OR",
+		"tag=chill AND OR plays>3",
+		`title="Rock AND Roll`,
+		"tag=chill AND unknown=1",
+		"plays>many",
+		"tag>chill",
+	} {
+		if _, err := ParseSmartQuery(smartQuery); err == nil {
+			t.Errorf("%q: no error", smartQuery)
+		}
+	}
+}
@@ -1,44 +1,75 @@
-package app
-
-import "fmt"
-
-// helper string
-const (
-	ludoBaseDir        = "ludo"
-	ludoPropertiesFile = "ludo.props"
-	defaultCacheDir    = "cache"
-)
-
-// properties file
-const (
-	isSourcePiped          = "config.source.isPiped"
-	isCacheEnabledKey      = "config.cache.enabled"
-	dataStoreKey           = "config.database.path"
-	cacheDirKey            = "config.cache.path"
-	pipedApiKey            = "config.piped.apiUrl"
-	defaultPipedApi        = "https://pipedapi.kavin.rocks"
-	instanceListApiKey     = "config.piped.instanceListApi"
-	defaultInstanceListApi = "https://piped-instances.kavin.rocks"
-)
-
-// Helpers //
-
-func trimList[T any](inputList []T, offset int, limit int) []T {
-	outputList := inputList
-
-	if offset > 0 && offset < len(inputList) {
-		outputList = outputList[offset:]
-	}
-
-	if limit > 0 && offset >= 0 && limit < len(inputList)-offset {
-		outputList = outputList[:limit]
-	}
-
-	return outputList
-}
-
-func GetFormattedTime(s int) string {
-	formattedPos := fmt.Sprintf("%dm%ds", s/60, s%60)
-
-	return formattedPos
-}
+package app
+
+import "fmt"
+
+// helper string
+const (
+	ludoBaseDir        = "ludo"
+	ludoPropertiesFile = "ludo.props"
+	defaultCacheDir    = "cache"
+	defaultBackupDir   = "backups"
+	defaultSocketFile  = "ludo.sock"
+	defaultLogFile     = "ludo.log"
+)
+
+// properties file
+const (
+	isSourcePiped          = "config.source.isPiped"
+	isCacheEnabledKey      = "config.cache.enabled"
+	dataStoreKey           = "config.database.path"
+	backupCountKey         = "config.database.backups"
+	defaultBackupCount     = 5
+	cacheDirKey            = "config.cache.path"
+	cacheExportTemplateKey = "config.cache.exportTemplate"
+	defaultExportTemplate  = "{uploader}/{title}.{ext}"
+	streamQualityKey       = "config.stream.quality"
+	listenBrainzTokenKey   = "config.listenbrainz.token"
+	listenBrainzApiRootKey = "config.listenbrainz.apiRoot"
+	defaultListenBrainzApi = "https://api.listenbrainz.org"
+	lastFmApiKeyKey        = "config.lastfm.apiKey"
+	lastFmApiSecretKey     = "config.lastfm.apiSecret"
+	lastFmSessionKeyKey    = "config.lastfm.sessionKey"
+	lastFmUsernameKey      = "config.lastfm.username"
+	lastFmPasswordKey      = "config.lastfm.password"
+	lastFmTokenKey         = "config.lastfm.token"
+	lastFmApiRootKey       = "config.lastfm.apiRoot"
+	defaultLastFmApi       = "https://ws.audioscrobbler.com/2.0/"
+	httpListenKey          = "config.http.listen"
+	httpTokenKey           = "config.http.token"
+	daemonSocketKey        = "config.daemon.socket"
+	mprisEnabledKey        = "config.mpris.enabled"
+	logLevelKey            = "config.log.level"
+	logFileKey             = "config.log.file"
+	logMaxSizeKey          = "config.log.maxSize"
+	defaultLogMaxSize      = 5
+	logBackupsKey          = "config.log.backups"
+	defaultLogBackups      = 3
+	lyricsApiKey           = "config.lyrics.apiUrl"
+	defaultLyricsApi       = "https://lrclib.net"
+	pipedApiKey            = "config.piped.apiUrl"
+	defaultPipedApi        = "https://pipedapi.kavin.rocks"
+	instanceListApiKey     = "config.piped.instanceListApi"
+	defaultInstanceListApi = "https://piped-instances.kavin.rocks"
+)
+
+// Helpers //
+
+func trimList[T any](inputList []T, offset int, limit int) []T {
+	outputList := inputList
+
+	if offset > 0 && offset < len(inputList) {
+		outputList = outputList[offset:]
+	}
+
+	if limit > 0 && offset >= 0 && limit < len(inputList)-offset {
+		outputList = outputList[:limit]
+	}
+
+	return outputList
+}
+
+func GetFormattedTime(s int) string {
+	formattedPos := fmt.Sprintf("%dm%ds", s/60, s%60)
+
+	return formattedPos
+}
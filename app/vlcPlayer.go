@@ -3,8 +3,8 @@ package app
 import (
 	"errors"
 	"fmt"
-	"io"
-	"log"
+	"sort"
+	"sync"
 
 	vlc "github.com/adrg/libvlc-go/v3"
 	uuid "github.com/satori/go.uuid"
@@ -12,9 +12,16 @@ import (
 
 var vlcPlayer VlcPlayer
 
+// VlcPlayer is used by the ui, the http api, mpris and the vlc event callbacks,
+// mu guards the queue and the state. It is not held during the vlc calls,
+// as they can fire the callbacks which take it. queueMu serialises the changes
+// of the queue, which are made to the media list and audioQueue together.
+// The callbacks never take it, so it is held during the media list calls
 type VlcPlayer struct {
 	player       *vlc.ListPlayer
 	mediaList    *vlc.MediaList
+	queueMu      sync.Mutex
+	mu           sync.RWMutex
 	audioQueue   []AudioDetails
 	audioState   AudioState
 	eventIDs     EventIdList
@@ -26,8 +33,8 @@ type EventIdList struct {
 	listPlayer []vlc.EventID
 }
 
-var vlcLog = log.New(io.Discard, "vlc: ", log.LstdFlags|log.Lmsgprefix)
-var eventLog = log.New(io.Discard, "vlcEvent: ", log.LstdFlags|log.Lmsgprefix)
+var vlcLog = newLogger("vlc")
+var eventLog = newLogger("vlcEvent")
 
 var playerStateMap = map[int]string{
 	0: "Nothing Special",
@@ -64,7 +71,7 @@ func (vlcPlayer *VlcPlayer) InitPlayer() error {
 	if err != nil {
 		return err
 	}
-	vlcLog.Println("List Player created")
+	vlcLog.Debug("List player created")
 
 	mediaList, err := vlc.NewMediaList()
 	if err != nil {
@@ -72,22 +79,27 @@ func (vlcPlayer *VlcPlayer) InitPlayer() error {
 	}
 
 	player.SetMediaList(mediaList)
-	vlcLog.Println("MediaList created")
+	vlcLog.Debug("Media list created")
 
+	vlcPlayer.queueMu.Lock()
 	vlcPlayer.mediaList = mediaList
 	vlcPlayer.player = player
+	vlcPlayer.mu.Lock()
 	vlcPlayer.audioQueue = make([]AudioDetails, 0)
 	vlcPlayer.audioState = AudioState{}
 	vlcPlayer.isMediaError = false
 	vlcPlayer.audioState.currentTrackIndex = -1
+	vlcPlayer.mu.Unlock()
+	vlcPlayer.queueMu.Unlock()
 
 	return vlcPlayer.attachEvents()
 }
 
 // Stops and releases the creates vlc player
 func (vlcPlayer *VlcPlayer) ClosePlayer() error {
-	vlcLog.Println("VLC Player closing...")
+	vlcLog.Info("Closing player")
 	vlcPlayer.player.Stop()
+	playHistory.finishTrack(PlaySkipped)
 	vlcPlayer.mediaList.Release()
 
 	player, err := vlcPlayer.player.Player()
@@ -95,25 +107,24 @@ func (vlcPlayer *VlcPlayer) ClosePlayer() error {
 		// Retrieve player event manager.
 		manager, err := player.EventManager()
 		if err == nil {
-			vlcLog.Println("player events detached")
+			vlcLog.Debug("Player events detached")
 			manager.Detach(vlcPlayer.eventIDs.player...)
 		}
 	}
-	vlcLog.Println("Reached here")
 
 	manager, err := vlcPlayer.player.EventManager()
 	if err == nil {
-		vlcLog.Println("List player event detached")
+		vlcLog.Debug("List player events detached")
 		manager.Detach(vlcPlayer.eventIDs.listPlayer...)
 	} else {
-		vlcLog.Println(err)
+		vlcLog.Warn("Error in detaching list player events", "err", err)
 	}
 
 	err = vlcPlayer.player.Release()
 	if err != nil {
 		return err
 	}
-	vlcLog.Println("VLC Player closed")
+	vlcLog.Info("Player closed")
 	return nil
 }
 
@@ -132,8 +143,8 @@ func (vlcPlayer *VlcPlayer) StartPlayback() error {
 	if err != nil {
 		return err
 	}
-	trackIndex := vlcPlayer.audioState.currentTrackIndex
-	vlcLog.Println("Current Index:", trackIndex)
+	trackIndex := vlcPlayer.GetQueueIndex()
+	vlcLog.Debug("Starting playback", "index", trackIndex)
 
 	if trackIndex < 0 {
 		trackIndex = 0
@@ -153,7 +164,9 @@ func (vlcPlayer *VlcPlayer) StopPlayback() error {
 		return err
 	}
 
+	vlcPlayer.mu.Lock()
 	vlcPlayer.audioState.currentTrackIndex = -1
+	vlcPlayer.mu.Unlock()
 	return nil
 }
 
@@ -219,6 +232,29 @@ func (vlcPlayer *VlcPlayer) RewindBySeconds(duration int) error {
 	return player.SetMediaTime(newTime)
 }
 
+// seeks to the position (seconds) of the current audio, capped at its length
+func (vlcPlayer *VlcPlayer) SeekToSeconds(position int) error {
+	if position < 0 {
+		return errors.New("negative position")
+	}
+
+	player, err := vlcPlayer.player.Player()
+	if err != nil {
+		return err
+	}
+
+	totalTime, err := player.MediaLength()
+	if err != nil {
+		return err
+	}
+
+	newTime := position * 1000
+	if newTime >= totalTime {
+		newTime = totalTime
+	}
+	return player.SetMediaTime(newTime)
+}
+
 func (vlcPlayer *VlcPlayer) SetVol(vol int) error {
 
 	if vol < 0 || vol > 100 {
@@ -237,35 +273,56 @@ func (vlcPlayer *VlcPlayer) SetVol(vol int) error {
 // info functions //
 ////////////////////
 
+// returns the volume (0-100), -1 if not available
+func (vlcPlayer *VlcPlayer) GetVol() int {
+	player, err := vlcPlayer.player.Player()
+	if err != nil {
+		return -1
+	}
+	vol, err := player.Volume()
+	if err != nil {
+		return -1
+	}
+	return vol
+}
+
 func (vlcPlayer *VlcPlayer) IsPlaying() bool {
 	return vlcPlayer.player.IsPlaying()
 }
 
 func (vlcPlayer *VlcPlayer) GetAudioState() AudioState {
+	vlcPlayer.mu.RLock()
+	defer vlcPlayer.mu.RUnlock()
 	return vlcPlayer.audioState
 }
 
 func (vlcPlayer *VlcPlayer) GetQueueIndex() int {
+	vlcPlayer.mu.RLock()
+	defer vlcPlayer.mu.RUnlock()
 	return vlcPlayer.audioState.currentTrackIndex
 }
 
+// returns a copy of the queue
 func (vlcPlayer *VlcPlayer) GetQueue() []AudioDetails {
-	return vlcPlayer.audioQueue
+	vlcPlayer.mu.RLock()
+	defer vlcPlayer.mu.RUnlock()
+	return append([]AudioDetails(nil), vlcPlayer.audioQueue...)
 }
 
 func (vlcPlayer *VlcPlayer) FetchPlayerState() int {
-	vlcLog.Println("Getting player state")
 	mediaState, err := vlcPlayer.player.MediaState()
 	if err != nil {
 		return 99
 	}
 
-	vlcLog.Printf("%v", mediaState)
+	vlcLog.Debug("Player state", "state", mediaState)
 
 	return int(mediaState)
 }
 
 func (vlcPlayer *VlcPlayer) CheckMediaError() bool {
+	vlcPlayer.mu.RLock()
+	defer vlcPlayer.mu.RUnlock()
 	return vlcPlayer.isMediaError
 }
 
@@ -293,74 +350,55 @@ func (vlcPlayer *VlcPlayer) GetMediaPosition() (int, int) {
 
 func (vlcPlayer *VlcPlayer) AppendAudio(audio *AudioDetails) error {
 	audio.uid = uuid.NewV1().String()
-	vlcLog.Println("Audio UUID:", audio.uid)
+	vlcLog.Debug("Appending audio", "ytId", audio.YtId, "uid", audio.uid)
 	err := vlcPlayer.addSongToQueue(audio)
 	return err
 }
 
 func (vlcPlayer *VlcPlayer) RemoveAudioFromIndex(removeIndex int) error {
-	currIndex := vlcPlayer.audioState.currentTrackIndex
-	queueLen, err := vlcPlayer.mediaList.Count()
-	if err != nil {
-		return err
-	}
-
-	if removeIndex <= currIndex || removeIndex > queueLen {
-		errString := fmt.Sprintf("%d, %d, %d", currIndex, removeIndex, queueLen)
-		return errors.New("Invalid remove index: " + errString)
-	}
-
-	if err = vlcPlayer.mediaList.Lock(); err != nil {
-		return err
-	}
-
-	if err = vlcPlayer.mediaList.RemoveMediaAtIndex(uint(removeIndex)); err != nil {
-		vlcPlayer.mediaList.Unlock()
-		return err
-	}
-
-	vlcPlayer.mediaList.Unlock()
-
-	vlcPlayer.audioQueue = append(vlcPlayer.audioQueue[:removeIndex], vlcPlayer.audioQueue[removeIndex+1:]...)
-
-	return nil
+	vlcPlayer.queueMu.Lock()
+	defer vlcPlayer.queueMu.Unlock()
+	return vlcPlayer.removeAudioRange(removeIndex, removeIndex+1)
 }
 
 func (vlcPlayer *VlcPlayer) RemoveLastAudio(removeIndex int) error {
-	return vlcPlayer.RemoveAudioFromIndex(len(vlcPlayer.audioQueue) - 1)
+	vlcPlayer.queueMu.Lock()
+	defer vlcPlayer.queueMu.Unlock()
+	lastIndex := len(vlcPlayer.GetQueue()) - 1
+	return vlcPlayer.removeAudioRange(lastIndex, lastIndex+1)
 }
 
-func (vlcPlayer *VlcPlayer) RemoveAllAudioFromIndex(removeIndex int) error {
-	currIndex := vlcPlayer.audioState.currentTrackIndex
-	queueLen, err := vlcPlayer.mediaList.Count()
-	if err != nil {
-		return err
-	}
-
-	if removeIndex <= currIndex || removeIndex > queueLen {
-		errString := fmt.Sprintf("%d, %d, %d", currIndex, removeIndex, queueLen)
-		return errors.New("Invalid remove index: " + errString)
-	}
+// removes the audio at all the indices or none of them, if any index cannot be removed
+func (vlcPlayer *VlcPlayer) RemoveAudioAtIndices(removeIndices []int) error {
+	vlcPlayer.queueMu.Lock()
+	defer vlcPlayer.queueMu.Unlock()
 
-	if err = vlcPlayer.mediaList.Lock(); err != nil {
-		return err
+	indices := append([]int(nil), removeIndices...)
+	sort.Ints(indices)
+	for _, removeIndex := range indices {
+		if err := vlcPlayer.validateRemoveIndex(removeIndex); err != nil {
+			return err
+		}
 	}
 
-	i := removeIndex
-	for ; i < queueLen; i++ {
-		if err = vlcPlayer.mediaList.RemoveMediaAtIndex(uint(removeIndex)); err != nil {
-			vlcPlayer.mediaList.Unlock()
-			break
+	// removing from the last keeps the other indices valid
+	for i := len(indices) - 1; i >= 0; i-- {
+		if i < len(indices)-1 && indices[i] == indices[i+1] {
+			continue
+		}
+		if err := vlcPlayer.removeAudioRange(indices[i], indices[i]+1); err != nil {
+			return err
 		}
 	}
-
-	vlcPlayer.mediaList.Unlock()
-
-	vlcPlayer.audioQueue = append(vlcPlayer.audioQueue[:removeIndex], vlcPlayer.audioQueue[i:]...)
-
 	return nil
 }
 
+func (vlcPlayer *VlcPlayer) RemoveAllAudioFromIndex(removeIndex int) error {
+	vlcPlayer.queueMu.Lock()
+	defer vlcPlayer.queueMu.Unlock()
+	return vlcPlayer.removeAudioRange(removeIndex, len(vlcPlayer.GetQueue()))
+}
+
 func (vlcPlayer *VlcPlayer) SkipToNext() error {
 	return vlcPlayer.player.PlayNext()
 }
@@ -370,9 +408,12 @@ func (vlcPlayer *VlcPlayer) SkipToPrevious() error {
 }
 
 func (vlcPlayer *VlcPlayer) SkipToIndex(trackIndex int) error {
-	if !vlcPlayer.validateTrackIndex(trackIndex) {
-		vlcLog.Println("!! [mediaChangedCallback] invalid track index")
-		return errors.New("invalid track index")
+	vlcPlayer.mu.RLock()
+	isValid := vlcPlayer.validateTrackIndex(trackIndex)
+	vlcPlayer.mu.RUnlock()
+	if !isValid {
+		vlcLog.Warn("Invalid track index", "index", trackIndex)
+		return fmt.Errorf("%w: %d", ErrInvalidIndex, trackIndex)
 	}
 
 	err := vlcPlayer.player.PlayAtIndex(uint(trackIndex))
@@ -392,39 +433,88 @@ func (vlcPlayer *VlcPlayer) addSongToQueue(audio *AudioDetails) error {
 	mediaCreated := false
 
 	if mediaPath, ok := audioCache.LookupCache(audio.AudioBasic); ok {
-		vlcLog.Println("Playing Cached audio:", audio.Title, ",", mediaPath)
+		vlcLog.Info("Playing cached audio", "title", audio.Title, "path", mediaPath)
 		newMedia, err := vlc.NewMediaFromPath(mediaPath)
 		if err == nil {
 			media = newMedia
 			mediaCreated = true
+			audio.StreamFormat = getCachedStreamFormat(mediaPath)
 		}
 	}
 	if !mediaCreated {
 		newMedia, err := vlc.NewMediaFromURL(audio.AudioStreamUrl)
 		if err != nil {
-			return err
+			return wrapErr(ErrMediaUnplayable, err)
 		}
 		media = newMedia
 		mediaCreated = true
 	}
 
 	if !mediaCreated {
-		return errors.New("could not create Media")
+		return fmt.Errorf("%w: could not create Media", ErrMediaUnplayable)
 	}
 
 	err := media.SetUserData(audio.uid)
 	if err != nil {
+		media.Release()
+		return err
+	}
+
+	vlcPlayer.queueMu.Lock()
+	defer vlcPlayer.queueMu.Unlock()
+	if err := vlcPlayer.mediaList.AddMedia(media); err != nil {
+		media.Release()
 		return err
 	}
 
+	vlcPlayer.mu.Lock()
 	vlcPlayer.audioQueue = append(vlcPlayer.audioQueue, *audio)
-	return vlcPlayer.mediaList.AddMedia(media)
+	vlcPlayer.mu.Unlock()
+	return nil
+}
+
+// removes the audio in [from, to) from the media list and the queue,
+// to be called with queueMu held. On a failed removal the queue keeps
+// the audio which is still in the media list
+func (vlcPlayer *VlcPlayer) removeAudioRange(from int, to int) error {
+	if err := vlcPlayer.validateRemoveIndex(from); err != nil {
+		return err
+	}
+
+	listLen, err := vlcPlayer.mediaList.Count()
+	if err != nil {
+		return err
+	}
+	vlcPlayer.mu.RLock()
+	queueLen := len(vlcPlayer.audioQueue)
+	vlcPlayer.mu.RUnlock()
+	if listLen != queueLen || to > queueLen {
+		return fmt.Errorf("%w to remove, the queue has %d songs and the media list %d: %d..%d", ErrInvalidIndex, queueLen, listLen, from, to)
+	}
+
+	if err := vlcPlayer.mediaList.Lock(); err != nil {
+		return err
+	}
+	removed := 0
+	for ; from+removed < to; removed++ {
+		if err = vlcPlayer.mediaList.RemoveMediaAtIndex(uint(from)); err != nil {
+			break
+		}
+	}
+	vlcPlayer.mediaList.Unlock()
+
+	vlcPlayer.mu.Lock()
+	vlcPlayer.audioQueue = append(vlcPlayer.audioQueue[:from], vlcPlayer.audioQueue[from+removed:]...)
+	vlcPlayer.mu.Unlock()
+	return err
 }
 
 func (vlcPlayer *VlcPlayer) updateCurrentMedia(trackIndex int) error {
+	vlcPlayer.mu.Lock()
+	defer vlcPlayer.mu.Unlock()
 	if !vlcPlayer.validateTrackIndex(trackIndex) {
-		vlcLog.Println("!! [mediaChangedCallback] invalid track index")
-		return errors.New("invalid track index")
+		vlcLog.Warn("Invalid track index", "index", trackIndex)
+		return fmt.Errorf("%w: %d", ErrInvalidIndex, trackIndex)
 	}
 
 	vlcPlayer.audioState.currentTrackIndex = trackIndex
@@ -448,54 +538,69 @@ func (vlcPlayer *VlcPlayer) resetMediaList() error {
 }
 
 func (vlcPlayer *VlcPlayer) getPlayerState() (*vlc.MediaState, error) {
-	vlcLog.Println("Getting player state")
 	mediaState, err := vlcPlayer.player.MediaState()
 	if err != nil {
 		return nil, err
 	}
 
-	vlcLog.Printf("%v", mediaState)
+	vlcLog.Debug("Player state", "state", mediaState)
 
 	return &mediaState, nil
 }
 
+// to be called with mu held
 func (vlcPlayer *VlcPlayer) validateTrackIndex(trackIndex int) bool {
 	return trackIndex >= 0 && trackIndex < len(vlcPlayer.audioQueue)
 }
 
+// only the audio after the current one can be removed
+func (vlcPlayer *VlcPlayer) validateRemoveIndex(removeIndex int) error {
+	vlcPlayer.mu.RLock()
+	currIndex, queueLen := vlcPlayer.audioState.currentTrackIndex, len(vlcPlayer.audioQueue)
+	vlcPlayer.mu.RUnlock()
+
+	if removeIndex <= currIndex || removeIndex >= queueLen {
+		errString := fmt.Sprintf("%d, %d, %d", currIndex, removeIndex, queueLen)
+		return fmt.Errorf("%w to remove, only songs after the current one can be removed: %s", ErrInvalidIndex, errString)
+	}
+	return nil
+}
+
 func (vlcPlayer *VlcPlayer) attachEvents() error {
 
 	mediaChangedCallback := func(event vlc.Event, userData interface{}) {
-		eventLog.Println("MediaChange Event")
+		defer HandlePanic()
+		eventLog.Debug("Media changed")
 
 		vlcPlayer, ok := userData.(*VlcPlayer)
 		if !ok {
-			eventLog.Println("!! [mediaChangedCallback] no vlc data")
+			eventLog.Error("Media changed without player data")
 			return
 		}
 
 		player, err := vlcPlayer.player.Player()
 		if err != nil {
-			eventLog.Println("!! [mediaChangedCallback] error:", err)
+			eventLog.Error("Error in reading the changed media", "err", err)
 			return
 		}
 		media, err := player.Media()
 		if err != nil {
-			eventLog.Println("!! [mediaChangedCallback] error:", err)
+			eventLog.Error("Error in reading the changed media", "err", err)
 			return
 		}
 		uData, err := media.UserData()
 		if err != nil {
-			eventLog.Println("!! [mediaChangedCallback] error:", err)
+			eventLog.Error("Error in reading the changed media", "err", err)
 			return
 		}
 		currUid, ok := uData.(string)
 		if !ok {
-			eventLog.Println("!! [mediaChangedCallback] error: could not convert to uid")
+			eventLog.Error("Changed media has no uid")
 			return
 		}
-		eventLog.Println("Curr UID:", currUid)
+		eventLog.Debug("Current media", "uid", currUid)
 
+		vlcPlayer.mu.Lock()
 		currInd := -1
 		for i, aud := range vlcPlayer.audioQueue {
 			if aud.uid == currUid {
@@ -506,23 +611,26 @@ func (vlcPlayer *VlcPlayer) attachEvents() error {
 
 		vlcPlayer.audioState.currentTrackIndex = currInd
 		trackIndex := vlcPlayer.audioState.currentTrackIndex
-		eventLog.Println(trackIndex)
-		if trackIndex < 0 || trackIndex > len(vlcPlayer.audioQueue) {
-			eventLog.Println("!! [mediaChangedCallback] invalid track index")
+		if trackIndex < 0 || trackIndex >= len(vlcPlayer.audioQueue) {
+			vlcPlayer.mu.Unlock()
+			eventLog.Error("Changed media not in queue", "index", trackIndex)
 			return
 		}
 
 		vlcPlayer.audioState.updateAudioState(&vlcPlayer.audioQueue[trackIndex])
-		eventLog.Println(vlcPlayer.audioState.String())
+		audioState := vlcPlayer.audioState
+		vlcPlayer.mu.Unlock()
+		eventLog.Info("Now playing", "index", trackIndex, "ytId", audioState.YtId, "title", audioState.Title)
+
+		playHistory.startTrack(audioState.AudioBasic)
 
-		err = audioDb.SaveOrIncrementAudioDoc(vlcPlayer.audioState.AudioBasic)
+		err = audioDb.SaveOrIncrementAudioDoc(audioState.AudioBasic)
 
 		if err != nil {
-			eventLog.Println("!! [mediaChangedCallback] error in saving to db")
-			eventLog.Println(err)
+			eventLog.Error("Error in saving to db", "err", err)
 		}
 
-		audioCache.CacheAudio(vlcPlayer.audioState.AudioDetails)
+		audioCache.CacheAudio(audioState.AudioDetails)
 	}
 
 	/*
@@ -530,25 +638,25 @@ func (vlcPlayer *VlcPlayer) attachEvents() error {
 
 			vlcPlayer, ok := userData.(*VlcPlayer)
 			if !ok {
-				eventLog.Println("!! [positionChangedCallback] could not vlc user instance")
+				eventLog.Error("Position changed: could not vlc user instance")
 				return
 			}
 
 			//eventLog.Println("PositionChange Event")
 			player, err := vlcPlayer.player.Player()
 			if err != nil {
-				eventLog.Println("!! [positionChangedCallback] could not fetch player")
+				eventLog.Error("Position changed: could not fetch player")
 				return
 			}
 
 			currPos, err := player.MediaTime()
 			if err != nil {
-				eventLog.Println("!! [positionChangedCallback] could not media curr time")
+				eventLog.Error("Position changed: could not media curr time")
 				return
 			}
 			totPos, err := player.MediaLength()
 			if err != nil {
-				eventLog.Println("!! [positionChangedCallback] could not media total length")
+				eventLog.Error("Position changed: could not media total length")
 				return
 			}
 
@@ -566,23 +674,43 @@ func (vlcPlayer *VlcPlayer) attachEvents() error {
 	*/
 
 	encounteredErrorCallback := func(event vlc.Event, userData interface{}) {
-		eventLog.Println("List player encountered error")
+		defer HandlePanic()
+		eventLog.Warn("List player encountered error")
 
 		vlcPlayer, ok := userData.(*VlcPlayer)
 		if !ok {
-			eventLog.Println("!! [encounteredErrorCallback] could not vlc user instance")
+			eventLog.Error("Player error without player data")
 			return
 		}
 
 		mediaState, err := vlcPlayer.player.MediaState()
 		if err != nil {
-			eventLog.Println("!! [encounteredErrorCallback] error: ", err)
+			eventLog.Error("Error in reading media state", "err", err)
 			return
 		}
 
-		eventLog.Println("MediaState: ", playerStateMap[int(mediaState)])
+		eventLog.Warn("Media could not be played", "state", playerStateMap[int(mediaState)])
 
+		vlcPlayer.mu.Lock()
 		vlcPlayer.isMediaError = true
+		vlcPlayer.mu.Unlock()
+		playHistory.finishTrack(PlayErrored)
+	}
+
+	playStateCallback := func(event vlc.Event, userData interface{}) {
+		defer HandlePanic()
+		eventLog.Debug("Play state changed", "event", event)
+
+		switch event {
+		case vlc.MediaPlayerPlaying:
+			playHistory.resume()
+		case vlc.MediaPlayerPaused:
+			playHistory.pause()
+		case vlc.MediaPlayerEndReached:
+			playHistory.finishTrack(PlayCompleted)
+		case vlc.MediaPlayerStopped:
+			playHistory.finishTrack(PlaySkipped)
+		}
 	}
 
 	player, err := vlcPlayer.player.Player()
@@ -620,6 +748,15 @@ func (vlcPlayer *VlcPlayer) attachEvents() error {
 	}
 
 	playerEventID := []vlc.EventID{eventID1, eventID3}
+
+	playStateEvents := []vlc.Event{vlc.MediaPlayerPlaying, vlc.MediaPlayerPaused, vlc.MediaPlayerEndReached, vlc.MediaPlayerStopped}
+	for _, playStateEvent := range playStateEvents {
+		eventID, err := listPlayerMan.Attach(playStateEvent, playStateCallback, vlcPlayer)
+		if err != nil {
+			return err
+		}
+		playerEventID = append(playerEventID, eventID)
+	}
 	vlcPlayer.eventIDs.player = playerEventID
 
 	lPlayerEventID := []vlc.EventID{}
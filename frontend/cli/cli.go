@@ -0,0 +1,231 @@
+package cli
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/johnrijoy/ludo-go/app"
+)
+
+// exit codes of the subcommands, kept stable for scripts
+const (
+	ExitOk         = 0
+	ExitError      = 1
+	ExitUsage      = 2
+	ExitNoInstance = 3
+	ExitLocked     = 4
+)
+
+// request type of the daemon protocol for subcommands, see frontend/tui/remote.go
+const requestCli = "cli"
+
+var errNoInstance = errors.New("ludo daemon is not running, start it with: ludo daemon")
+
+type usageError struct {
+	msg string
+}
+
+func (e usageError) Error() string {
+	return e.msg
+}
+
+type daemonRequest struct {
+	Type string   `json:"type"`
+	Args []string `json:"args"`
+}
+
+type daemonResponse struct {
+	Ok       bool            `json:"ok"`
+	Error    string          `json:"error,omitempty"`
+	ExitCode int             `json:"exitCode,omitempty"`
+	Result   json.RawMessage `json:"result,omitempty"`
+	Text     string          `json:"text,omitempty"`
+}
+
+// IsCommand reports if name is a subcommand
+func IsCommand(name string) bool {
+	_, ok := cliCommands[name]
+	return ok
+}
+
+// Usage writes the list of subcommands
+func Usage(w io.Writer) {
+	fmt.Fprintln(w, "Subcommands (--json for json output):")
+	for _, name := range cliCommandOrder {
+		fmt.Fprintf(w, "  %-28s %s\n", cliCommands[name].usage, cliCommands[name].desc)
+	}
+}
+
+// Run runs the subcommand on the daemon at socketPath if it is running,
+// else directly for the subcommands which do not need the player.
+// The result is written to stdout, errors to stderr, and the exit code is returned
+func Run(args []string, socketPath string) int {
+	result, text, err := run(args, socketPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		return ExitCode(err)
+	}
+
+	if hasFlag(args, "--json") {
+		os.Stdout.Write(result)
+		fmt.Println()
+	} else if text != "" {
+		fmt.Println(text)
+	}
+	return ExitOk
+}
+
+// ExitCode returns the exit code for the error of a subcommand
+func ExitCode(err error) int {
+	var usageErr usageError
+	var remoteErr remoteError
+	switch {
+	case err == nil:
+		return ExitOk
+	case errors.As(err, &remoteErr) && remoteErr.code != 0:
+		return remoteErr.code
+	case errors.As(err, &usageErr):
+		return ExitUsage
+	case errors.Is(err, errNoInstance):
+		return ExitNoInstance
+	case errors.Is(err, app.ErrLibraryLocked):
+		return ExitLocked
+	default:
+		return ExitError
+	}
+}
+
+func run(args []string, socketPath string) (json.RawMessage, string, error) {
+	if len(args) == 0 || !IsCommand(args[0]) {
+		return nil, "", usageError{"invalid subcommand"}
+	}
+	cmd := cliCommands[args[0]]
+
+	// file paths are sent to the daemon as absolute, it may run in another dir
+	args = append([]string{}, args...)
+	if cmd.pathArg > 0 {
+		resolvePathArg(args, cmd.pathArg)
+	}
+
+	conn, err := net.Dial("unix", socketPath)
+	if err == nil {
+		defer conn.Close()
+		return runRemote(conn, args)
+	}
+
+	if cmd.needsPlayer {
+		return nil, "", errNoInstance
+	}
+	return runLocal(args)
+}
+
+func runRemote(conn net.Conn, args []string) (json.RawMessage, string, error) {
+	if err := json.NewEncoder(conn).Encode(daemonRequest{Type: requestCli, Args: args}); err != nil {
+		return nil, "", err
+	}
+	line, err := bufio.NewReader(conn).ReadBytes('\n')
+	if err != nil {
+		return nil, "", err
+	}
+
+	var resp daemonResponse
+	if err := json.Unmarshal(line, &resp); err != nil {
+		return nil, "", err
+	}
+	if !resp.Ok {
+		return nil, "", remoteError{resp.Error, resp.ExitCode}
+	}
+	return resp.Result, resp.Text, nil
+}
+
+func runLocal(args []string) (json.RawMessage, string, error) {
+	if err := app.InitLibrary(); err != nil {
+		return nil, "", err
+	}
+	defer app.Close()
+
+	result, text, err := Exec(args)
+	if err != nil {
+		return nil, "", err
+	}
+	data, err := json.Marshal(result)
+	return data, text, err
+}
+
+// error returned by the daemon, keeps its exit code
+type remoteError struct {
+	msg  string
+	code int
+}
+
+func (e remoteError) Error() string {
+	return e.msg
+}
+
+// makes the positional arg at index absolute
+func resolvePathArg(args []string, index int) {
+	pos := 0
+	for i := 0; i < len(args); i++ {
+		switch {
+		case args[i] == "--limit":
+			i++
+		case strings.HasPrefix(args[i], "--"):
+		case pos == index:
+			if absPath, err := filepath.Abs(args[i]); err == nil {
+				args[i] = absPath
+			}
+			return
+		default:
+			pos++
+		}
+	}
+}
+
+func hasFlag(args []string, flag string) bool {
+	for _, arg := range args {
+		if arg == flag {
+			return true
+		}
+	}
+	return false
+}
+
+// splits the args into positional args and the flags
+func parseArgs(args []string) ([]string, cliOptions, error) {
+	opts := cliOptions{limit: defaultLimit}
+	positional := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--limit" || strings.HasPrefix(arg, "--limit="):
+			value, ok := strings.CutPrefix(arg, "--limit=")
+			if !ok {
+				if i+1 >= len(args) {
+					return nil, opts, usageError{"no value given for --limit"}
+				}
+				i++
+				value = args[i]
+			}
+			var err error
+			if opts.limit, err = strconv.Atoi(value); err != nil || opts.limit <= 0 {
+				return nil, opts, usageError{"invalid limit: " + value}
+			}
+		case arg == "--merge":
+			opts.merge = true
+		case arg == "--json":
+		case strings.HasPrefix(arg, "--"):
+			return nil, opts, usageError{"unknown flag: " + arg}
+		default:
+			positional = append(positional, arg)
+		}
+	}
+	return positional, opts, nil
+}
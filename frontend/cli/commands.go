@@ -0,0 +1,256 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/johnrijoy/ludo-go/app"
+)
+
+const defaultLimit = 10
+
+type cliCommand struct {
+	usage       string
+	desc        string
+	needsPlayer bool
+	pathArg     int // positional index of a file path arg, 0 if none
+	run         func(args []string, opts cliOptions) (interface{}, string, error)
+}
+
+// result of the player actions
+type actionResult struct {
+	Action string          `json:"action"`
+	Audio  *app.AudioBasic `json:"audio,omitempty"`
+}
+
+// flags of the subcommands, --json is handled by the client
+type cliOptions struct {
+	limit int
+	merge bool
+}
+
+type fileResult struct {
+	File string `json:"file"`
+}
+
+var cliCommands = map[string]cliCommand{
+	"search": {usage: "search <query> [--limit n]", desc: "search songs", run: searchSongs},
+	"play":   {usage: "play <query>", desc: "add the song to the queue, playback starts if not playing", needsPlayer: true, run: playSong},
+	"queue":  {usage: "queue", desc: "display the song queue", needsPlayer: true, run: showQueue},
+	"status": {usage: "status", desc: "display the current song and player state", needsPlayer: true, run: showStatus},
+	"next":   {usage: "next", desc: "skip to next song", needsPlayer: true, run: skipNext},
+	"prev":   {usage: "prev", desc: "skip to previous song", needsPlayer: true, run: skipPrevious},
+	"pause":  {usage: "pause", desc: "toggle pause/resume", needsPlayer: true, run: pauseResume},
+	"ls":     {usage: "ls <criteria> [--limit n]", desc: "list songs by criteria (recent, plays, likes) or a smart playlist", run: listSongs},
+	"db":     {usage: "db export|import <file> [--merge]", desc: "export or import the library, or take a backup (db backup)", pathArg: 2, run: manageDb},
+	"cache":  {usage: "cache stats", desc: "display the number and size of cached songs", run: manageCache},
+}
+
+var cliCommandOrder = []string{"search", "play", "queue", "status", "next", "prev", "pause", "ls", "db", "cache"}
+
+// Exec runs the subcommand in this process, the player must be running for the player subcommands.
+// Returns the result for json output along with its text form
+func Exec(args []string) (interface{}, string, error) {
+	positional, opts, err := parseArgs(args)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(positional) == 0 || !IsCommand(positional[0]) {
+		return nil, "", usageError{"invalid subcommand"}
+	}
+	return cliCommands[positional[0]].run(positional[1:], opts)
+}
+
+// Library commands
+
+func searchSongs(args []string, opts cliOptions) (interface{}, string, error) {
+	if len(args) == 0 {
+		return nil, "", usageError{"no search query given"}
+	}
+
+	audioList, err := app.GetSearchList(app.IsSourcePiped())(strings.Join(args, " "), 0, opts.limit)
+	if err != nil {
+		return nil, "", err
+	}
+	return *audioList, formatAudioList(*audioList), nil
+}
+
+func listSongs(args []string, opts cliOptions) (interface{}, string, error) {
+	if len(args) == 0 {
+		return nil, "", usageError{"no criteria given"}
+	}
+
+	audDocs, err := app.AudioDb().GetNamedAudioList(strings.Join(args, " "), 0, opts.limit)
+	if err != nil {
+		return nil, "", err
+	}
+
+	audioList := make([]app.AudioBasic, len(audDocs))
+	for i, audDoc := range audDocs {
+		audioList[i] = audDoc.AudioBasic
+	}
+	return audDocs, formatAudioList(audioList), nil
+}
+
+func manageDb(args []string, opts cliOptions) (interface{}, string, error) {
+	if len(args) == 0 {
+		return nil, "", usageError{"no db command given (export, import, backup)"}
+	}
+
+	switch args[0] {
+	case "export":
+		if len(args) < 2 {
+			return nil, "", usageError{"no export file given"}
+		}
+		if err := app.ExportDb(args[1]); err != nil {
+			return nil, "", err
+		}
+		return fileResult{args[1]}, "Library exported to " + args[1], nil
+
+	case "import":
+		if len(args) < 2 {
+			return nil, "", usageError{"no import file given"}
+		}
+		report, err := app.ImportDb(args[1], opts.merge)
+		if err != nil {
+			return nil, "", err
+		}
+		return report, fmt.Sprintf("Imported %d new and merged %d songs from %s", report.Added, report.Merged, args[1]), nil
+
+	case "backup":
+		backupPath, err := app.BackupDb()
+		if err != nil {
+			return nil, "", err
+		}
+		return fileResult{backupPath}, "Backup created at " + backupPath, nil
+
+	default:
+		return nil, "", usageError{"invalid db command (export, import, backup)"}
+	}
+}
+
+func manageCache(args []string, opts cliOptions) (interface{}, string, error) {
+	if len(args) == 0 || args[0] != "stats" {
+		return nil, "", usageError{"invalid cache command (stats)"}
+	}
+
+	stats, err := app.AudioCache().Stats()
+	if err != nil {
+		return nil, "", err
+	}
+	if !stats.Enabled {
+		return stats, "Caching is disabled", nil
+	}
+
+	formats := make([]string, 0, len(stats.Formats))
+	for format, count := range stats.Formats {
+		formats = append(formats, fmt.Sprintf("%s: %d", format, count))
+	}
+	sort.Strings(formats)
+	text := fmt.Sprintf("%d songs, %.1f MB in %s", stats.Files, float64(stats.Size)/(1<<20), stats.Dir)
+	if len(formats) > 0 {
+		text += fmt.Sprintf(" (%s)", strings.Join(formats, ", "))
+	}
+	return stats, text, nil
+}
+
+// Player commands
+
+func playSong(args []string, opts cliOptions) (interface{}, string, error) {
+	if len(args) == 0 {
+		return nil, "", usageError{"no search query given"}
+	}
+
+	audio, err := app.GetSong(app.IsSourcePiped())(strings.Join(args, " "), false)
+	if err != nil {
+		return nil, "", err
+	}
+	if err := app.MediaPlayer().AppendAudio(audio); err != nil {
+		return nil, "", err
+	}
+
+	result := actionResult{Action: "added", Audio: &audio.AudioBasic}
+	if !app.MediaPlayer().IsPlaying() {
+		if err := app.MediaPlayer().StartPlayback(); err != nil {
+			return nil, "", err
+		}
+		result.Action = "playing"
+	}
+	return result, formatAction(result), nil
+}
+
+func showQueue(args []string, opts cliOptions) (interface{}, string, error) {
+	queue := app.GetQueueStatus()
+
+	lines := make([]string, len(queue.Queue))
+	for i, audio := range queue.Queue {
+		marker := ""
+		if i == queue.Index {
+			marker = "*"
+		}
+		lines[i] = marker + "\t" + formatAudio(i, audio)
+	}
+	return queue, strings.Join(lines, "\n"), nil
+}
+
+func showStatus(args []string, opts cliOptions) (interface{}, string, error) {
+	status := app.GetPlayerStatus()
+	text := fmt.Sprintf("%s\t%s\t%s\t%s\t%s/%s", status.State, status.Audio.YtId, status.Audio.Title, status.Audio.Uploader,
+		app.GetFormattedTime(status.Position), app.GetFormattedTime(status.Length))
+	return status, text, nil
+}
+
+func skipNext(args []string, opts cliOptions) (interface{}, string, error) {
+	if err := app.MediaPlayer().SkipToNext(); err != nil {
+		return nil, "", err
+	}
+	return currentAction("next")
+}
+
+func skipPrevious(args []string, opts cliOptions) (interface{}, string, error) {
+	if err := app.MediaPlayer().SkipToPrevious(); err != nil {
+		return nil, "", err
+	}
+	return currentAction("prev")
+}
+
+func pauseResume(args []string, opts cliOptions) (interface{}, string, error) {
+	action := "resumed"
+	if app.MediaPlayer().IsPlaying() {
+		action = "paused"
+	}
+	if err := app.MediaPlayer().PauseResume(); err != nil {
+		return nil, "", err
+	}
+	return currentAction(action)
+}
+
+// Helpers
+
+func currentAction(action string) (interface{}, string, error) {
+	audio := app.MediaPlayer().GetAudioState().AudioBasic
+	if audio.YtId == "" {
+		return nil, "", errors.New("no song in queue")
+	}
+	result := actionResult{Action: action, Audio: &audio}
+	return result, formatAction(result), nil
+}
+
+func formatAction(result actionResult) string {
+	return fmt.Sprintf("%s\t%s\t%s", result.Action, result.Audio.YtId, result.Audio.Title)
+}
+
+// one line per audio, tab separated: index (from 1), id, title, uploader, duration
+func formatAudioList(audioList []app.AudioBasic) string {
+	lines := make([]string, len(audioList))
+	for i, audio := range audioList {
+		lines[i] = formatAudio(i, audio)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func formatAudio(index int, audio app.AudioBasic) string {
+	return fmt.Sprintf("%d\t%s\t%s\t%s\t%s", index+1, audio.YtId, audio.Title, audio.Uploader, audio.GetFormattedDuration())
+}
@@ -0,0 +1,87 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/johnrijoy/ludo-go/app"
+)
+
+const configKeyWidth = 32
+
+// Config commands
+
+func listConfig(s *Session, args Args) (*Result, error) {
+	table := &Table{Title: "Config"}
+	for _, entry := range app.ListConfig() {
+		table.Rows = append(table.Rows, configRow(entry))
+	}
+	return &Result{Table: table}, nil
+}
+
+func getConfig(s *Session, args Args) (*Result, error) {
+	entry, err := app.GetConfig(args.String("key"))
+	if err != nil {
+		return nil, err
+	}
+	if !entry.IsSet {
+		if entry.Default == "" {
+			return message("%s is not set", entry.Key), nil
+		}
+		return message("%s is not set, default is %s", entry.Key, entry.Default), nil
+	}
+	return message("%s = %s", entry.Key, entry.Value), nil
+}
+
+func setConfig(s *Session, args Args) (*Result, error) {
+	key := args.String("key")
+	applied, err := app.SetConfig(key, args.String("value"))
+	if err != nil {
+		return nil, err
+	}
+	s.IsPiped = app.IsSourcePiped()
+	return configSaved(key, applied), nil
+}
+
+func unsetConfig(s *Session, args Args) (*Result, error) {
+	key := args.String("key")
+	applied, err := app.UnsetConfig(key)
+	if err != nil {
+		return nil, err
+	}
+	s.IsPiped = app.IsSourcePiped()
+	return configSaved(key, applied), nil
+}
+
+func validateConfig(s *Session, args Args) (*Result, error) {
+	issues := app.ValidateConfig()
+	if len(issues) == 0 {
+		return message("Config is %s", "valid"), nil
+	}
+
+	table := &Table{Title: "Config issues"}
+	for _, issue := range issues {
+		table.Rows = append(table.Rows, Row{Cells: []Cell{{Text: issue.Key, Width: configKeyWidth, Style: Name}, {Text: issue.Message}}})
+	}
+	return &Result{Table: table}, nil
+}
+
+func configSaved(key string, applied bool) *Result {
+	if applied {
+		return message("Saved %s", key)
+	}
+	return message("Saved %s, applied on restart", key)
+}
+
+// set properties are highlighted, secrets are hidden
+func configRow(entry app.ConfigEntry) Row {
+	value := Cell{Text: entry.Value, Style: Accent}
+	switch {
+	case entry.IsSet && entry.Secret:
+		value.Text = "****"
+	case !entry.IsSet && entry.Value == "":
+		value = Cell{Text: "not set", Style: Muted}
+	case !entry.IsSet:
+		value = Cell{Text: fmt.Sprintf("%s (default)", entry.Value), Style: Muted}
+	}
+	return Row{Highlight: entry.IsSet, Cells: []Cell{{Text: entry.Key, Width: configKeyWidth}, value}}
+}
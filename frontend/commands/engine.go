@@ -0,0 +1,273 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/johnrijoy/ludo-go/app"
+)
+
+// Command is an entry of the registry, shared by the prompt UI and the TUI
+type Command struct {
+	Name        string
+	Aliases     []string
+	Args        []Arg
+	Flags       []Flag
+	Help        string
+	Subcommands []*Command // selected by the first argument, else the command runs itself
+	Run         func(s *Session, args Args) (*Result, error)
+}
+
+// Arg describes a positional argument of a command
+type Arg struct {
+	Name     string
+	Type     ArgType
+	Optional bool
+	Rest     bool // takes the rest of the input, ex: a search query
+}
+
+// Flag describes an option given anywhere in the input as --name, --name value or --name=value
+type Flag struct {
+	Name  string
+	Value string // name of the value shown in usage, a flag without value is a switch
+	Type  ArgType
+}
+
+// Args are the parsed arguments by name, and the flags by --name
+type Args map[string]string
+
+// Session is the state kept across the commands of a UI
+type Session struct {
+	IsPiped bool
+}
+
+// Warn error, shown as a warning by the UIs
+
+type ErrWarn struct {
+	msg string
+}
+
+func Warn(msg string) ErrWarn {
+	return ErrWarn{msg: msg}
+}
+
+func (w ErrWarn) Error() string {
+	return w.msg
+}
+
+// returns true if the err should be shown as a warning, for ErrWarn and the recoverable errors of the app
+func IsWarning(err error) bool {
+	var warn ErrWarn
+	return errors.As(err, &warn) || app.IsRecoverable(err)
+}
+
+// returns a session with the source from the properties, app must be initialised
+func NewSession() *Session {
+	return &Session{IsPiped: app.IsSourcePiped()}
+}
+
+// Run parses the input and runs the command
+func (s *Session) Run(input string) (*Result, error) {
+	fields, err := tokenize(input)
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) == 0 {
+		return nil, Warn("Invalid command")
+	}
+
+	cmd := lookup(fields[0])
+	if cmd == nil {
+		return nil, Warn("Invalid command")
+	}
+
+	name, fields := cmd.Name, fields[1:]
+	if len(fields) > 0 {
+		for _, sub := range cmd.Subcommands {
+			if sub.Name == fields[0] {
+				cmd, name, fields = sub, name+" "+sub.Name, fields[1:]
+				break
+			}
+		}
+	}
+	if cmd.Run == nil {
+		return nil, Warn(fmt.Sprintf("Invalid %s command (%s)", name, cmd.subcommandNames()))
+	}
+
+	args, err := cmd.parseArgs(fields)
+	if err != nil {
+		return nil, Warn(fmt.Sprintf("%s, usage: %s", err, strings.TrimSpace(name+" "+cmd.usage())))
+	}
+	return cmd.Run(s, args)
+}
+
+func lookup(name string) *Command {
+	for _, cmd := range registry {
+		if cmd.Name == name {
+			return cmd
+		}
+		for _, alias := range cmd.Aliases {
+			if alias == name {
+				return cmd
+			}
+		}
+	}
+	return nil
+}
+
+// maps the tokens to the flags and args and validates their types.
+// Tokens after -- are never taken as flags
+func (cmd *Command) parseArgs(tokens []string) (Args, error) {
+	args := make(Args, len(cmd.Args)+len(cmd.Flags))
+
+	var fields []string
+	for i := 0; i < len(tokens); i++ {
+		token := tokens[i]
+		if token == "--" {
+			fields = append(fields, tokens[i+1:]...)
+			break
+		}
+		if !strings.HasPrefix(token, "--") {
+			fields = append(fields, token)
+			continue
+		}
+
+		name, value, hasValue := strings.Cut(token[2:], "=")
+		flag := cmd.flag(name)
+		switch {
+		case flag == nil:
+			return nil, fmt.Errorf("unknown flag --%s", name)
+		case flag.Value == "" && hasValue:
+			return nil, fmt.Errorf("flag --%s takes no value", name)
+		case flag.Value == "":
+			value = "true"
+		case !hasValue:
+			if i+1 >= len(tokens) {
+				return nil, fmt.Errorf("flag --%s needs a %s", name, flag.Value)
+			}
+			i++
+			value = tokens[i]
+		}
+		if err := flag.Type.validate("--"+name, value); err != nil {
+			return nil, err
+		}
+		args["--"+name] = value
+	}
+
+	for i, arg := range cmd.Args {
+		if i >= len(fields) {
+			if !arg.Optional {
+				return nil, fmt.Errorf("missing <%s>", arg.Name)
+			}
+			continue
+		}
+
+		value := fields[i]
+		if arg.Rest {
+			value = strings.Join(fields[i:], " ")
+		}
+		if err := arg.Type.validate(arg.Name, value); err != nil {
+			return nil, err
+		}
+		args[arg.Name] = value
+		if arg.Rest {
+			return args, nil
+		}
+	}
+
+	if len(fields) > len(cmd.Args) {
+		return nil, fmt.Errorf("unexpected argument %q", fields[len(cmd.Args)])
+	}
+	return args, nil
+}
+
+func (cmd *Command) flag(name string) *Flag {
+	for i := range cmd.Flags {
+		if cmd.Flags[i].Name == name {
+			return &cmd.Flags[i]
+		}
+	}
+	return nil
+}
+
+// returns the usage of the flags and args, ex: [index] for skip
+func (cmd *Command) usage() string {
+	if len(cmd.Args) == 0 && len(cmd.Subcommands) > 0 && cmd.Run == nil {
+		return "<" + strings.ReplaceAll(cmd.subcommandNames(), ", ", "/") + ">"
+	}
+
+	usage := make([]string, 0, len(cmd.Flags)+len(cmd.Args))
+	for _, flag := range cmd.Flags {
+		if flag.Value == "" {
+			usage = append(usage, "[--"+flag.Name+"]")
+		} else {
+			usage = append(usage, "[--"+flag.Name+" <"+flag.Value+">]")
+		}
+	}
+	for _, arg := range cmd.Args {
+		if arg.Optional {
+			usage = append(usage, "["+arg.Name+"]")
+		} else {
+			usage = append(usage, "<"+arg.Name+">")
+		}
+	}
+	return strings.Join(usage, " ")
+}
+
+func (cmd *Command) subcommandNames() string {
+	names := make([]string, len(cmd.Subcommands))
+	for i, sub := range cmd.Subcommands {
+		names[i] = sub.Name
+	}
+	return strings.Join(names, ", ")
+}
+
+// Args helpers
+
+func (args Args) String(name string) string {
+	return args[name]
+}
+
+// returns the int argument, def if not given
+func (args Args) Int(name string, def int) (int, error) {
+	value, ok := args[name]
+	if !ok || value == "" {
+		return def, nil
+	}
+	return strconv.Atoi(value)
+}
+
+// returns true if the switch flag is given, ex: --all
+func (args Args) Bool(name string) bool {
+	return args[name] == "true"
+}
+
+// returns the queue index from 0 of the position argument in a queue of the length.
+// def is the index from 0 used if not given
+func (args Args) QueueIndex(name string, def int, length int) (int, error) {
+	value, ok := args[name]
+	if !ok || value == "" {
+		return def, nil
+	}
+	pos, err := parsePosition(value)
+	if err != nil {
+		return 0, err
+	}
+	return resolvePosition(pos, length)
+}
+
+// returns the sorted queue indices from 0 of the positions argument in a queue of the length.
+// def is the index from 0 used if not given
+func (args Args) QueueIndices(name string, def int, length int) ([]int, error) {
+	value, ok := args[name]
+	if !ok || value == "" {
+		return []int{def}, nil
+	}
+	ranges, err := parseIndexList(value)
+	if err != nil {
+		return nil, err
+	}
+	return resolveIndexList(ranges, length)
+}
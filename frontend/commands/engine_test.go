@@ -0,0 +1,91 @@
+package commands
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParseArgs(t *testing.T) {
+	cmd := &Command{
+		Name:  "test",
+		Args:  []Arg{{Name: "index", Type: Index}, {Name: "text", Optional: true, Rest: true}},
+		Flags: []Flag{{Name: "all"}, {Name: "limit", Value: "count", Type: Number}},
+	}
+
+	tests := []struct {
+		name   string
+		tokens []string
+		args   Args
+		err    string
+	}{
+		{"args", []string{"3", "get", "lucky"}, Args{"index": "3", "text": "get lucky"}, ""},
+		{"optional arg", []string{"-1"}, Args{"index": "-1"}, ""},
+		{"switch", []string{"--all", "3"}, Args{"--all": "true", "index": "3"}, ""},
+		{"flag value", []string{"--limit", "5", "3"}, Args{"--limit": "5", "index": "3"}, ""},
+		{"flag equals value", []string{"3", "--limit=5"}, Args{"--limit": "5", "index": "3"}, ""},
+		{"flag in the rest", []string{"3", "get", "--all", "lucky"}, Args{"--all": "true", "index": "3", "text": "get lucky"}, ""},
+		{"double dash", []string{"3", "--", "--all", "--limit"}, Args{"index": "3", "text": "--all --limit"}, ""},
+		{"double dash before an arg", []string{"--all", "--", "-1"}, Args{"--all": "true", "index": "-1"}, ""},
+		{"missing arg", []string{"--all"}, nil, "missing <index>"},
+		{"unknown flag", []string{"--none", "3"}, nil, "unknown flag --none"},
+		{"switch with value", []string{"--all=yes", "3"}, nil, "flag --all takes no value"},
+		{"flag without value", []string{"3", "--limit"}, nil, "flag --limit needs a count"},
+		{"invalid flag value", []string{"--limit", "ten", "3"}, nil, `invalid --limit "ten", expected a number`},
+		{"invalid arg", []string{"0"}, nil, `invalid index "0", expected a position like 3 or -1`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args, err := cmd.parseArgs(tt.tokens)
+			if tt.err != "" {
+				if err == nil || err.Error() != tt.err {
+					t.Fatalf("err = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parse: %v", err)
+			}
+			if !reflect.DeepEqual(args, tt.args) {
+				t.Errorf("args = %v, want %v", args, tt.args)
+			}
+		})
+	}
+}
+
+func TestParseArgsUnexpected(t *testing.T) {
+	cmd := &Command{Name: "test", Args: []Arg{{Name: "lines", Type: Number, Optional: true}}}
+	if _, err := cmd.parseArgs([]string{"10", "20"}); err == nil || err.Error() != `unexpected argument "20"` {
+		t.Errorf("err = %v, want unexpected argument", err)
+	}
+}
+
+// the warnings shown for input which fails before a command runs
+func TestSessionRunWarnings(t *testing.T) {
+	tests := []struct {
+		input string
+		err   string
+	}{
+		{"", "Invalid command"},
+		{"unknown", "Invalid command"},
+		{`play "get lucky`, `unterminated " quote`},
+		{`play lucky\`, "nothing to escape at the end of input"},
+		{"remove 0", `invalid indices "0", expected positions like 3, -1, 3-7 or 2,4,6, usage: remove [indices]`},
+		{"rem 2,,4", `invalid indices "2,,4", expected positions like 3, -1, 3-7 or 2,4,6, usage: remove [indices]`},
+		{"search", "missing <song name>, usage: search [--all] [--limit <n>] <song name>"},
+		{"search --limit", "flag --limit needs a n, usage: search [--all] [--limit <n>] <song name>"},
+		{"tag rm", "missing <tag>, usage: tag rm <tag> [indices]"},
+		{"tag", "Invalid tag command (add, rm)"},
+	}
+
+	s := &Session{}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			_, err := s.Run(tt.input)
+			if !errors.As(err, &ErrWarn{}) || err.Error() != tt.err {
+				t.Errorf("err = %v, want warning %q", err, tt.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package commands
+
+import (
+	"errors"
+	"log/slog"
+	"math/rand"
+
+	"github.com/johnrijoy/ludo-go/app"
+)
+
+const logListSize = 20
+
+// Info commands
+
+func setSource(s *Session, args Args) (*Result, error) {
+	switch args.String("youtube/yt|piped/pp") {
+	case "":
+		if s.IsPiped {
+			return message("Source is %s", "Piped"), nil
+		}
+		return message("Source is %s", "Youtube"), nil
+	case "youtube", "yt":
+		s.IsPiped = false
+		return message("Source changed to %s", "Youtube"), app.SetSourcePiped(false)
+	case "piped", "pp":
+		s.IsPiped = true
+		return message("Source changed to %s", "Piped"), app.SetSourcePiped(true)
+	default:
+		return nil, Warn("Source not valid (youtube/yt, piped/pp)")
+	}
+}
+
+func checkApi(s *Session, args Args) (*Result, error) {
+	return message("Piped Api: %s", app.Piped.GetPipedApi()), nil
+}
+
+func modifyApi(s *Session, args Args) (*Result, error) {
+	if err := app.SetPipedApi(args.String("piped api")); err != nil {
+		return nil, err
+	}
+	return message("Api changed from %s to %s", app.Piped.GetOldPipedApi(), app.Piped.GetPipedApi()), nil
+}
+
+func displayApiList(s *Session, args Args) (*Result, error) {
+	apiList, err := app.Piped.GetPipedInstanceList()
+	if err != nil {
+		return nil, errors.Join(errors.New("error in fetching Instance list"), err)
+	}
+
+	choice := &Choice{Name: "api list", Prompt: "> Enter index number to change api (q to escape): "}
+	for i, inst := range apiList {
+		choice.Rows = append(choice.Rows, Row{Index: i + 1, Cells: []Cell{{Text: inst.String()}}})
+	}
+	choice.OnSelect = func(index int) (*Result, error) {
+		return modifyApi(s, Args{"piped api": apiList[index].ApiUrl})
+	}
+	return &Result{Choice: choice}, nil
+}
+
+func modifyApiRandom(s *Session, args Args) (*Result, error) {
+	apiList, err := app.Piped.GetPipedInstanceList()
+	if err != nil {
+		return nil, errors.Join(errors.New("error in fetching Instance list"), err)
+	}
+	if len(apiList) == 0 {
+		return nil, Warn("No instances found")
+	}
+	return modifyApi(s, Args{"piped api": apiList[rand.Intn(len(apiList))].ApiUrl})
+}
+
+func displayVersion(s *Session, args Args) (*Result, error) {
+	table := &Table{Title: "Info"}
+	for _, item := range [][2]string{
+		{"Ludo version", app.Version},
+		{"Api", app.Piped.GetPipedApi()},
+		{"libVlc Binding Version", app.Info().String()},
+		{"Vlc Runtime Version", app.Info().Changeset()},
+	} {
+		table.Rows = append(table.Rows, Row{Cells: []Cell{{Text: item[0], Width: 24}, {Text: item[1], Style: Accent}}})
+	}
+	return &Result{Table: table}, nil
+}
+
+func displayLog(s *Session, args Args) (*Result, error) {
+	count, err := args.Int("lines", logListSize)
+	if err != nil {
+		return nil, err
+	}
+
+	level := slog.LevelDebug
+	if value := args.String("--level"); value != "" {
+		if err := level.UnmarshalText([]byte(value)); err != nil {
+			return nil, Warn("Log level not valid (debug, info, warn, error)")
+		}
+	}
+
+	lines := app.RecentLogs(count, level)
+	if len(lines) == 0 {
+		return nil, Warn("No log entries")
+	}
+
+	table := &Table{Title: "Log"}
+	for _, line := range lines {
+		table.Rows = append(table.Rows, Row{Highlight: app.LogLineLevel(line) >= slog.LevelWarn, Cells: []Cell{{Text: line}}})
+	}
+	return &Result{Table: table}, nil
+}
+
+func showHelp(s *Session, args Args) (*Result, error) {
+	return &Result{Help: true}, nil
+}
+
+func quit(s *Session, args Args) (*Result, error) {
+	return &Result{Quit: true}, nil
+}
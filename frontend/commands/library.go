@@ -0,0 +1,378 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/johnrijoy/ludo-go/app"
+)
+
+const (
+	songListSize    = 10
+	historyPageSize = 20
+	statsListSize   = 10
+	statsBarWidth   = 30
+)
+
+// Likes and ratings
+
+func likeSong(s *Session, args Args) (*Result, error) {
+	return setLike(args, app.Liked, "Liked %s")
+}
+
+func unlikeSong(s *Session, args Args) (*Result, error) {
+	return setLike(args, app.Neutral, "Unliked %s")
+}
+
+func dislikeSong(s *Session, args Args) (*Result, error) {
+	return setLike(args, app.Disliked, "Disliked %s")
+}
+
+func setLike(args Args, likeState app.LikeState, msg string) (*Result, error) {
+	audios, err := queueAudioArgs(args, "indices")
+	if err != nil {
+		return nil, err
+	}
+	for _, audio := range audios {
+		if err := app.AudioDb().SetLike(audio.AudioBasic, likeState); err != nil {
+			return nil, err
+		}
+	}
+	return message(msg, describeAudios(audios)), nil
+}
+
+func rateSong(s *Session, args Args) (*Result, error) {
+	rating, err := args.Int("stars", 0)
+	if err != nil {
+		return nil, err
+	}
+	audios, err := queueAudioArgs(args, "indices")
+	if err != nil {
+		return nil, err
+	}
+	for _, audio := range audios {
+		if err := app.AudioDb().SetRating(audio.AudioBasic, rating); err != nil {
+			return nil, err
+		}
+	}
+	return message("Rated %s %s", describeAudios(audios), strconv.Itoa(rating)), nil
+}
+
+func addTag(s *Session, args Args) (*Result, error) {
+	audios, err := queueAudioArgs(args, "indices")
+	if err != nil {
+		return nil, err
+	}
+	for _, audio := range audios {
+		if err := app.AudioDb().AddTag(audio.AudioBasic, args.String("tag")); err != nil {
+			return nil, err
+		}
+	}
+	return message("Tagged %s with %s", describeAudios(audios), args.String("tag")), nil
+}
+
+func removeTag(s *Session, args Args) (*Result, error) {
+	audios, err := queueAudioArgs(args, "indices")
+	if err != nil {
+		return nil, err
+	}
+	for _, audio := range audios {
+		if err := app.AudioDb().RemoveTag(audio.AudioBasic, args.String("tag")); err != nil {
+			return nil, err
+		}
+	}
+	return message("Removed tag %s from %s", args.String("tag"), describeAudios(audios)), nil
+}
+
+// Smart playlists
+
+func saveSmartPlaylist(s *Session, args Args) (*Result, error) {
+	if err := app.AudioDb().SaveSmartPlaylist(args.String("name"), args.String("query")); err != nil {
+		return nil, err
+	}
+	return message("Saved smart playlist %s", args.String("name")), nil
+}
+
+func removeSmartPlaylist(s *Session, args Args) (*Result, error) {
+	if err := app.AudioDb().DeleteSmartPlaylist(args.String("name")); err != nil {
+		return nil, err
+	}
+	return message("Removed smart playlist %s", args.String("name")), nil
+}
+
+func listSmartPlaylists(s *Session, args Args) (*Result, error) {
+	playlists, err := app.AudioDb().GetSmartPlaylists()
+	if err != nil {
+		return nil, err
+	}
+
+	table := &Table{Title: "Smart Playlists"}
+	for i, playlist := range playlists {
+		table.Rows = append(table.Rows, Row{Index: i + 1, Cells: []Cell{{Text: playlist.Name, Width: 20}, {Text: playlist.Query}}})
+	}
+	return &Result{Table: table}, nil
+}
+
+func loadSmartPlaylist(s *Session, args Args) (*Result, error) {
+	name := args.String("name")
+	audDocs, err := app.AudioDb().GetSmartPlaylistAudio(name, 0, -1)
+	if err != nil {
+		return nil, err
+	}
+	if len(audDocs) == 0 {
+		return nil, Warn("No songs match the smart playlist")
+	}
+
+	isPiped := s.IsPiped
+	go func() {
+		defer app.HandlePanic()
+		for _, audDoc := range audDocs {
+			audio, err := app.GetSong(isPiped)(audDoc.YtId, true)
+			if err != nil {
+				continue
+			}
+			app.MediaPlayer().AppendAudio(audio)
+			if !app.MediaPlayer().IsPlaying() {
+				app.MediaPlayer().StartPlayback()
+			}
+		}
+	}()
+	return message("Queueing %s songs from %s", strconv.Itoa(len(audDocs)), name), nil
+}
+
+// Library lists
+
+func fetchSongList(s *Session, args Args) (*Result, error) {
+	criteria := args.String("criteria/smart playlist")
+
+	table := &Table{Title: criteria}
+	switch criteria {
+	case "recent":
+		table.Title = "Recently Played"
+	case "plays":
+		table.Title = "Most Played"
+	case "likes":
+		table.Title = "Most Liked"
+	}
+
+	limit, err := args.Int("--limit", songListSize)
+	if err != nil {
+		return nil, err
+	}
+
+	audDocs, err := app.AudioDb().GetNamedAudioList(criteria, 0, limit)
+	if err != nil {
+		return nil, err
+	}
+	for i, audDoc := range audDocs {
+		table.Rows = append(table.Rows, audioRow(i, audDoc.AudioBasic, 30, ""))
+	}
+	return &Result{Table: table}, nil
+}
+
+func displayHistory(s *Session, args Args) (*Result, error) {
+	dateRange := args.String("date|range")
+	page, err := args.Int("page", 1)
+	if err != nil {
+		return nil, err
+	}
+	// a single number is the page of today
+	if p, err := strconv.Atoi(dateRange); err == nil && args.String("page") == "" {
+		dateRange, page = "", p
+	}
+	if page < 1 {
+		return nil, errors.New("invalid page number")
+	}
+
+	from, to, err := app.ParseHistoryRange(dateRange)
+	if err != nil {
+		return nil, err
+	}
+
+	events, err := app.AudioDb().GetPlayEvents(from, to, (page-1)*historyPageSize, historyPageSize)
+	if err != nil {
+		return nil, err
+	}
+	if len(events) == 0 {
+		return nil, Warn("No history found")
+	}
+
+	table := &Table{Title: "History"}
+	for _, session := range app.GroupPlaySessions(events) {
+		table.Rows = append(table.Rows, Row{Highlight: true, Cells: []Cell{
+			{Text: session.Start.Format("2006-01-02 15:04") + " - " + session.End.Format("15:04")},
+			{Text: fmt.Sprintf("%d songs", len(session.Events))},
+			{Text: app.GetFormattedTime(session.Listened) + " listened"},
+		}})
+		for _, event := range session.Events {
+			table.Rows = append(table.Rows, Row{Indent: 2, Cells: []Cell{
+				{Text: event.StartTime.Format("15:04")},
+				{Text: event.Title, Width: 30},
+				{Text: event.Uploader, Width: 20},
+				{Text: app.GetFormattedTime(event.Listened), Width: 8, Right: true},
+				{Text: string(event.Status)},
+			}})
+		}
+	}
+
+	next := strings.TrimSpace(fmt.Sprintf("history %s %d", dateRange, page+1))
+	table.Rows = append(table.Rows, Row{Cells: []Cell{{Text: fmt.Sprintf("page %d, enter %s for more", page, next), Style: Muted}}})
+	return &Result{Table: table}, nil
+}
+
+func displayStats(s *Session, args Args) (*Result, error) {
+	view, period := "summary", ""
+	for _, field := range []string{args.String("view"), args.String("period")} {
+		switch field {
+		case "":
+		case "summary", "tracks", "uploaders", "hours":
+			view = field
+		default:
+			period = field
+		}
+	}
+
+	stats, err := app.GetListeningStats(period, statsListSize)
+	if err != nil {
+		return nil, err
+	}
+
+	table := &Table{Title: "Stats", Rows: []Row{{Highlight: true, Cells: []Cell{{Text: "Stats for " + stats.Period}}}}}
+	switch view {
+	case "summary":
+		for _, item := range [][2]string{
+			{"Plays", strconv.Itoa(stats.TotalPlays)},
+			{"Listened", app.GetFormattedTime(stats.TotalListened)},
+			{"Current streak", fmt.Sprintf("%d days", stats.CurrentStreak)},
+			{"Longest streak", fmt.Sprintf("%d days", stats.LongestStreak)},
+		} {
+			table.Rows = append(table.Rows, Row{Cells: []Cell{{Text: item[0], Width: 20}, {Text: item[1], Style: Accent}}})
+		}
+	case "tracks":
+		for i, track := range stats.TopTracks {
+			table.Rows = append(table.Rows, Row{Index: i + 1, Cells: []Cell{
+				{Text: track.Title, Width: 30}, {Text: track.Uploader, Width: 20},
+				{Text: fmt.Sprintf("%5d plays", track.Plays)}, {Text: app.GetFormattedTime(track.Listened), Width: 10, Right: true},
+			}})
+		}
+	case "uploaders":
+		for i, uploader := range stats.TopUploaders {
+			table.Rows = append(table.Rows, Row{Index: i + 1, Cells: []Cell{
+				{Text: uploader.Uploader, Width: 30},
+				{Text: fmt.Sprintf("%5d plays", uploader.Plays)}, {Text: app.GetFormattedTime(uploader.Listened), Width: 10, Right: true},
+			}})
+		}
+	case "hours":
+		maxListened := 1
+		for _, listened := range stats.HourlyListened {
+			if listened > maxListened {
+				maxListened = listened
+			}
+		}
+		for hour, listened := range stats.HourlyListened {
+			table.Rows = append(table.Rows, Row{Cells: []Cell{
+				{Text: fmt.Sprintf("%02d:00", hour)},
+				{Text: strings.Repeat("#", listened*statsBarWidth/maxListened), Width: statsBarWidth, Style: Emphasis},
+				{Text: app.GetFormattedTime(listened)},
+			}})
+		}
+	}
+	return &Result{Table: table}, nil
+}
+
+func exportStats(s *Session, args Args) (*Result, error) {
+	stats, err := app.GetListeningStats(args.String("period"), 0)
+	if err != nil {
+		return nil, err
+	}
+	if err := stats.ExportFile(args.String("json|csv"), args.String("file")); err != nil {
+		return nil, err
+	}
+	return message("Stats exported to %s", args.String("file")), nil
+}
+
+// Cache and library files
+
+func verifyCache(s *Session, args Args) (*Result, error) {
+	report, err := app.AudioCache().VerifyCache(true)
+	if err != nil {
+		return nil, err
+	}
+	return message("Checked %s, removed %s, re-downloading %s",
+		strconv.Itoa(report.Checked), strconv.Itoa(len(report.Removed)), strconv.Itoa(report.Requeued)), nil
+}
+
+func exportCache(s *Session, args Args) (*Result, error) {
+	exportDir := args.String("dir")
+	count, err := app.ExportCache(exportDir)
+	if err != nil {
+		return nil, err
+	}
+	return message("Exported %s songs to %s", strconv.Itoa(count), exportDir), nil
+}
+
+func exportDb(s *Session, args Args) (*Result, error) {
+	if err := app.ExportDb(args.String("file")); err != nil {
+		return nil, err
+	}
+	return message("Library exported to %s", args.String("file")), nil
+}
+
+func importDb(s *Session, args Args) (*Result, error) {
+	report, err := app.ImportDb(args.String("file"), args.Bool("--merge"))
+	if err != nil {
+		return nil, err
+	}
+	return message("Imported %s new and merged %s songs from %s",
+		strconv.Itoa(report.Added), strconv.Itoa(report.Merged), args.String("file")), nil
+}
+
+func backupDb(s *Session, args Args) (*Result, error) {
+	backupPath, err := app.BackupDb()
+	if err != nil {
+		return nil, err
+	}
+	return message("Backup created at %s", backupPath), nil
+}
+
+// Scrobbles and lyrics
+
+func displayScrobbleStatus(s *Session, args Args) (*Result, error) {
+	statusList, err := app.GetScrobbleStatus()
+	if err != nil {
+		return nil, err
+	}
+	if len(statusList) == 0 {
+		return nil, Warn("No scrobbler configured")
+	}
+
+	formats := make([]string, len(statusList))
+	values := make([]string, len(statusList))
+	for i, status := range statusList {
+		formats[i] = status.Service + ": %s listens pending"
+		values[i] = strconv.Itoa(status.Pending)
+	}
+	return message(strings.Join(formats, " | "), values...), nil
+}
+
+// returns the lyrics of the current song, with a warning if none are found
+func showLyrics(s *Session, args Args) (*Result, error) {
+	audState := app.MediaPlayer().GetAudioState()
+	if audState.YtId == "" {
+		return nil, Warn("No song is playing")
+	}
+
+	result := &Result{Lyrics: &LyricsView{Audio: audState.AudioBasic}}
+	lyrics, err := app.GetLyrics(audState.AudioBasic)
+	if errors.Is(err, app.ErrNoLyrics) {
+		return result, Warn("No lyrics found")
+	}
+	if err != nil {
+		return result, err
+	}
+
+	result.Lyrics.Lyrics = lyrics
+	return result, nil
+}
@@ -0,0 +1,187 @@
+package commands
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// ArgType is the type of an argument or flag value, validated when the input is parsed
+type ArgType uint8
+
+const (
+	Text      ArgType = iota
+	Number            // ex: 10
+	Index             // a queue position from 1, negative counts from the end, ex: 3, -1
+	IndexList         // queue positions, ranges and lists, ex: 3-7, 2,4,6
+)
+
+func (argType ArgType) expected() string {
+	switch argType {
+	case Number:
+		return "a number"
+	case Index:
+		return "a position like 3 or -1"
+	case IndexList:
+		return "positions like 3, -1, 3-7 or 2,4,6"
+	}
+	return "text"
+}
+
+// returns an error if the value is not of the type
+func (argType ArgType) validate(name string, value string) error {
+	var err error
+	switch argType {
+	case Number:
+		_, err = strconv.Atoi(value)
+	case Index:
+		_, err = parsePosition(value)
+	case IndexList:
+		_, err = parseIndexList(value)
+	}
+	if err != nil {
+		return fmt.Errorf("invalid %s %q, expected %s", name, value, argType.expected())
+	}
+	return nil
+}
+
+// tokenize splits the input into words like a shell. Words are separated by spaces,
+// quotes group words and a backslash escapes the next character, ex: smart save "my mix" tag=chill
+func tokenize(input string) ([]string, error) {
+	var tokens []string
+	var token strings.Builder
+	var quote rune
+	inToken, escaped := false, false
+
+	for _, r := range input {
+		switch {
+		case escaped:
+			token.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped, inToken = true, true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				token.WriteRune(r)
+			}
+		case r == '"' || r == '\'':
+			quote, inToken = r, true
+		case unicode.IsSpace(r):
+			if inToken {
+				tokens = append(tokens, token.String())
+				token.Reset()
+				inToken = false
+			}
+		default:
+			token.WriteRune(r)
+			inToken = true
+		}
+	}
+
+	if quote != 0 {
+		return nil, Warn(fmt.Sprintf("unterminated %c quote", quote))
+	}
+	if escaped {
+		return nil, Warn("nothing to escape at the end of input")
+	}
+	if inToken {
+		tokens = append(tokens, token.String())
+	}
+	return tokens, nil
+}
+
+// Queue positions
+
+// positions entered from 1, negative positions count from the end of the queue
+type positionRange struct {
+	from, to int
+}
+
+// parses a list of positions and ranges, ex: 2,4,6 or 3-7 or -3--1
+func parseIndexList(value string) ([]positionRange, error) {
+	parts := strings.Split(value, ",")
+	ranges := make([]positionRange, len(parts))
+	for i, part := range parts {
+		// the separator is the first dash after the first character, which may be a minus sign
+		sep := -1
+		if len(part) > 1 {
+			if sep = strings.Index(part[1:], "-"); sep >= 0 {
+				sep++
+			}
+		}
+
+		var err error
+		if sep < 0 {
+			ranges[i].from, err = parsePosition(part)
+			ranges[i].to = ranges[i].from
+		} else {
+			ranges[i].from, err = parsePosition(part[:sep])
+			if err == nil {
+				ranges[i].to, err = parsePosition(part[sep+1:])
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return ranges, nil
+}
+
+func parsePosition(value string) (int, error) {
+	pos, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, err
+	}
+	if pos == 0 {
+		return 0, fmt.Errorf("position starts from 1")
+	}
+	return pos, nil
+}
+
+// returns the index from 0 of the position in a queue of the length
+func resolvePosition(pos int, length int) (int, error) {
+	if length == 0 {
+		return 0, Warn("No songs in queue")
+	}
+
+	index := pos - 1
+	if pos < 0 {
+		index = length + pos
+	}
+	if index < 0 || index >= length {
+		return 0, Warn(fmt.Sprintf("position %d out of range (1-%d)", pos, length))
+	}
+	return index, nil
+}
+
+// returns the sorted unique indices from 0 of the ranges in a queue of the length
+func resolveIndexList(ranges []positionRange, length int) ([]int, error) {
+	seen := make(map[int]bool)
+	var indices []int
+	for _, r := range ranges {
+		from, err := resolvePosition(r.from, length)
+		if err != nil {
+			return nil, err
+		}
+		to, err := resolvePosition(r.to, length)
+		if err != nil {
+			return nil, err
+		}
+		if from > to {
+			from, to = to, from
+		}
+
+		for index := from; index <= to; index++ {
+			if !seen[index] {
+				seen[index] = true
+				indices = append(indices, index)
+			}
+		}
+	}
+	sort.Ints(indices)
+	return indices, nil
+}
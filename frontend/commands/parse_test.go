@@ -0,0 +1,148 @@
+package commands
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		input  string
+		tokens []string
+		err    string
+	}{
+		{"", nil, ""},
+		{"   ", nil, ""},
+		{"play get lucky", []string{"play", "get", "lucky"}, ""},
+		{"  skip\t 3  ", []string{"skip", "3"}, ""},
+		{`smart save "my mix" tag=chill`, []string{"smart", "save", "my mix", "tag=chill"}, ""},
+		{`play 'rock n roll'`, []string{"play", "rock n roll"}, ""},
+		{`tag add "" 3`, []string{"tag", "add", "", "3"}, ""},
+		{`a"b c"d`, []string{"ab cd"}, ""},
+		{`play don\'t`, []string{"play", "don't"}, ""},
+		{`play my\ mix`, []string{"play", "my mix"}, ""},
+		{`"say \"hi\""`, []string{`say "hi"`}, ""},
+		{`'back\slash'`, []string{`back\slash`}, ""},
+		{`\\`, []string{`\`}, ""},
+		{`play "get lucky`, nil, `unterminated " quote`},
+		{`play 'get lucky`, nil, `unterminated ' quote`},
+		{`play 'it\'s'`, nil, `unterminated ' quote`},
+		{`play lucky\`, nil, "nothing to escape at the end of input"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			tokens, err := tokenize(tt.input)
+			if tt.err != "" {
+				if !errors.As(err, &ErrWarn{}) || err.Error() != tt.err {
+					t.Fatalf("err = %v, want warning %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("tokenize: %v", err)
+			}
+			if !reflect.DeepEqual(tokens, tt.tokens) {
+				t.Errorf("tokens = %q, want %q", tokens, tt.tokens)
+			}
+		})
+	}
+}
+
+func TestParseIndexList(t *testing.T) {
+	tests := []struct {
+		value   string
+		length  int
+		indices []int
+	}{
+		{"3", 10, []int{2}},
+		{"-1", 10, []int{9}},
+		{"3-7", 10, []int{2, 3, 4, 5, 6}},
+		{"7-3", 10, []int{2, 3, 4, 5, 6}},
+		{"-3--1", 10, []int{7, 8, 9}},
+		{"8--1", 10, []int{7, 8, 9}},
+		{"2,4,6", 10, []int{1, 3, 5}},
+		{"6,2,4,2", 10, []int{1, 3, 5}},
+		{"1-3,2-4,-1", 10, []int{0, 1, 2, 3, 9}},
+		{"1", 1, []int{0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			ranges, err := parseIndexList(tt.value)
+			if err != nil {
+				t.Fatalf("parse: %v", err)
+			}
+			indices, err := resolveIndexList(ranges, tt.length)
+			if err != nil {
+				t.Fatalf("resolve: %v", err)
+			}
+			if !reflect.DeepEqual(indices, tt.indices) {
+				t.Errorf("indices = %v, want %v", indices, tt.indices)
+			}
+		})
+	}
+}
+
+func TestParseIndexListInvalid(t *testing.T) {
+	for _, value := range []string{"", "0", "1-0", "0-3", "a", "3-", "3-a", "2,,4", "2;4", "1.5", "--1"} {
+		if ranges, err := parseIndexList(value); err == nil {
+			t.Errorf("parseIndexList(%q) = %v, want an error", value, ranges)
+		}
+	}
+}
+
+func TestResolveIndexListOutOfRange(t *testing.T) {
+	tests := []struct {
+		value  string
+		length int
+		err    string
+	}{
+		{"1", 0, "No songs in queue"},
+		{"11", 10, "position 11 out of range (1-10)"},
+		{"-11", 10, "position -11 out of range (1-10)"},
+		{"8-12", 10, "position 12 out of range (1-10)"},
+		{"2,4,12", 10, "position 12 out of range (1-10)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			ranges, err := parseIndexList(tt.value)
+			if err != nil {
+				t.Fatalf("parse: %v", err)
+			}
+			_, err = resolveIndexList(ranges, tt.length)
+			if !errors.As(err, &ErrWarn{}) || err.Error() != tt.err {
+				t.Errorf("err = %v, want warning %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestArgTypeValidate(t *testing.T) {
+	tests := []struct {
+		argType ArgType
+		value   string
+		err     string
+	}{
+		{Text, "anything", ""},
+		{Number, "10", ""},
+		{Number, "ten", `invalid value "ten", expected a number`},
+		{Index, "-1", ""},
+		{Index, "0", `invalid value "0", expected a position like 3 or -1`},
+		{Index, "3-7", `invalid value "3-7", expected a position like 3 or -1`},
+		{IndexList, "3-7", ""},
+		{IndexList, "0", `invalid value "0", expected positions like 3, -1, 3-7 or 2,4,6`},
+	}
+
+	for _, tt := range tests {
+		err := tt.argType.validate("value", tt.value)
+		if tt.err == "" && err != nil {
+			t.Errorf("validate(%d, %q): %v", tt.argType, tt.value, err)
+		}
+		if tt.err != "" && (err == nil || err.Error() != tt.err) {
+			t.Errorf("validate(%d, %q) = %v, want %q", tt.argType, tt.value, err, tt.err)
+		}
+	}
+}
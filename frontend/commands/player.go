@@ -0,0 +1,328 @@
+package commands
+
+import (
+	"strconv"
+
+	"github.com/johnrijoy/ludo-go/app"
+)
+
+const (
+	defaultForwardRewind = 10
+	searchListSize       = 10
+	findListSize         = 20
+	radioListSize        = 10
+	selectPrompt         = "> Enter index number (q to escape): "
+)
+
+// Audio Search
+
+func appendPlay(s *Session, args Args) (*Result, error) {
+	var audTitle string
+	if query := args.String("song name"); query != "" {
+		audio, err := s.getSong(query, args.Bool("--all"))
+		if err != nil {
+			return nil, err
+		}
+		if err := app.MediaPlayer().AppendAudio(audio); err != nil {
+			return nil, err
+		}
+		audTitle = audio.Title
+	}
+
+	if len(app.MediaPlayer().GetQueue()) < 1 {
+		return nil, Warn("No songs in queue")
+	}
+
+	if app.MediaPlayer().IsPlaying() {
+		return message("Added %s", audTitle), nil
+	}
+	if err := app.MediaPlayer().StartPlayback(); err != nil {
+		return nil, err
+	}
+	return message("Playing %s", audTitle), nil
+}
+
+func radioPlay(s *Session, args Args) (*Result, error) {
+	query := args.String("song name")
+
+	var audio *app.AudioDetails
+	if query == "." {
+		audioD := app.MediaPlayer().GetAudioState().AudioDetails
+		if audioD.YtId == "" {
+			return nil, Warn("No song is playing")
+		}
+		audio = &audioD
+		if err := app.MediaPlayer().RemoveAllAudioFromIndex(app.MediaPlayer().GetQueueIndex() + 1); err != nil {
+			return nil, err
+		}
+	} else {
+		var err error
+		audio, err = s.getSong(query, args.Bool("--all"))
+		if err != nil {
+			return nil, err
+		}
+		if err := app.MediaPlayer().ResetPlayer(); err != nil {
+			return nil, err
+		}
+		app.MediaPlayer().AppendAudio(audio)
+		app.MediaPlayer().StartPlayback()
+	}
+
+	isPiped := s.IsPiped
+	go func() {
+		defer app.HandlePanic()
+		audioList, err := app.GetPlayList(isPiped)(audio.YtId, true, 1, radioListSize)
+		if err != nil {
+			return
+		}
+		for _, audio := range *audioList {
+			app.MediaPlayer().AppendAudio(&audio)
+		}
+	}()
+
+	return message("Starting radio from %s", audio.Title), nil
+}
+
+func searchPlay(s *Session, args Args) (*Result, error) {
+	limit, err := args.Int("--limit", searchListSize)
+	if err != nil {
+		return nil, err
+	}
+
+	resetFilter := s.allFilter(args.Bool("--all"))
+	audioBasicList, err := app.GetSearchList(s.IsPiped)(args.String("song name"), 0, limit)
+	resetFilter()
+	if err != nil {
+		return nil, err
+	}
+
+	choice := &Choice{Name: "search", Prompt: selectPrompt}
+	for i, audio := range *audioBasicList {
+		choice.Rows = append(choice.Rows, audioRow(i, audio, 30, ""))
+	}
+	choice.OnSelect = func(index int) (*Result, error) {
+		return s.queueAudio((*audioBasicList)[index].YtId)
+	}
+	return &Result{Choice: choice}, nil
+}
+
+// searches the songs in library and queues the selected one
+func findPlay(s *Session, args Args) (*Result, error) {
+	limit, err := args.Int("--limit", findListSize)
+	if err != nil {
+		return nil, err
+	}
+
+	audDocs, err := app.AudioDb().FindAudio(args.String("text"), limit)
+	if err != nil {
+		return nil, err
+	}
+	if len(audDocs) == 0 {
+		return nil, Warn("No songs found in library")
+	}
+
+	choice := &Choice{Name: "find", Prompt: selectPrompt}
+	for i, audDoc := range audDocs {
+		choice.Rows = append(choice.Rows, audioRow(i, audDoc.AudioBasic, 30, ""))
+	}
+	choice.OnSelect = func(index int) (*Result, error) {
+		return s.queueAudio(audDocs[index].YtId)
+	}
+	return &Result{Choice: choice}, nil
+}
+
+// media queue control
+
+func displayQueue(s *Session, args Args) (*Result, error) {
+	audList := app.MediaPlayer().GetQueue()
+	qIndex := app.MediaPlayer().GetQueueIndex()
+	if len(audList) == 0 {
+		return nil, Warn("No songs in queue")
+	}
+
+	ytIds := make([]string, len(audList))
+	for i, audio := range audList {
+		ytIds[i] = audio.YtId
+	}
+	markers := app.GetRatingMarkers(ytIds...)
+
+	table := &Table{Title: "Queue"}
+	for i, audio := range audList {
+		row := audioRow(i, audio.AudioBasic, 45, markers[audio.YtId])
+		row.Highlight = i == qIndex
+		table.Rows = append(table.Rows, row)
+	}
+	return &Result{Table: table}, nil
+}
+
+func displayCurrentSong(s *Session, args Args) (*Result, error) {
+	player := app.MediaPlayer()
+	audState := player.GetAudioState()
+	position, length := player.GetMediaPosition()
+
+	return &Result{Current: &Current{
+		Audio:     audState.AudioBasic,
+		State:     player.FetchPlayerState(),
+		IsPlaying: player.IsPlaying(),
+		IsError:   player.CheckMediaError(),
+		Position:  position,
+		Length:    length,
+		Rating:    app.GetRatingMarkers(audState.YtId)[audState.YtId],
+		Format:    audState.StreamFormat.String(),
+	}}, nil
+}
+
+func removeAllIndex(s *Session, args Args) (*Result, error) {
+	trackIndex, err := args.QueueIndex("index", app.MediaPlayer().GetQueueIndex()+1, len(app.MediaPlayer().GetQueue()))
+	if err != nil {
+		return nil, err
+	}
+	if err := app.MediaPlayer().RemoveAllAudioFromIndex(trackIndex); err != nil {
+		return nil, err
+	}
+	return message("Removed all from index %s", strconv.Itoa(trackIndex+1)), nil
+}
+
+func removeIndex(s *Session, args Args) (*Result, error) {
+	queueLen := len(app.MediaPlayer().GetQueue())
+	trackIndices, err := args.QueueIndices("indices", queueLen-1, queueLen)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := app.MediaPlayer().RemoveAudioAtIndices(trackIndices); err != nil {
+		return nil, err
+	}
+
+	if len(trackIndices) == 1 {
+		return message("Removed from index %s", strconv.Itoa(trackIndices[0]+1)), nil
+	}
+	return message("Removed %s songs", strconv.Itoa(len(trackIndices))), nil
+}
+
+func skipIndex(s *Session, args Args) (*Result, error) {
+	trackIndex, err := args.QueueIndex("index", app.MediaPlayer().GetQueueIndex()+1, len(app.MediaPlayer().GetQueue()))
+	if err != nil {
+		return nil, err
+	}
+	if err := app.MediaPlayer().SkipToIndex(trackIndex); err != nil {
+		return nil, err
+	}
+	return message("Skipping to index %s", strconv.Itoa(trackIndex+1)), nil
+}
+
+func skipPrevious(s *Session, args Args) (*Result, error) {
+	if err := app.MediaPlayer().SkipToPrevious(); err != nil {
+		return nil, err
+	}
+	return message("Previous song"), nil
+}
+
+func skipNext(s *Session, args Args) (*Result, error) {
+	if err := app.MediaPlayer().SkipToNext(); err != nil {
+		return nil, err
+	}
+	return message("Next song"), nil
+}
+
+// media playback control
+
+func pauseResume(s *Session, args Args) (*Result, error) {
+	return nil, app.MediaPlayer().PauseResume()
+}
+
+func audioRewind(s *Session, args Args) (*Result, error) {
+	duration, err := args.Int("seconds", defaultForwardRewind)
+	if err != nil {
+		return nil, err
+	}
+	return nil, app.MediaPlayer().RewindBySeconds(duration)
+}
+
+func audioForward(s *Session, args Args) (*Result, error) {
+	duration, err := args.Int("seconds", defaultForwardRewind)
+	if err != nil {
+		return nil, err
+	}
+	return nil, app.MediaPlayer().ForwardBySeconds(duration)
+}
+
+func resetPlayer(s *Session, args Args) (*Result, error) {
+	if err := app.MediaPlayer().ResetPlayer(); err != nil {
+		return nil, err
+	}
+	return message("Resetting playlist"), nil
+}
+
+func modifyVolume(s *Session, args Args) (*Result, error) {
+	vol, err := args.Int("volume", 0)
+	if err != nil {
+		return nil, err
+	}
+	if err := app.MediaPlayer().SetVol(vol); err != nil {
+		return nil, err
+	}
+	return message("Volume set: %s", strconv.Itoa(vol)), nil
+}
+
+// Helpers
+
+// fetches the audio by id and appends it to the queue, the playback is started if not playing
+func (s *Session) queueAudio(ytId string) (*Result, error) {
+	audio, err := app.GetSong(s.IsPiped)(ytId, true)
+	if err != nil {
+		return nil, err
+	}
+	if err := app.MediaPlayer().AppendAudio(audio); err != nil {
+		return nil, err
+	}
+
+	resMsg := "Added %s"
+	if !app.MediaPlayer().IsPlaying() {
+		app.MediaPlayer().StartPlayback()
+		resMsg = "Playing %s"
+	}
+	return message(resMsg, audio.Title), nil
+}
+
+func (s *Session) getSong(query string, all bool) (*app.AudioDetails, error) {
+	defer s.allFilter(all)()
+	return app.GetSong(s.IsPiped)(query, false)
+}
+
+// the --all flag searches all videos on piped, not only music.
+// Returns a func to reset the filter
+func (s *Session) allFilter(all bool) func() {
+	if !s.IsPiped || !all {
+		return func() {}
+	}
+
+	app.SetPipedAllFilterType(true)
+	return func() { app.SetPipedAllFilterType(false) }
+}
+
+// returns the audios of the queue at the positions argument, default is the current audio
+func queueAudioArgs(args Args, name string) ([]app.AudioDetails, error) {
+	queue := app.MediaPlayer().GetQueue()
+	trackIndices, err := args.QueueIndices(name, app.MediaPlayer().GetQueueIndex(), len(queue))
+	if err != nil {
+		return nil, err
+	}
+
+	audios := make([]app.AudioDetails, len(trackIndices))
+	for i, trackIndex := range trackIndices {
+		if trackIndex < 0 || trackIndex >= len(queue) {
+			return nil, app.ErrInvalidIndex
+		}
+		audios[i] = queue[trackIndex]
+	}
+	return audios, nil
+}
+
+// returns the title of the audio, or the count when there are more
+func describeAudios(audios []app.AudioDetails) string {
+	if len(audios) == 1 {
+		return audios[0].Title
+	}
+	return strconv.Itoa(len(audios)) + " songs"
+}
@@ -0,0 +1,97 @@
+package commands
+
+// flags shared by the commands
+var (
+	allFlag   = Flag{Name: "all"}
+	limitFlag = Flag{Name: "limit", Value: "n", Type: Number}
+)
+
+// registry of the commands, in the order shown in help
+var registry = []*Command{
+	{Name: "play", Aliases: []string{"add"}, Args: []Arg{{Name: "song name", Optional: true, Rest: true}}, Flags: []Flag{allFlag},
+		Help: "play the song, --all searches all videos on piped", Run: appendPlay},
+	{Name: "search", Aliases: []string{"s"}, Args: []Arg{{Name: "song name", Rest: true}}, Flags: []Flag{allFlag, limitFlag},
+		Help: "search the song and display search result, --all searches all videos on piped", Run: searchPlay},
+	{Name: "find", Args: []Arg{{Name: "text", Rest: true}}, Flags: []Flag{limitFlag},
+		Help: "search the songs played before by title or uploader, without a network search", Run: findPlay},
+	{Name: "radio", Args: []Arg{{Name: "song name", Rest: true}}, Flags: []Flag{allFlag},
+		Help: "start radio for song, . for the current song", Run: radioPlay},
+	{Name: "pause", Aliases: []string{"resume", "p"}, Help: "toggle pause/resume", Run: pauseResume},
+	{Name: "showq", Aliases: []string{"q"}, Help: "display song queue", Run: displayQueue},
+	{Name: "curr", Aliases: []string{"c"}, Help: "display current song", Run: displayCurrentSong},
+	{Name: "skipn", Aliases: []string{"n"}, Help: "skip to next song", Run: skipNext},
+	{Name: "skipb", Aliases: []string{"b"}, Help: "skip to previous song", Run: skipPrevious},
+	{Name: "skip", Args: []Arg{{Name: "index", Type: Index, Optional: true}},
+		Help: "skip to the specified index, negative counts from the end (-1 is last), default is next", Run: skipIndex},
+	{Name: "remove", Aliases: []string{"rem"}, Args: []Arg{{Name: "indices", Type: IndexList, Optional: true}},
+		Help: "remove songs at the indices, ex: 3, 3-7 or 2,4,6, default is last", Run: removeIndex},
+	{Name: "removeAll", Aliases: []string{"reml"}, Args: []Arg{{Name: "index", Type: Index, Optional: true}},
+		Help: "remove all songs starting from the specified index, default is current+1", Run: removeAllIndex},
+	{Name: "forward", Aliases: []string{"f"}, Args: []Arg{{Name: "seconds", Type: Number, Optional: true}},
+		Help: "forwards playback by 10s", Run: audioForward},
+	{Name: "rewind", Aliases: []string{"r"}, Args: []Arg{{Name: "seconds", Type: Number, Optional: true}},
+		Help: "rewinds playback by 10s", Run: audioRewind},
+	{Name: "setVol", Aliases: []string{"v"}, Args: []Arg{{Name: "volume", Type: Number}},
+		Help: "sets the volume by amount (0-100)", Run: modifyVolume},
+	{Name: "stop", Help: "resets the player", Run: resetPlayer},
+	{Name: "like", Args: []Arg{{Name: "indices", Type: IndexList, Optional: true}},
+		Help: "like the songs at indices, default is current", Run: likeSong},
+	{Name: "unlike", Args: []Arg{{Name: "indices", Type: IndexList, Optional: true}},
+		Help: "remove like or dislike of the songs at indices, default is current", Run: unlikeSong},
+	{Name: "dislike", Args: []Arg{{Name: "indices", Type: IndexList, Optional: true}},
+		Help: "dislike the songs at indices, disliked songs are skipped in radio", Run: dislikeSong},
+	{Name: "rate", Args: []Arg{{Name: "stars", Type: Number}, {Name: "indices", Type: IndexList, Optional: true}},
+		Help: "rate the songs at indices with 1-5 stars, 0 clears rating", Run: rateSong},
+	{Name: "tag", Help: "add or remove a tag of the songs at indices, default is current", Subcommands: []*Command{
+		{Name: "add", Args: []Arg{{Name: "tag"}, {Name: "indices", Type: IndexList, Optional: true}}, Help: "add the tag", Run: addTag},
+		{Name: "rm", Args: []Arg{{Name: "tag"}, {Name: "indices", Type: IndexList, Optional: true}}, Help: "remove the tag", Run: removeTag},
+	}},
+	{Name: "smart", Help: "list smart playlists", Run: listSmartPlaylists, Subcommands: []*Command{
+		{Name: "save", Args: []Arg{{Name: "name"}, {Name: "query", Rest: true}},
+			Help: "save a smart playlist, ex: tag=chill AND plays>3 OR lastplay older than 30d", Run: saveSmartPlaylist},
+		{Name: "rm", Args: []Arg{{Name: "name"}}, Help: "remove the smart playlist", Run: removeSmartPlaylist},
+		{Name: "list", Help: "list smart playlists", Run: listSmartPlaylists},
+		{Name: "load", Args: []Arg{{Name: "name"}}, Help: "queue the songs of the smart playlist", Run: loadSmartPlaylist},
+	}},
+	{Name: "listSongs", Aliases: []string{"ls"}, Args: []Arg{{Name: "criteria/smart playlist", Rest: true}}, Flags: []Flag{limitFlag},
+		Help: "displays list of songs based on criteria (recent,likes,plays) or a smart playlist", Run: fetchSongList},
+	{Name: "history", Args: []Arg{{Name: "date|range", Optional: true}, {Name: "page", Type: Number, Optional: true}},
+		Help: "displays played songs grouped by session, for a date (today,yesterday,week,month,2006-01-02) or range (from..to)", Run: displayHistory},
+	{Name: "stats", Args: []Arg{{Name: "view", Optional: true}, {Name: "period", Optional: true}},
+		Help: "displays listening stats (summary,tracks,uploaders,hours) for a period (day,week,month,all)", Run: displayStats, Subcommands: []*Command{
+			{Name: "export", Args: []Arg{{Name: "json|csv"}, {Name: "file"}, {Name: "period", Optional: true}},
+				Help: "exports listening stats of a period as json or csv", Run: exportStats},
+		}},
+	{Name: "cache", Help: "manage the audio cache", Subcommands: []*Command{
+		{Name: "verify", Help: "re-checks cached songs, removes and re-downloads broken ones", Run: verifyCache},
+	}},
+	{Name: "export", Help: "export songs", Subcommands: []*Command{
+		{Name: "cache", Args: []Arg{{Name: "dir", Rest: true}}, Help: "copy cached songs to a directory, named as per export template", Run: exportCache},
+	}},
+	{Name: "db", Help: "manage the library", Subcommands: []*Command{
+		{Name: "export", Args: []Arg{{Name: "file"}}, Help: "export the library to a file", Run: exportDb},
+		{Name: "import", Args: []Arg{{Name: "file"}}, Flags: []Flag{{Name: "merge"}},
+			Help: "import the library from a file, replacing or merging (--merge) the library", Run: importDb},
+		{Name: "backup", Help: "take a backup of the library", Run: backupDb},
+	}},
+	{Name: "lyrics", Help: "display lyrics of the current song, synced lyrics follow the song in the tui", Run: showLyrics},
+	{Name: "scrobble", Help: "submit the queued listens and display the listens pending for each scrobbler", Run: displayScrobbleStatus},
+	{Name: "setSource", Aliases: []string{"ss"}, Args: []Arg{{Name: "youtube/yt|piped/pp", Optional: true}},
+		Help: "set the source for audio searching, displays the current source if not given", Run: setSource},
+	{Name: "checkApi", Help: "check the current piped api", Run: checkApi},
+	{Name: "setApi", Args: []Arg{{Name: "piped api"}}, Help: "set new piped api", Run: modifyApi},
+	{Name: "listApi", Help: "display all available instances", Run: displayApiList},
+	{Name: "randApi", Help: "randomly select an piped instance", Run: modifyApiRandom},
+	{Name: "config", Help: "list the properties and their values, set ones are highlighted", Run: listConfig, Subcommands: []*Command{
+		{Name: "get", Args: []Arg{{Name: "key"}}, Help: "display the value of the property", Run: getConfig},
+		{Name: "set", Args: []Arg{{Name: "key"}, {Name: "value", Rest: true}}, Help: "validate and save the property to ludo.props", Run: setConfig},
+		{Name: "unset", Args: []Arg{{Name: "key"}}, Help: "remove the property from ludo.props, its default is used", Run: unsetConfig},
+		{Name: "list", Help: "list the properties and their values", Run: listConfig},
+		{Name: "validate", Help: "check ludo.props for unknown keys and invalid values", Run: validateConfig},
+	}},
+	{Name: "version", Help: "display application details", Run: displayVersion},
+	{Name: "log", Args: []Arg{{Name: "lines", Type: Number, Optional: true}}, Flags: []Flag{{Name: "level", Value: "level"}},
+		Help: "display the recent log entries, default 20, --level shows entries at or above the level (debug,info,warn,error)", Run: displayLog},
+	{Name: "help", Help: "display help", Run: showHelp},
+	{Name: "quit", Help: "quit application", Run: quit},
+}
@@ -0,0 +1,199 @@
+package commands
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/johnrijoy/ludo-go/app"
+)
+
+// Result is the structured output of a command, rendered by the UIs.
+// A command may return a result along with an error, ex: the lyrics pane with a warning
+type Result struct {
+	Message *Message
+	Table   *Table
+	Choice  *Choice
+	Current *Current
+	Lyrics  *LyricsView
+	Help    bool
+	Quit    bool
+}
+
+// Message is a one line result, the values are highlighted by the UIs
+type Message struct {
+	Format string
+	Values []string
+}
+
+// Table is a list of rows, ex: the song queue or listening stats
+type Table struct {
+	Title string
+	Rows  []Row
+}
+
+type Row struct {
+	Index     int // shown before the cells when set, from 1
+	Indent    int
+	Cells     []Cell
+	Highlight bool // ex: the current song
+}
+
+type Cell struct {
+	Text  string
+	Width int // padded or truncated to the width, 0 to keep as is
+	Right bool
+	Style Style
+}
+
+// Choice is a table from which the user selects a row by index
+type Choice struct {
+	Table
+	Name     string
+	Prompt   string
+	OnSelect func(index int) (*Result, error)
+}
+
+// Current is the state of the current song
+type Current struct {
+	Audio     app.AudioBasic
+	State     int
+	IsPlaying bool
+	IsError   bool
+	Position  int
+	Length    int
+	Rating    string
+	Format    string
+}
+
+// LyricsView is the lyrics of a song, Lyrics is nil if none are found
+type LyricsView struct {
+	Audio  app.AudioBasic
+	Lyrics *app.Lyrics
+}
+
+// Style is the role of a text, mapped to colors by the UIs
+type Style uint8
+
+const (
+	Plain    Style = iota
+	Accent         // highlighted values, ex: song titles in messages
+	Muted          // secondary text, ex: hints
+	Emphasis       // headers, bars and highlighted rows
+	Name           // command and property names in help
+)
+
+// Styler renders the text in the style
+type Styler func(style Style, text string) string
+
+// Select parses the index entered by the user, from 1, and runs the selection. q escapes the choice
+func (choice *Choice) Select(input string) (*Result, error) {
+	if input == "q" {
+		return message(fmt.Sprintf("exiting %s...", choice.Name)), nil
+	}
+
+	index, err := strconv.Atoi(input)
+	if err != nil {
+		return nil, fmt.Errorf("%w '%s', enter a number or q", app.ErrInvalidIndex, input)
+	}
+	index--
+
+	if index < 0 || index >= len(choice.Rows) {
+		return nil, fmt.Errorf("%w, enter a number from 1 to %d", app.ErrInvalidIndex, len(choice.Rows))
+	}
+	return choice.OnSelect(index)
+}
+
+// FormatMessage renders the message with the values highlighted
+func FormatMessage(msg *Message, styler Styler) string {
+	values := make([]interface{}, len(msg.Values))
+	for i, value := range msg.Values {
+		values[i] = styler(Accent, value)
+	}
+	return fmt.Sprintf(msg.Format, values...)
+}
+
+// FormatRow renders the cells of the row separated by |, ex: 1  - title | uploader | 3m20s
+func FormatRow(row Row, styler Styler) string {
+	var s strings.Builder
+	s.WriteString(strings.Repeat(" ", row.Indent))
+	if row.Index > 0 {
+		fmt.Fprintf(&s, "%-2d - ", row.Index)
+	}
+
+	cells := make([]string, len(row.Cells))
+	for i, cell := range row.Cells {
+		text := cell.Text
+		if cell.Width > 0 {
+			text = truncate(text, cell.Width)
+			if cell.Right {
+				text = fmt.Sprintf("%*s", cell.Width, text)
+			} else {
+				text = fmt.Sprintf("%-*s", cell.Width, text)
+			}
+		}
+		if cell.Style != Plain {
+			text = styler(cell.Style, text)
+		}
+		cells[i] = text
+	}
+	s.WriteString(strings.Join(cells, " | "))
+	return s.String()
+}
+
+// HelpLines renders the commands and properties, with their usage
+func HelpLines(styler Styler) []string {
+	lines := []string{"Commands"}
+	for _, cmd := range registry {
+		names := strings.Join(append([]string{cmd.Name}, cmd.Aliases...), ", ")
+		lines = append(lines, formatHelp(styler, names, cmd.Help, joinUsage(cmd.Name, cmd.usage())))
+		for _, sub := range cmd.Subcommands {
+			name := cmd.Name + " " + sub.Name
+			lines = append(lines, formatHelp(styler, name, sub.Help, joinUsage(name, sub.usage())))
+		}
+	}
+
+	lines = append(lines, "", "Properties")
+	for _, config := range app.ConfigSchema() {
+		lines = append(lines, formatHelp(styler, config.Key, config.Help, ""))
+	}
+	return lines
+}
+
+func joinUsage(name string, usage string) string {
+	if usage == "" {
+		return ""
+	}
+	return name + " " + usage
+}
+
+func formatHelp(styler Styler, names string, help string, usage string) string {
+	if usage != "" {
+		help += styler(Emphasis, " | "+usage)
+	}
+	return fmt.Sprintf("%-40s - %s", styler(Name, names), help)
+}
+
+// Result builders
+
+func message(format string, values ...string) *Result {
+	return &Result{Message: &Message{Format: format, Values: values}}
+}
+
+func audioRow(index int, audio app.AudioBasic, width int, marker string) Row {
+	cells := []Cell{{Text: audio.Title, Width: width}, {Text: audio.Uploader, Width: width * 2 / 3}, {Text: audio.GetFormattedDuration()}}
+	if marker != "" {
+		cells = append(cells, Cell{Text: marker, Style: Accent})
+	}
+	return Row{Index: index + 1, Cells: cells}
+}
+
+func truncate(label string, max int) string {
+	if len(label) <= max {
+		return label
+	}
+	if max <= 3 {
+		return label[:max]
+	}
+	return label[0:(max-3)] + "..."
+}
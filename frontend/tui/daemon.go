@@ -0,0 +1,187 @@
+package tui
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+
+	"github.com/johnrijoy/ludo-go/app"
+	"github.com/johnrijoy/ludo-go/frontend/cli"
+	"github.com/johnrijoy/ludo-go/frontend/commands"
+)
+
+var daemonLog = app.NewLogger("daemon")
+
+// commands of all sessions are run one at a time on the player
+var daemonMu sync.Mutex
+
+// will run the player without UI, controlled through the unix socket
+func RunDaemon(socketPath string) error {
+	defer app.Close()
+	if err := app.Init(); err != nil {
+		return err
+	}
+
+	session = commands.NewSession()
+	for _, issue := range app.ConfigWarnings() {
+		daemonLog.Warn("Config issue", "issue", issue.String())
+		fmt.Fprintln(os.Stderr, "Config:", issue)
+	}
+
+	listener, err := listenSocket(socketPath)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(socketPath)
+
+	var once sync.Once
+	shutdown := func() { once.Do(func() { listener.Close() }) }
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sigChan)
+	go func() {
+		defer app.HandlePanic()
+		if _, ok := <-sigChan; ok {
+			shutdown()
+		}
+	}()
+
+	fmt.Println("Ludo daemon listening on", socketPath)
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				daemonLog.Info("Shutting down")
+				return nil
+			}
+			return err
+		}
+		go serveSession(conn, shutdown)
+	}
+}
+
+// listens on the socket, a stale socket of a stopped daemon is removed
+func listenSocket(socketPath string) (net.Listener, error) {
+	if _, err := os.Stat(socketPath); err == nil {
+		if conn, err := net.Dial("unix", socketPath); err == nil {
+			conn.Close()
+			return nil, fmt.Errorf("ludo daemon is already running at %s", socketPath)
+		}
+		if err := os.Remove(socketPath); err != nil {
+			return nil, err
+		}
+	}
+
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(socketPath, 0600); err != nil {
+		listener.Close()
+		return nil, err
+	}
+	return listener, nil
+}
+
+// serves the requests of a client, each client has its own view state
+func serveSession(conn net.Conn, shutdown func()) {
+	defer app.HandlePanic()
+	defer conn.Close()
+	daemonLog.Info("Client connected")
+
+	m := newMainModel()
+	var poller statusPoller
+	reader := bufio.NewReader(conn)
+	enc := json.NewEncoder(conn)
+
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err != nil {
+			if err != io.EOF {
+				daemonLog.Error("Error in reading request", "err", err)
+			}
+			daemonLog.Info("Client disconnected")
+			return
+		}
+
+		var resp *remoteResponse
+		var req remoteRequest
+		if err := json.Unmarshal(line, &req); err != nil {
+			resp = newErrorResponse(fmt.Errorf("invalid request: %w", err))
+		} else {
+			resp = handleRequest(req, &m, &poller)
+		}
+
+		if err := enc.Encode(resp); err != nil {
+			daemonLog.Error("Error in writing response", "err", err)
+			return
+		}
+		if req.Type == requestShutdown {
+			shutdown()
+			return
+		}
+	}
+}
+
+func handleRequest(req remoteRequest, m *mainModel, poller *statusPoller) *remoteResponse {
+	switch req.Type {
+	case requestCommand:
+		daemonMu.Lock()
+		defer daemonMu.Unlock()
+
+		m.err, m.resultMsg = nil, ""
+		if m.mode == interactiveListMode {
+			doInterativeList(req.Input, m)
+		} else {
+			doCommand(req.Input, m)
+		}
+		state := newRemoteState(m)
+		m.quit = false
+		return &remoteResponse{Ok: true, State: state}
+
+	case requestStatus:
+		return &remoteResponse{Ok: true, Status: newRemoteStatus(poller.poll())}
+
+	case requestLyrics:
+		if req.Audio == nil {
+			return newErrorResponse(errors.New("no audio given"))
+		}
+		lyrics, err := app.GetLyrics(*req.Audio)
+		if err != nil {
+			return newErrorResponse(err)
+		}
+		return &remoteResponse{Ok: true, Lyrics: lyrics}
+
+	case requestCli:
+		daemonMu.Lock()
+		defer daemonMu.Unlock()
+
+		result, text, err := cli.Exec(req.Args)
+		if err != nil {
+			resp := newErrorResponse(err)
+			resp.ExitCode = cli.ExitCode(err)
+			return resp
+		}
+		data, err := json.Marshal(result)
+		if err != nil {
+			resp := newErrorResponse(err)
+			resp.ExitCode = cli.ExitError
+			return resp
+		}
+		return &remoteResponse{Ok: true, Result: data, Text: text}
+
+	case requestShutdown:
+		return &remoteResponse{Ok: true}
+
+	default:
+		return newErrorResponse(errors.New("invalid request type: " + req.Type))
+	}
+}
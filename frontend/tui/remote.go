@@ -0,0 +1,290 @@
+package tui
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/johnrijoy/ludo-go/app"
+	"github.com/johnrijoy/ludo-go/frontend/commands"
+)
+
+// The daemon protocol is line-delimited json over a unix socket.
+// Each request line is answered by one response line, in order.
+//
+//	{"type": "command", "input": "play song name"}  runs a command, as entered in the tui
+//	{"type": "status"}                               returns the player status
+//	{"type": "lyrics", "audio": {...}}               returns the lyrics of the audio
+//	{"type": "cli", "args": ["queue"]}               runs a subcommand, returns its result and text
+//	{"type": "shutdown"}                             stops the daemon
+//
+// A failed request is answered with the error message and, for the recoverable
+// errors of the app, its code, ex: {"error": "no lyrics found", "code": "noLyrics"}
+
+const (
+	requestCommand  = "command"
+	requestStatus   = "status"
+	requestLyrics   = "lyrics"
+	requestCli      = "cli"
+	requestShutdown = "shutdown"
+)
+
+// codes of the recoverable errors of the app in the responses
+var remoteErrorCodes = []struct {
+	code string
+	err  error
+}{
+	{"noLyrics", app.ErrNoLyrics},
+	{"noResults", app.ErrNoResults},
+	{"invalidIndex", app.ErrInvalidIndex},
+	{"sourceUnavailable", app.ErrSourceUnavailable},
+	{"mediaUnplayable", app.ErrMediaUnplayable},
+	{"invalidConfig", app.ErrInvalidConfig},
+}
+
+type remoteRequest struct {
+	Type  string          `json:"type"`
+	Input string          `json:"input,omitempty"`
+	Audio *app.AudioBasic `json:"audio,omitempty"`
+	Args  []string        `json:"args,omitempty"`
+}
+
+type remoteResponse struct {
+	Ok       bool            `json:"ok"`
+	Error    string          `json:"error,omitempty"`
+	Code     string          `json:"code,omitempty"`
+	ExitCode int             `json:"exitCode,omitempty"`
+	State    *remoteState    `json:"state,omitempty"`
+	Status   *remoteStatus   `json:"status,omitempty"`
+	Lyrics   *app.Lyrics     `json:"lyrics,omitempty"`
+	Result   json.RawMessage `json:"result,omitempty"`
+	Text     string          `json:"text,omitempty"`
+}
+
+// remoteError is an error of the daemon, matching the app error of its code with errors.Is
+type remoteError struct {
+	msg  string
+	kind error
+}
+
+func (e remoteError) Error() string {
+	return e.msg
+}
+
+func (e remoteError) Unwrap() error {
+	return e.kind
+}
+
+// returns the response of a failed request, with the code of a recoverable error
+func newErrorResponse(err error) *remoteResponse {
+	resp := &remoteResponse{Error: err.Error()}
+	for _, errCode := range remoteErrorCodes {
+		if errors.Is(err, errCode.err) {
+			resp.Code = errCode.code
+			break
+		}
+	}
+	return resp
+}
+
+// returns the error of a failed response
+func (resp *remoteResponse) err() error {
+	remoteErr := remoteError{msg: resp.Error}
+	for _, errCode := range remoteErrorCodes {
+		if resp.Code == errCode.code {
+			remoteErr.kind = errCode.err
+			break
+		}
+	}
+	return remoteErr
+}
+
+// remoteState is the view state of a session after a command
+type remoteState struct {
+	Mode             imode       `json:"mode"`
+	Prompt           string      `json:"prompt"`
+	ResultMsg        string      `json:"resultMsg,omitempty"`
+	ListTitle        string      `json:"listTitle,omitempty"`
+	List             []string    `json:"list,omitempty"`
+	HighlightIndices []int       `json:"highlightIndices,omitempty"`
+	LyricsId         string      `json:"lyricsId,omitempty"`
+	Lyrics           *app.Lyrics `json:"lyrics,omitempty"`
+	Error            string      `json:"error,omitempty"`
+	Warn             bool        `json:"warn,omitempty"`
+	Quit             bool        `json:"quit,omitempty"`
+}
+
+type remoteStatus struct {
+	Audio       app.AudioBasic `json:"audio"`
+	Format      string         `json:"format"`
+	Rating      string         `json:"rating"`
+	MediaStatus mediaStat      `json:"mediaStatus"`
+	Pos         int            `json:"pos"`
+	Total       int            `json:"total"`
+}
+
+// connection to the daemon was lost
+type remoteErrMsg struct {
+	err error
+}
+
+// remoteClient sends requests to the daemon, one at a time
+type remoteClient struct {
+	mu     sync.Mutex
+	conn   net.Conn
+	reader *bufio.Reader
+	enc    *json.Encoder
+}
+
+func dialDaemon(socketPath string) (*remoteClient, error) {
+	conn, err := net.Dial("unix", socketPath)
+	if err != nil {
+		return nil, fmt.Errorf("ludo daemon is not running at %s: %w", socketPath, err)
+	}
+	return &remoteClient{conn: conn, reader: bufio.NewReader(conn), enc: json.NewEncoder(conn)}, nil
+}
+
+func (client *remoteClient) close() error {
+	return client.conn.Close()
+}
+
+func (client *remoteClient) do(req remoteRequest) (*remoteResponse, error) {
+	client.mu.Lock()
+	defer client.mu.Unlock()
+
+	if err := client.enc.Encode(req); err != nil {
+		return nil, err
+	}
+	line, err := client.reader.ReadBytes('\n')
+	if err != nil {
+		return nil, err
+	}
+
+	var resp remoteResponse
+	if err := json.Unmarshal(line, &resp); err != nil {
+		return nil, err
+	}
+	if !resp.Ok {
+		return nil, resp.err()
+	}
+	return &resp, nil
+}
+
+func (client *remoteClient) command(input string) (*remoteState, error) {
+	resp, err := client.do(remoteRequest{Type: requestCommand, Input: input})
+	if err != nil {
+		return nil, err
+	}
+	return resp.State, nil
+}
+
+func (client *remoteClient) status() (respStatus, error) {
+	resp, err := client.do(remoteRequest{Type: requestStatus})
+	if err != nil || resp.Status == nil {
+		return respStatus{}, err
+	}
+	return resp.Status.respStatus(), nil
+}
+
+func (client *remoteClient) lyrics(audio app.AudioBasic) (*app.Lyrics, error) {
+	resp, err := client.do(remoteRequest{Type: requestLyrics, Audio: &audio})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Lyrics, nil
+}
+
+// returns a bubble tea command which
+// will regularly poll the daemon for audio status and
+// push them in a channel
+func startRemoteActivity(status chan respStatus, client *remoteClient) tea.Cmd {
+	return func() tea.Msg {
+		defer app.HandlePanic()
+		for {
+			time.Sleep(time.Second)
+			stat, err := client.status()
+			if err != nil {
+				return remoteErrMsg{err}
+			}
+			status <- stat
+		}
+	}
+}
+
+// runs the command on the daemon and takes over the resulting view state
+func doRemoteCommand(input string, m *mainModel) {
+	state, err := m.remote.command(input)
+	if handleErr(err, m) {
+		return
+	}
+	state.apply(m)
+}
+
+// Conversions
+
+func newRemoteState(m *mainModel) *remoteState {
+	state := &remoteState{
+		Mode:             m.mode,
+		Prompt:           m.cmdInput.Prompt,
+		ResultMsg:        m.resultMsg,
+		ListTitle:        m.listTitle,
+		List:             m.searchList,
+		HighlightIndices: m.highlightIndices,
+		LyricsId:         m.lyricsId,
+		Lyrics:           m.lyrics,
+		Quit:             m.quit,
+	}
+	if m.err != nil {
+		state.Warn = commands.IsWarning(m.err)
+		state.Error = m.err.Error()
+	}
+	return state
+}
+
+func (state *remoteState) apply(m *mainModel) {
+	switch state.Mode {
+	case listMode:
+		setListMode(m)
+	case interactiveListMode:
+		setInteractiveListMode(m, state.Prompt)
+	case helpMode:
+		setHelpMode(m)
+	case lyricsMode:
+		setLyricsMode(m)
+	default:
+		setCommandMode(m)
+	}
+
+	m.resultMsg = state.ResultMsg
+	m.listTitle = state.ListTitle
+	m.searchList = state.List
+	m.highlightIndices = state.HighlightIndices
+	m.quit = state.Quit
+
+	m.lyricsId, m.lyrics, m.lyricLines = state.LyricsId, state.Lyrics, nil
+	if m.lyrics != nil {
+		m.lyricLines = m.lyrics.SyncedLines()
+	}
+
+	m.err = nil
+	if state.Warn {
+		m.err = commands.Warn(state.Error)
+	} else if state.Error != "" {
+		m.err = errors.New(state.Error)
+	}
+}
+
+func newRemoteStatus(stat respStatus) *remoteStatus {
+	return &remoteStatus{Audio: stat.audio, Format: stat.format, Rating: stat.rating,
+		MediaStatus: stat.mediaStatus, Pos: stat.pos, Total: stat.total}
+}
+
+func (stat *remoteStatus) respStatus() respStatus {
+	return respStatus{audio: stat.Audio, format: stat.Format, rating: stat.Rating,
+		mediaStatus: stat.MediaStatus, pos: stat.Pos, total: stat.Total}
+}
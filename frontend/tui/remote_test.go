@@ -0,0 +1,52 @@
+package tui
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/johnrijoy/ludo-go/app"
+)
+
+// errors sent over the daemon protocol keep their app error kind
+func TestRemoteErrorCodes(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		code string
+		kind error
+	}{
+		{"no lyrics", app.ErrNoLyrics, "noLyrics", app.ErrNoLyrics},
+		{"wrapped", fmt.Errorf("%w: timeout", app.ErrSourceUnavailable), "sourceUnavailable", app.ErrSourceUnavailable},
+		{"invalid index", app.ErrInvalidIndex, "invalidIndex", app.ErrInvalidIndex},
+		{"other error", errors.New("no lyrics found in cache"), "", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			line, err := json.Marshal(newErrorResponse(tt.err))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var resp remoteResponse
+			if err := json.Unmarshal(line, &resp); err != nil {
+				t.Fatal(err)
+			}
+			if resp.Ok || resp.Code != tt.code {
+				t.Fatalf("response = %s, want code %q", line, tt.code)
+			}
+
+			err = resp.err()
+			if err.Error() != tt.err.Error() {
+				t.Errorf("message = %q, want %q", err, tt.err)
+			}
+			if tt.kind != nil && !errors.Is(err, tt.kind) {
+				t.Errorf("%v is not %v", err, tt.kind)
+			}
+			if tt.kind == nil && app.IsRecoverable(err) {
+				t.Errorf("%v is recoverable", err)
+			}
+		})
+	}
+}
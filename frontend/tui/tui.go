@@ -1,284 +1,415 @@
-package tui
-
-import (
-	"fmt"
-	"math"
-	"os"
-	"strings"
-	"time"
-
-	"github.com/charmbracelet/bubbles/textinput"
-	"github.com/charmbracelet/bubbles/viewport"
-	tea "github.com/charmbracelet/bubbletea"
-	"github.com/charmbracelet/lipgloss"
-	"github.com/johnrijoy/ludo-go/app"
-	"golang.org/x/term"
-)
-
-// will launch the TUI
-func Run() {
-	if err := app.Init(); err != nil {
-		panic(err)
-	}
-	defer app.Close()
-
-	isPiped = app.IsSourcePiped()
-
-	p := tea.NewProgram(newMainModel())
-
-	if _, err := p.Run(); err != nil {
-		panic(err)
-	}
-}
-
-type mainModel struct {
-	currentStatus    respStatus
-	statusChan       chan respStatus
-	cmdInput         textinput.Model
-	cmdHist          []string
-	cmdHistIndex     int
-	resultMsg        string
-	listTitle        string
-	searchList       []string
-	highlightIndices []int
-	postSearchFunc   postIntList
-	mode             imode
-	help             viewport.Model
-	err              error
-	width, height    int
-	quit             bool
-}
-
-func newMainModel() mainModel {
-	m := mainModel{}
-
-	m.cmdInput = textinput.New()
-	m.cmdInput.Prompt = commandPrompt
-	m.cmdInput.Focus()
-	m.cmdInput.CharLimit = 200
-	m.cmdInput.Width = 50
-
-	m.statusChan = make(chan respStatus)
-
-	m.currentStatus = respStatus{mediaStatus: nothing, total: 0}
-
-	m.mode = commandMode
-	m.quit = false
-
-	m.help = viewport.New(50, 10)
-	m.help.SetContent(showHelp())
-
-	return m
-}
-
-func (m mainModel) Init() tea.Cmd {
-	return tea.Batch(tea.SetWindowTitle("Ludo Go"), tea.EnterAltScreen, startActivity(m.statusChan), listenActivity(m.statusChan), resizeTicker)
-}
-
-func (m mainModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
-	var cmd tea.Cmd
-
-	if m.quit {
-		return m, tea.Quit
-	}
-
-	// General events
-	switch msg := msg.(type) {
-	case tea.WindowSizeMsg:
-		m.height = msg.Height
-		m.width = msg.Width
-		return m, nil
-	case resizeTickMsg:
-		w, h, _ := term.GetSize(int(os.Stdout.Fd()))
-		// if w != m.width || h != m.height {
-		// 	m.updateSize(w, h)
-		// }
-		return m, tea.Batch(resizeTicker, func() tea.Msg { return tea.WindowSizeMsg{Width: w, Height: h} })
-	case respStatus:
-		m.currentStatus = msg
-		return m, listenActivity(m.statusChan)
-	}
-
-	// help mode
-	if m.mode == helpMode {
-		switch msg := msg.(type) {
-		case tea.KeyMsg:
-			switch msg.Type.String() {
-			case "esc":
-				setCommandMode(&m)
-				return m, nil
-			}
-		}
-		var cmd tea.Cmd
-		m.help, cmd = m.help.Update(msg)
-		return m, cmd
-	}
-
-	m.cmdInput, cmd = m.cmdInput.Update(msg)
-	// Player events
-	switch msg := msg.(type) {
-	case tea.KeyMsg:
-		switch msg.Type.String() {
-		case "ctrl+c":
-			return m, tea.Quit
-		case "ctrl+h":
-			setHelpMode(&m)
-			m.help, cmd = m.help.Update(msg)
-			return m, cmd
-		case "enter":
-			inp := m.cmdInput.Value()
-			if m.mode == interactiveListMode {
-				doInterativeList(inp, &m)
-			} else {
-				doCommand(inp, &m)
-			}
-			m.cmdHist = append(m.cmdHist, inp)
-			m.cmdHistIndex = len(m.cmdHist)
-			m.cmdInput.Reset()
-
-			return m, m.cmdInput.Focus()
-		case "up":
-			m.cmdHistIndex--
-			if m.cmdHistIndex < 0 {
-				m.cmdHistIndex = 0
-			}
-			if len(m.cmdHist) > 0 {
-				m.cmdInput.SetValue(m.cmdHist[m.cmdHistIndex])
-			}
-		case "down":
-			m.cmdHistIndex++
-			if m.cmdHistIndex >= len(m.cmdHist) {
-				m.cmdHistIndex = len(m.cmdHist)
-			}
-			if m.cmdHistIndex == len(m.cmdHist) {
-				m.cmdInput.Reset()
-			} else {
-				val := m.cmdHist[m.cmdHistIndex]
-				m.cmdInput.SetValue(val)
-			}
-		case "runes":
-			m.err = nil
-			m.resultMsg = ""
-			return m, nil
-		}
-	}
-
-	return m, cmd
-}
-
-func (m mainModel) View() string {
-	s := ""
-
-	// help screen
-	if m.mode == helpMode {
-		m.help.Height = m.height - 4
-		m.help.Width = getBaseHorizontalWidth(&m)
-		return lipgloss.JoinVertical(lipgloss.Left, m.getAppTitle(), baseStyle.Height(m.height-2).Width(m.width-2).Render(m.help.View()))
-	}
-
-	// result in case of command mode
-	if m.mode == commandMode {
-		if m.resultMsg != "" {
-			s += fmt.Sprintf("\n%s\n", m.resultMsg)
-		}
-	}
-
-	// for list or interactive list mode
-	if m.mode == listMode || m.mode == interactiveListMode {
-		s += fmt.Sprintln()
-
-		dispWidth := getBaseHorizontalWidth(&m)
-		for i, item := range m.searchList {
-			itemView := safeTruncString(item, dispWidth)
-			if sliceContains(m.highlightIndices, i) {
-				itemView = Magenta(itemView)
-			}
-			s += itemView
-			s += "\n"
-		}
-
-		// s += lipgloss.JoinHorizontal(lipgloss.Left, m.searchList...)
-		// s += "\n"
-	}
-
-	// error display
-	if m.err != nil {
-		if _, ok := m.err.(ErrWarn); ok {
-			s += fmt.Sprintf("\n%s %s\n", Yellow("WARN:"), m.err.Error())
-		} else {
-			s += fmt.Sprintf("\n%s %s\n", Red("ERROR:"), m.err.Error())
-		}
-	}
-
-	// assembling other components
-	s = lipgloss.JoinVertical(lipgloss.Left, "\n", m.viewCurrentAudio(), m.cmdInput.View(), s)
-
-	// trimming excess height
-	s = safeTrimHeight(s, m.height-4)
-	// s = safeTrimWidth(s, m.width)
-
-	//s += fmt.Sprintf("TextHeight: %d WindowHeight: %d WindowWidth: %d", height, m.height, m.width)
-	return lipgloss.JoinVertical(lipgloss.Left, m.getAppTitle(), baseStyle.Height(m.height-2).Width(m.width-2).Render(s))
-}
-
-// returns a bubble tea command which
-// will regularly poll the mediaPlayer for audio status and
-// push them in a channel
-func startActivity(status chan respStatus) tea.Cmd {
-	return func() tea.Msg {
-		for {
-			time.Sleep(time.Second)
-			stat := app.MediaPlayer().FetchPlayerState()
-			curr, pos := app.MediaPlayer().GetMediaPosition()
-			aud := app.MediaPlayer().GetAudioState().AudioBasic
-			status <- respStatus{pos: curr, total: pos, mediaStatus: mediaStat(stat), audio: aud}
-		}
-	}
-}
-
-// returns a bubble tea command which
-// will fetch audio status from channel and return it
-func listenActivity(status chan respStatus) tea.Cmd {
-	return func() tea.Msg {
-		return <-status
-	}
-}
-
-// builds the live UI for currently playing audio
-func (m *mainModel) viewCurrentAudio() string {
-	s := ""
-
-	scale := 50
-	dispWidth := getBaseHorizontalWidth(m)
-	if dispWidth < scale && dispWidth > 0 {
-		scale = dispWidth
-	}
-
-	currPos, totPos := m.currentStatus.pos, m.currentStatus.total
-
-	navMsg := Gray(strings.Repeat("-", scale))
-	if totPos > currPos {
-		scaledCurrPos := int(math.Round((float64(currPos) / float64(totPos)) * float64(scale)))
-		restPosition := scale - scaledCurrPos
-
-		navMsg = Magenta(strings.Repeat(">", scaledCurrPos)) + Gray(strings.Repeat("-", restPosition))
-	}
-
-	audTitle := safeTruncString(m.currentStatus.audio.Title, 30)
-	audUploader := safeTruncString(m.currentStatus.audio.Uploader, 20)
-
-	s += fmt.Sprintf("%s%s%s\n",
-		NoStyle.Width(scale*3/5).Render(m.currentStatus.mediaStatus.String()),
-		NoStyle.Width(scale/5).AlignHorizontal(lipgloss.Right).Render(app.GetFormattedTime(currPos)),
-		NoStyle.Width(scale/5).AlignHorizontal(lipgloss.Right).Render(app.GetFormattedTime(totPos)),
-	)
-	s += fmt.Sprintf("%s\n", navMsg)
-	s += fmt.Sprintf("%s%s\n",
-		Aqua.Width(scale*3/5).Render(audTitle),
-		AquaD.Width(scale*2/5).AlignHorizontal(lipgloss.Right).Render(audUploader))
-
-	// s += fmt.Sprintf("\n%s  | %s / %s\n", m.currentStatus.mediaStatus, app.GetFormattedTime(m.currentStatus.pos), app.GetFormattedTime(m.currentStatus.total))
-	// s += fmt.Sprintf("%s\n", m.currentStatus.audio.Title)
-	// s += fmt.Sprintf("%-20s %10s\n", m.currentStatus.audio.Uploader, m.currentStatus.audio.GetFormattedDuration())
-	return s
-}
+package tui
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/johnrijoy/ludo-go/app"
+	"github.com/johnrijoy/ludo-go/frontend/commands"
+	"golang.org/x/term"
+)
+
+// will launch the TUI
+func Run() error {
+	defer app.Close()
+	if err := app.Init(); err != nil {
+		return err
+	}
+
+	session = commands.NewSession()
+	saveTerminal()
+
+	m := newMainModel()
+	if warnings := app.ConfigWarnings(); len(warnings) > 0 {
+		m.err = commands.Warn(fmt.Sprintf("%d issues in ludo.props, see config validate", len(warnings)))
+	}
+
+	// panics are handled by app.HandlePanic, which restores the terminal and closes the app
+	p := tea.NewProgram(m, tea.WithoutCatchPanics())
+
+	_, err := p.Run()
+	return err
+}
+
+// will launch the TUI as a client of the daemon running at the socket
+func Attach(socketPath string) error {
+	client, err := dialDaemon(socketPath)
+	if err != nil {
+		return err
+	}
+	defer client.close()
+
+	m := newMainModel()
+	m.remote = client
+	saveTerminal()
+
+	final, err := tea.NewProgram(m, tea.WithoutCatchPanics()).Run()
+	if err != nil {
+		return err
+	}
+	if fm, ok := final.(mainModel); ok && fm.remoteErr != nil {
+		return fmt.Errorf("connection to daemon lost: %w", fm.remoteErr)
+	}
+	return nil
+}
+
+type mainModel struct {
+	currentStatus    respStatus
+	statusChan       chan respStatus
+	cmdInput         textinput.Model
+	cmdHist          []string
+	cmdHistIndex     int
+	resultMsg        string
+	listTitle        string
+	searchList       []string
+	highlightIndices []int
+	choice           *commands.Choice // selected from in interactive list mode
+	lyricsId         string
+	lyrics           *app.Lyrics
+	lyricLines       []app.LyricLine
+	mode             imode
+	help             viewport.Model
+	err              error
+	width, height    int
+	quit             bool
+	remote           *remoteClient // set when attached to the daemon
+	remoteErr        error
+}
+
+func newMainModel() mainModel {
+	m := mainModel{}
+
+	m.cmdInput = textinput.New()
+	m.cmdInput.Prompt = commandPrompt
+	m.cmdInput.Focus()
+	m.cmdInput.CharLimit = 200
+	m.cmdInput.Width = 50
+
+	m.statusChan = make(chan respStatus)
+
+	m.currentStatus = respStatus{mediaStatus: nothing, total: 0}
+
+	m.mode = commandMode
+	m.quit = false
+
+	m.help = viewport.New(50, 10)
+	m.help.SetContent(showHelp())
+
+	return m
+}
+
+func (m mainModel) Init() tea.Cmd {
+	activity := startActivity(m.statusChan)
+	if m.remote != nil {
+		activity = startRemoteActivity(m.statusChan, m.remote)
+	}
+	return tea.Batch(tea.SetWindowTitle("Ludo Go"), tea.EnterAltScreen, activity, listenActivity(m.statusChan), resizeTicker)
+}
+
+func (m mainModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	var cmd tea.Cmd
+
+	if m.quit {
+		return m, tea.Quit
+	}
+
+	// General events
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.height = msg.Height
+		m.width = msg.Width
+		return m, nil
+	case resizeTickMsg:
+		w, h, _ := term.GetSize(int(os.Stdout.Fd()))
+		// if w != m.width || h != m.height {
+		// 	m.updateSize(w, h)
+		// }
+		return m, tea.Batch(resizeTicker, func() tea.Msg { return tea.WindowSizeMsg{Width: w, Height: h} })
+	case respStatus:
+		m.currentStatus = msg
+		// lyrics pane follows the track change
+		if m.mode == lyricsMode && msg.audio.YtId != "" && msg.audio.YtId != m.lyricsId {
+			m.lyricsId = msg.audio.YtId
+			return m, tea.Batch(listenActivity(m.statusChan), fetchLyrics(msg.audio, m.remote))
+		}
+		return m, listenActivity(m.statusChan)
+	case remoteErrMsg:
+		m.remoteErr = msg.err
+		return m, tea.Quit
+	case lyricsMsg:
+		if msg.ytId == m.lyricsId {
+			setLyrics(msg.lyrics, msg.err, &m)
+		}
+		return m, nil
+	}
+
+	// help mode
+	if m.mode == helpMode {
+		switch msg := msg.(type) {
+		case tea.KeyMsg:
+			switch msg.Type.String() {
+			case "esc":
+				setCommandMode(&m)
+				return m, nil
+			}
+		}
+		var cmd tea.Cmd
+		m.help, cmd = m.help.Update(msg)
+		return m, cmd
+	}
+
+	m.cmdInput, cmd = m.cmdInput.Update(msg)
+	// Player events
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch msg.Type.String() {
+		case "ctrl+c":
+			return m, tea.Quit
+		case "ctrl+h":
+			setHelpMode(&m)
+			m.help, cmd = m.help.Update(msg)
+			return m, cmd
+		case "enter":
+			inp := m.cmdInput.Value()
+			if m.remote != nil {
+				doRemoteCommand(inp, &m)
+			} else if m.mode == interactiveListMode {
+				doInterativeList(inp, &m)
+			} else {
+				doCommand(inp, &m)
+			}
+			m.cmdHist = append(m.cmdHist, inp)
+			m.cmdHistIndex = len(m.cmdHist)
+			m.cmdInput.Reset()
+
+			return m, m.cmdInput.Focus()
+		case "up":
+			m.cmdHistIndex--
+			if m.cmdHistIndex < 0 {
+				m.cmdHistIndex = 0
+			}
+			if len(m.cmdHist) > 0 {
+				m.cmdInput.SetValue(m.cmdHist[m.cmdHistIndex])
+			}
+		case "down":
+			m.cmdHistIndex++
+			if m.cmdHistIndex >= len(m.cmdHist) {
+				m.cmdHistIndex = len(m.cmdHist)
+			}
+			if m.cmdHistIndex == len(m.cmdHist) {
+				m.cmdInput.Reset()
+			} else {
+				val := m.cmdHist[m.cmdHistIndex]
+				m.cmdInput.SetValue(val)
+			}
+		case "runes":
+			m.err = nil
+			m.resultMsg = ""
+			return m, nil
+		}
+	}
+
+	return m, cmd
+}
+
+func (m mainModel) View() string {
+	s := ""
+
+	// help screen
+	if m.mode == helpMode {
+		m.help.Height = m.height - 4
+		m.help.Width = getBaseHorizontalWidth(&m)
+		return lipgloss.JoinVertical(lipgloss.Left, m.getAppTitle(), baseStyle.Height(m.height-2).Width(m.width-2).Render(m.help.View()))
+	}
+
+	// result in case of command mode
+	if m.mode == commandMode {
+		if m.resultMsg != "" {
+			s += fmt.Sprintf("\n%s\n", m.resultMsg)
+		}
+	}
+
+	// for list or interactive list mode
+	if m.mode == listMode || m.mode == interactiveListMode {
+		s += fmt.Sprintln()
+
+		dispWidth := getBaseHorizontalWidth(&m)
+		for i, item := range m.searchList {
+			itemView := safeTruncString(item, dispWidth)
+			if sliceContains(m.highlightIndices, i) {
+				itemView = Magenta(itemView)
+			}
+			s += itemView
+			s += "\n"
+		}
+
+		// s += lipgloss.JoinHorizontal(lipgloss.Left, m.searchList...)
+		// s += "\n"
+	}
+
+	// lyrics pane
+	if m.mode == lyricsMode {
+		s += m.viewLyrics()
+	}
+
+	// error display
+	if m.err != nil {
+		if commands.IsWarning(m.err) {
+			s += fmt.Sprintf("\n%s %s\n", Yellow("WARN:"), m.err.Error())
+		} else {
+			s += fmt.Sprintf("\n%s %s\n", Red("ERROR:"), m.err.Error())
+		}
+	}
+
+	// assembling other components
+	s = lipgloss.JoinVertical(lipgloss.Left, "\n", m.viewCurrentAudio(), m.cmdInput.View(), s)
+
+	// trimming excess height
+	s = safeTrimHeight(s, m.height-4)
+	// s = safeTrimWidth(s, m.width)
+
+	//s += fmt.Sprintf("TextHeight: %d WindowHeight: %d WindowWidth: %d", height, m.height, m.width)
+	return lipgloss.JoinVertical(lipgloss.Left, m.getAppTitle(), baseStyle.Height(m.height-2).Width(m.width-2).Render(s))
+}
+
+// returns a bubble tea command which
+// will regularly poll the mediaPlayer for audio status and
+// push them in a channel
+func startActivity(status chan respStatus) tea.Cmd {
+	return func() tea.Msg {
+		defer app.HandlePanic()
+		var poller statusPoller
+		for {
+			time.Sleep(time.Second)
+			status <- poller.poll()
+		}
+	}
+}
+
+// statusPoller reads the audio status from the mediaPlayer,
+// the rating is fetched again only when the audio or ratings change
+type statusPoller struct {
+	ratingId  string
+	rating    string
+	ratingRev int64
+	loaded    bool
+}
+
+func (poller *statusPoller) poll() respStatus {
+	stat := app.MediaPlayer().FetchPlayerState()
+	curr, pos := app.MediaPlayer().GetMediaPosition()
+	audState := app.MediaPlayer().GetAudioState()
+	if rev := app.AudioDb().RatingRevision(); !poller.loaded || audState.YtId != poller.ratingId || rev != poller.ratingRev {
+		poller.ratingId, poller.ratingRev, poller.loaded = audState.YtId, rev, true
+		poller.rating = app.GetRatingMarkers(poller.ratingId)[poller.ratingId]
+	}
+	return respStatus{pos: curr, total: pos, mediaStatus: mediaStat(stat), audio: audState.AudioBasic, format: audState.StreamFormat.String(), rating: poller.rating}
+}
+
+// returns a bubble tea command which
+// will fetch audio status from channel and return it
+func listenActivity(status chan respStatus) tea.Cmd {
+	return func() tea.Msg {
+		return <-status
+	}
+}
+
+// builds the live UI for currently playing audio
+func (m *mainModel) viewCurrentAudio() string {
+	s := ""
+
+	scale := 50
+	dispWidth := getBaseHorizontalWidth(m)
+	if dispWidth < scale && dispWidth > 0 {
+		scale = dispWidth
+	}
+
+	currPos, totPos := m.currentStatus.pos, m.currentStatus.total
+
+	navMsg := Gray(strings.Repeat("-", scale))
+	if totPos > currPos {
+		scaledCurrPos := int(math.Round((float64(currPos) / float64(totPos)) * float64(scale)))
+		restPosition := scale - scaledCurrPos
+
+		navMsg = Magenta(strings.Repeat(">", scaledCurrPos)) + Gray(strings.Repeat("-", restPosition))
+	}
+
+	audTitle := safeTruncString(m.currentStatus.audio.Title, 30)
+	if rating := m.currentStatus.rating; rating != "" {
+		audTitle = Pink(rating) + " " + safeTruncString(m.currentStatus.audio.Title, 29-lipgloss.Width(rating))
+	}
+	audUploader := safeTruncString(m.currentStatus.audio.Uploader, 20)
+
+	mediaStatus := m.currentStatus.mediaStatus.String()
+	if formatWidth := scale*3/5 - lipgloss.Width(mediaStatus) - 2; formatWidth > 3 {
+		mediaStatus += "  " + Gray(safeTruncString(m.currentStatus.format, formatWidth))
+	}
+
+	s += fmt.Sprintf("%s%s%s\n",
+		NoStyle.Width(scale*3/5).Render(mediaStatus),
+		NoStyle.Width(scale/5).AlignHorizontal(lipgloss.Right).Render(app.GetFormattedTime(currPos)),
+		NoStyle.Width(scale/5).AlignHorizontal(lipgloss.Right).Render(app.GetFormattedTime(totPos)),
+	)
+	s += fmt.Sprintf("%s\n", navMsg)
+	s += fmt.Sprintf("%s%s\n",
+		Aqua.Width(scale*3/5).Render(audTitle),
+		AquaD.Width(scale*2/5).AlignHorizontal(lipgloss.Right).Render(audUploader))
+
+	// s += fmt.Sprintf("\n%s  | %s / %s\n", m.currentStatus.mediaStatus, app.GetFormattedTime(m.currentStatus.pos), app.GetFormattedTime(m.currentStatus.total))
+	// s += fmt.Sprintf("%s\n", m.currentStatus.audio.Title)
+	// s += fmt.Sprintf("%-20s %10s\n", m.currentStatus.audio.Uploader, m.currentStatus.audio.GetFormattedDuration())
+	return s
+}
+
+// builds the lyrics pane, synced lyrics are scrolled to the current position
+func (m *mainModel) viewLyrics() string {
+	if m.lyrics == nil {
+		return ""
+	}
+
+	dispWidth := getBaseHorizontalWidth(m)
+	s := fmt.Sprintf("\n%s\n", Magenta(safeTruncString(m.lyrics.TrackName+" - "+m.lyrics.ArtistName, dispWidth)))
+	if m.lyrics.Instrumental {
+		return s + Gray("Instrumental") + "\n"
+	}
+
+	if len(m.lyricLines) == 0 {
+		for _, line := range m.lyrics.PlainLines() {
+			s += safeTruncString(line, dispWidth) + "\n"
+		}
+		return s
+	}
+
+	paneHeight := m.height - lyricsPaneMargin
+	if paneHeight < minLyricsPaneHeight {
+		paneHeight = minLyricsPaneHeight
+	}
+
+	currLine := app.CurrentLyricLine(m.lyricLines, m.currentStatus.pos)
+	start := currLine - paneHeight/2
+	if start > len(m.lyricLines)-paneHeight {
+		start = len(m.lyricLines) - paneHeight
+	}
+	if start < 0 {
+		start = 0
+	}
+
+	for i := start; i < len(m.lyricLines) && i < start+paneHeight; i++ {
+		line := safeTruncString(m.lyricLines[i].Text, dispWidth)
+		switch {
+		case i == currLine:
+			line = Pink(line)
+		case i < currLine:
+			line = Gray(line)
+		}
+		s += line + "\n"
+	}
+	return s
+}
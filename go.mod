@@ -1,6 +1,6 @@
 module github.com/johnrijoy/ludo-go
 
-go 1.20
+go 1.21
 
 require (
 	github.com/adrg/libvlc-go/v3 v3.1.5
@@ -8,11 +8,14 @@ require (
 	github.com/charmbracelet/bubbletea v0.25.0
 	github.com/charmbracelet/lipgloss v0.9.1
 	github.com/fatih/color v1.16.0
+	github.com/godbus/dbus/v5 v5.1.0
 	github.com/magiconair/properties v1.8.7
 	github.com/ostafen/clover/v2 v2.0.0-alpha.3
 	github.com/raitonoberu/ytmusic v0.0.0-20240324143733-0e5780514b1d
 	github.com/satori/go.uuid v1.2.0
+	go.etcd.io/bbolt v1.3.6
 	golang.org/x/term v0.6.0
+	golang.org/x/text v0.3.8
 )
 
 require (
@@ -45,11 +48,9 @@ require (
 	github.com/rivo/uniseg v0.4.6 // indirect
 	github.com/vmihailenco/msgpack/v5 v5.3.5 // indirect
 	github.com/vmihailenco/tagparser/v2 v2.0.0 // indirect
-	go.etcd.io/bbolt v1.3.6 // indirect
 	go.opencensus.io v0.23.0 // indirect
 	golang.org/x/net v0.0.0-20220728211354-c7608f3a8462 // indirect
 	golang.org/x/sync v0.1.0 // indirect
 	golang.org/x/sys v0.14.0 // indirect
-	golang.org/x/text v0.3.8 // indirect
 	google.golang.org/protobuf v1.28.1 // indirect
 )
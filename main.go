@@ -2,18 +2,63 @@ package main
 
 import (
 	"flag"
+	"fmt"
+	"os"
 
+	"github.com/johnrijoy/ludo-go/app"
+	"github.com/johnrijoy/ludo-go/frontend/cli"
 	"github.com/johnrijoy/ludo-go/frontend/prompt"
 	"github.com/johnrijoy/ludo-go/frontend/tui"
 )
 
 func main() {
+	defer app.HandlePanic()
+
 	isPrompt := flag.Bool("p", false, "Start in prompt mode")
+	socketPath := flag.String("socket", "", "Unix socket of the daemon, default is config.daemon.socket")
+	debug := flag.Bool("debug", false, "Log at debug level, overrides config.log.level")
+	logFile := flag.String("log-file", "", "Log file, default is config.log.file")
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "Usage: ludo [flags] [daemon|attach|<subcommand>]")
+		flag.PrintDefaults()
+		cli.Usage(flag.CommandLine.Output())
+	}
 	flag.Parse()
 
-	if *isPrompt {
-		prompt.Run()
-	} else {
-		tui.Run()
+	app.SetLogOptions(app.LogOptions{Debug: *debug, File: *logFile})
+
+	switch flag.Arg(0) {
+	case "daemon":
+		exitOnErr(tui.RunDaemon(getSocketPath(*socketPath)))
+	case "attach":
+		exitOnErr(tui.Attach(getSocketPath(*socketPath)))
+	case "":
+		if *isPrompt {
+			prompt.Run()
+		} else {
+			exitOnErr(tui.Run())
+		}
+	default:
+		if !cli.IsCommand(flag.Arg(0)) {
+			flag.Usage()
+			os.Exit(cli.ExitUsage)
+		}
+		os.Exit(cli.Run(flag.Args(), getSocketPath(*socketPath)))
+	}
+}
+
+func getSocketPath(socketPath string) string {
+	if socketPath != "" {
+		return socketPath
+	}
+	socketPath, err := app.GetDaemonSocket()
+	exitOnErr(err)
+	return socketPath
+}
+
+func exitOnErr(err error) {
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
 	}
 }